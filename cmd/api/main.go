@@ -14,18 +14,23 @@ import (
 
 	"notif/internal/awsutil"
 	"notif/internal/config"
+	"notif/internal/domain"
 	"notif/internal/httpserver"
 	"notif/internal/logging"
 	"notif/internal/observability"
+	"notif/internal/outbox"
+	"notif/internal/phone"
 	sqsqueue "notif/internal/queue/sqs"
 	"notif/internal/service"
 	"notif/internal/store/pg"
+	"notif/internal/templates"
 	"notif/internal/util"
 )
 
 func main() {
 	cfg := config.LoadAPI()
 	logging.Init("api", cfg.LogFormat)
+	phone.SetDefaultRegion(cfg.PhoneDefaultRegion)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -51,20 +56,51 @@ func main() {
 	observability.RegisterAPI(prometheus.DefaultRegisterer)
 
 	store := pg.New(db)
-	producer := &sqsqueue.Producer{SQS: sqsClient, QueueURL: cfg.SQSQueueURL, GroupBuckets: cfg.SQSGroupBuckets}
+	producer := &sqsqueue.Producer{
+		SQS:          sqsClient,
+		QueueURL:     cfg.SQSQueueURL,
+		GroupBuckets: cfg.SQSGroupBuckets,
+		QueueURLs: map[string]string{
+			domain.PriorityHigh: cfg.SQSHighPriorityQueueURL,
+			domain.PriorityLow:  cfg.SQSLowPriorityQueueURL,
+		},
+	}
 
+	templateCache := templates.NewCache(store, time.Duration(cfg.TemplateCacheTTLSeconds)*time.Second)
 	svc := &service.NotificationService{
 		Store:     store,
-		Queue:     producer,
 		MaxPerDay: cfg.MaxSMSPerDay,
+		Templates: templateCache,
 	}
 
 	s := httpserver.New()
 	s.Mux.Use(httpserver.Metrics(observability.APIRequests))
 	s.Mux.Use(httpserver.Logging)
+	keys := &service.APIKeyService{Store: store}
 	api := &httpserver.API{
-		Svc:   svc,
-		IDGen: util.NewMessageID,
+		Svc:             svc,
+		IDGen:           util.NewMessageID,
+		MaxBatchSize:    cfg.MaxBatchSize,
+		Consents:        &service.ConsentService{Store: store},
+		Suppressions:    &service.SuppressionService{Store: store},
+		Templates:       &service.TemplateService{Store: store, Invalidate: templateCache.Invalidate},
+		DeliveryWindows: &service.DeliveryWindowService{Store: store},
+		KeywordReplies:  &service.KeywordReplyService{Store: store},
+	}
+	if cfg.APIAuthEnabled {
+		api.Auth = keys
+	} else {
+		slog.Warn("api key auth disabled; tenantId from request bodies is trusted")
+	}
+	// Admin routes first: they live under /v1 but use the admin token, not tenant keys.
+	if cfg.AdminAPIToken != "" {
+		admin := &httpserver.Admin{
+			Keys:       keys,
+			Policies:   &service.CountryPolicyService{Store: store},
+			RateLimits: &service.RateLimitService{Store: store},
+			Token:      cfg.AdminAPIToken,
+		}
+		admin.Register(s.Mux)
 	}
 	api.Register(s.Mux)
 
@@ -88,6 +124,25 @@ func main() {
 		metricsErrCh <- metricsSrv.ListenAndServe()
 	}()
 
+	// Outbox relay: publishes messages committed by the API to SQS.
+	if cfg.OutboxRelayEnabled {
+		relay := &outbox.Relay{
+			Store:        store,
+			Publisher:    producer,
+			BatchSize:    cfg.OutboxBatchSize,
+			PollInterval: time.Duration(cfg.OutboxPollIntervalMs) * time.Millisecond,
+		}
+		if producer.SupportsDelay() {
+			relay.DelayHorizon = min(time.Duration(cfg.OutboxDelayHorizonSeconds)*time.Second, sqsqueue.MaxDelay)
+		}
+		go func() {
+			slog.Info("api outbox relay starting", "batch_size", cfg.OutboxBatchSize)
+			if err := relay.Run(ctx); err != nil && err != context.Canceled {
+				slog.Error("api outbox relay stopped", "err", err)
+			}
+		}()
+	}
+
 	slog.Info("api listening", "port", cfg.Port)
 	serverErrCh := make(chan error, 1)
 	go func() {
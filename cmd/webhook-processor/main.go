@@ -12,12 +12,16 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
 	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
+	"notif/internal/autosuppress"
 	"notif/internal/awsutil"
 	"notif/internal/config"
 	"notif/internal/httpserver"
 	"notif/internal/logging"
+	"notif/internal/observability"
+	"notif/internal/providers/twilio"
 	sqsqueue "notif/internal/queue/sqs"
 	"notif/internal/store/pg"
 	"notif/internal/store"
@@ -27,6 +31,7 @@ import (
 func main() {
 	cfg := config.LoadWebhookProcessor()
 	logging.Init("webhook-processor", cfg.LogFormat)
+	observability.RegisterWebhookProcessor(prometheus.DefaultRegisterer)
 
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -44,6 +49,13 @@ func main() {
 	defer db.Close()
 	dbStore := pg.New(db)
 
+	autoSuppressRules, err := autosuppress.ParseRules(cfg.AutoSuppressRules)
+	if err != nil {
+		slog.Error("webhook-processor auto-suppress rules invalid", "err", err)
+		os.Exit(1)
+	}
+	autoSuppress := &autosuppress.Policy{Store: dbStore, Rules: autoSuppressRules}
+
 	sqsClient, err := awsutil.NewSQSClient(ctx, cfg.AWSRegion, cfg.LocalstackEndpoint)
 	if err != nil {
 		slog.Error("webhook-processor sqs client init failed", "err", err)
@@ -91,7 +103,7 @@ func main() {
 	go func() {
 		slog.Info("webhook-processor starting poll", "queue_url", cfg.WebhookEventsQueueURL)
 		pollErrCh <- consumer.PollConcurrent(ctx, cfg.ProcessorConcurrency, func(ctx context.Context, ev sqsqueue.WebhookEvent) error {
-			return processWebhookEvent(ctx, dbStore, ev)
+			return processWebhookEvent(ctx, dbStore, autoSuppress, ev)
 		})
 	}()
 
@@ -133,7 +145,7 @@ func main() {
 	}
 }
 
-func processWebhookEvent(ctx context.Context, st *pg.Store, ev sqsqueue.WebhookEvent) error {
+func processWebhookEvent(ctx context.Context, st *pg.Store, autoSuppress *autosuppress.Policy, ev sqsqueue.WebhookEvent) error {
 	newState := ""
 	switch ev.Status {
 	case "delivered":
@@ -141,6 +153,11 @@ func processWebhookEvent(ctx context.Context, st *pg.Store, ev sqsqueue.WebhookE
 	case "failed", "undelivered":
 		newState = "failed"
 	}
+	reason := ev.FailureReason
+	if newState == "failed" && reason == "" && ev.Provider == twilio.ProviderName {
+		// Enqueued before the webhook mapped reasons.
+		reason = string(twilio.DeliveryFailureReason(ev.ErrorCode))
+	}
 
 	// Make DB work bounded. Errors should cause SQS redrive.
 	dbCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -154,6 +171,7 @@ func processWebhookEvent(ctx context.Context, st *pg.Store, ev sqsqueue.WebhookE
 			ProviderMsgID: ev.ProviderMsgID,
 			NewState:      newState,
 			LastError:     ev.ErrorCode,
+			FailureReason: reason,
 			Now:           util.NowUTC(),
 		})
 		if err != nil {
@@ -162,6 +180,19 @@ func processWebhookEvent(ctx context.Context, st *pg.Store, ev sqsqueue.WebhookE
 		if !updated {
 			return errors.New("message not found for provider_msg_id")
 		}
+		if newState == "failed" {
+			observability.MessageFailures.WithLabelValues(ev.Provider, reason).Inc()
+		}
+		if err := autoSuppress.Apply(dbCtx, autosuppress.Receipt{
+			Provider:      ev.Provider,
+			ProviderMsgID: ev.ProviderMsgID,
+			State:         newState,
+			ErrorCode:     ev.ErrorCode,
+			FailureReason: reason,
+		}, util.NowUTC()); err != nil {
+			// Not worth a redrive: the receipt is applied and the next failure counts again.
+			slog.Error("webhook-processor auto-suppress failed", "err", err, "provider_msg_id", ev.ProviderMsgID)
+		}
 	}
 
 	// Persist the event (payload omitted to reduce DB load).
@@ -170,6 +201,7 @@ func processWebhookEvent(ctx context.Context, st *pg.Store, ev sqsqueue.WebhookE
 		ProviderMsgID: ev.ProviderMsgID,
 		VendorStatus:  ev.Status,
 		ErrorCode:     ev.ErrorCode,
+		FailureReason: reason,
 		Payload:       nil,
 		OccurredAt:    nil,
 	})
@@ -13,13 +13,16 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
+	"notif/internal/autosuppress"
 	"notif/internal/awsutil"
 	"notif/internal/config"
+	"notif/internal/domain"
 	"notif/internal/httpserver"
 	"notif/internal/logging"
 	"notif/internal/observability"
 	"notif/internal/providers/twilio"
 	sqsqueue "notif/internal/queue/sqs"
+	"notif/internal/service"
 	"notif/internal/store/pg"
 )
 
@@ -32,7 +35,8 @@ func main() {
 
 	var db *pgxpool.Pool
 	var dbStore *pg.Store
-	if !cfg.WebhookUseQueue {
+	// Queue mode only skips the DB for status callbacks; inbound messages are answered inline.
+	if !cfg.WebhookUseQueue || cfg.PublicInboundWebhookURL != "" {
 		var err error
 		db, err = pg.NewPool(ctx, cfg.DBDSN, pg.PoolOptions{
 			MaxConns:          cfg.DBPoolMaxConns,
@@ -48,6 +52,17 @@ func main() {
 		dbStore = pg.New(db)
 	}
 
+	// Receipts are applied here only outside queue mode; the webhook processor does it otherwise.
+	var autoSuppress *autosuppress.Policy
+	if !cfg.WebhookUseQueue {
+		rules, err := autosuppress.ParseRules(cfg.AutoSuppressRules)
+		if err != nil {
+			slog.Error("webhook auto-suppress rules invalid", "err", err)
+			os.Exit(1)
+		}
+		autoSuppress = &autosuppress.Policy{Store: dbStore, Rules: rules}
+	}
+
 	var enq httpserver.WebhookEnqueuer
 	if cfg.WebhookUseQueue {
 		if cfg.WebhookEventsQueueURL == "" {
@@ -82,9 +97,23 @@ func main() {
 		Store:           dbStore,
 		Enqueuer:        enq,
 		VerifySignature: twilio.VerifySignature,
+		ParseStatus:     twilio.ParseStatusCallback,
 		AuthToken:       cfg.TwilioAuthToken,
 		PublicURL:       cfg.PublicWebhookURL,
 		UseQueue:        cfg.WebhookUseQueue,
+		AutoSuppress:    autoSuppress,
+	}
+	if cfg.PublicInboundWebhookURL != "" {
+		webhook.Inbound = &service.InboundService{
+			Store: dbStore,
+			Defaults: domain.KeywordReplies{
+				Help:  cfg.InboundHelpReply,
+				Stop:  cfg.InboundStopReply,
+				Start: cfg.InboundStartReply,
+			},
+		}
+		webhook.ParseInbound = twilio.ParseInboundMessage
+		webhook.InboundURL = cfg.PublicInboundWebhookURL
 	}
 	webhook.Register(s.Mux)
 
@@ -2,10 +2,13 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -19,19 +22,24 @@ import (
 	"notif/internal/httpserver"
 	"notif/internal/logging"
 	"notif/internal/observability"
+	"notif/internal/phone"
+	"notif/internal/providers"
 	"notif/internal/providers/twilio"
 	sqsqueue "notif/internal/queue/sqs"
+	"notif/internal/ratelimit"
+	"notif/internal/retry"
 	"notif/internal/store/pg"
+	"notif/internal/templates"
 	"notif/internal/util"
 	workerproc "notif/internal/worker"
 
 	"github.com/sony/gobreaker"
-	"golang.org/x/time/rate"
 )
 
 func main() {
 	cfg := config.LoadWorker()
 	logging.Init("worker", cfg.LogFormat)
+	phone.SetDefaultRegion(cfg.PhoneDefaultRegion)
 
 	// Use a root ctx we can cancel
 	ctx, cancel := context.WithCancel(context.Background())
@@ -67,6 +75,22 @@ func main() {
 		VisibilityTimeout: cfg.SQSVizTimeout,
 	}
 
+	lanes, err := sqsqueue.ParseLanes(cfg.SQSLanes)
+	if err != nil {
+		slog.Error("worker sqs lanes invalid", "err", err)
+		os.Exit(1)
+	}
+	poll := consumer.PollConcurrent
+	if len(lanes) > 0 {
+		poll = (&sqsqueue.LaneConsumer{
+			SQS:               sqsClient,
+			Lanes:             lanes,
+			WaitTimeSeconds:   cfg.SQSWaitTime,
+			MaxMessages:       cfg.SQSMaxMsgs,
+			VisibilityTimeout: cfg.SQSVizTimeout,
+		}).PollConcurrent
+	}
+
 	// health server (dependency checks)
 	healthMux := httpserver.New().Mux
 	healthMux.Use(httpserver.Logging)
@@ -101,39 +125,82 @@ func main() {
 		metricsErrCh <- metricsSrv.ListenAndServe()
 	}()
 
-	// Twilio + limiter/breaker + processor
-	sender := &twilio.Client{
-		AccountSID:          cfg.TwilioAccountSID,
-		AuthToken:           cfg.TwilioAuthToken,
-		HTTP:                &http.Client{Timeout: 8 * time.Second},
-		MessagingServiceSID: cfg.TwilioMessagingServiceSID,
-		FromNumber:          cfg.TwilioFromNumber,
-		BaseURL:             cfg.TwilioBaseURL,
-	}
-	limiter := rate.NewLimiter(rate.Limit(cfg.TwilioRPSPerPod), cfg.TwilioBurst)
-	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{
-		Name:        "twilio",
-		MaxRequests: 3,
-		Timeout:     20 * time.Second,
-		ReadyToTrip: func(c gobreaker.Counts) bool { return c.ConsecutiveFailures >= 10 },
-	})
-	templates := map[string]string{
-		"txn_confirm_v1": "Hi {name}, your request is confirmed. Ref: {ref}. Thanks.",
+	// Providers + limiter/breakers + processor
+	registry, err := buildProviders(cfg)
+	if err != nil {
+		slog.Error("worker provider init failed", "err", err)
+		os.Exit(1)
+	}
+	rules, err := providers.ParseRules(cfg.SMSRoutingRules)
+	if err != nil {
+		slog.Error("worker routing rules invalid", "err", err)
+		os.Exit(1)
+	}
+	router, err := providers.NewRouter(registry, cfg.SMSDefaultProvider, rules)
+	if err != nil {
+		slog.Error("worker routing rules invalid", "err", err)
+		os.Exit(1)
+	}
+	retryRules, err := retry.ParseRules(cfg.SMSRetryRules)
+	if err != nil {
+		slog.Error("worker retry rules invalid", "err", err)
+		os.Exit(1)
+	}
+	retryPolicies := &retry.Policies{
+		Default: retry.Policy{
+			MaxAttempts:         cfg.WorkerMaxAttempts,
+			MaxAgeSeconds:       cfg.WorkerRetryMaxAgeSeconds,
+			BaseDelaySeconds:    cfg.WorkerRetryBaseSeconds,
+			Multiplier:          cfg.WorkerRetryMultiplier,
+			MaxDelaySeconds:     cfg.WorkerRetryMaxSeconds,
+			RetryableStatuses:   cfg.WorkerRetryableStatuses,
+			RetryableErrorCodes: cfg.WorkerRetryableErrorCodes,
+		},
+		Rules: retryRules,
+	}
+	if err := retryPolicies.Validate(cfg.SQSMaxReceiveCount); err != nil {
+		slog.Error("worker retry policy invalid", "err", err)
+		os.Exit(1)
+	}
+	breakers := make(map[string]*gobreaker.CircuitBreaker)
+	for _, name := range registry.Names() {
+		breakers[name] = gobreaker.NewCircuitBreaker(gobreaker.Settings{
+			Name:        name,
+			MaxRequests: 3,
+			Timeout:     20 * time.Second,
+			ReadyToTrip: func(c gobreaker.Counts) bool { return c.ConsecutiveFailures >= 10 },
+		})
 	}
+	templateCache := templates.NewCache(store, time.Duration(cfg.TemplateCacheTTLSeconds)*time.Second)
 	processor := &workerproc.Processor{
 		Store:           store,
-		Sender:          sender,
-		Templates:       templates,
-		Limiter:         limiter,
-		Breaker:         cb,
+		Providers:       registry,
+		Router:          router,
+		DefaultProvider: cfg.SMSDefaultProvider,
+		Templates:       templateCache,
+		Limiters:        buildLimiters(cfg, registry),
+		RateLimits: &ratelimit.Limiter{
+			Store:         store,
+			Providers:     map[string]ratelimit.Limit{twilio.ProviderName: {PerSecond: cfg.TwilioAccountMPS}},
+			TenantDefault: ratelimit.Limit{PerSecond: cfg.TenantDefaultRPS, Burst: cfg.TenantDefaultBurst},
+			MaxWait:       time.Duration(cfg.RateLimitMaxWaitMs) * time.Millisecond,
+			TenantTTL:     time.Duration(cfg.RateLimitCacheTTLSeconds) * time.Second,
+			Shards:        cfg.RateLimitShards,
+		},
+		Breakers:        breakers,
 		ClaimStaleAfter: time.Duration(cfg.SQSVizTimeout) * time.Second,
+		MaxReceives:     cfg.SQSMaxReceiveCount,
+		Retry:           retryPolicies,
+	}
+	if polledFIFO(cfg.SQSQueueURL, lanes) {
+		processor.MaxVisibilityDelay = time.Duration(cfg.SQSFIFORetryVisibilitySeconds) * time.Second
 	}
 
 	// start polling
 	pollErrCh := make(chan error, 1)
 	go func() {
-		slog.Info("worker starting poll", "queue_url", cfg.SQSQueueURL)
-		pollErrCh <- consumer.PollConcurrent(ctx, cfg.WorkerConcurrency, func(ctx context.Context, job sqsqueue.SMSJob) (err error) {
+		slog.Info("worker starting poll", "queue_url", cfg.SQSQueueURL, "lanes", len(lanes))
+		pollErrCh <- poll(ctx, cfg.WorkerConcurrency, func(ctx context.Context, job sqsqueue.SMSJob) (err error) {
 			start := util.NowUTC()
 			slog.Info("worker job start", "message_id", job.MessageID)
 			defer func() {
@@ -194,3 +261,69 @@ func main() {
 		slog.Info("worker shutdown timeout waiting for poll loop")
 	}
 }
+
+// buildLimiters gives each provider its own adaptive per-pod limiter. Only twilio has rate
+// settings so far; it starts at TWILIO_RPS_PER_POD, backs off on 429s and climbs back while sends
+// succeed.
+func buildLimiters(cfg config.WorkerConfig, registry *providers.Registry) map[string]workerproc.Limiter {
+	limiters := make(map[string]workerproc.Limiter)
+	for _, name := range registry.Names() {
+		if name != twilio.ProviderName {
+			continue
+		}
+		l := ratelimit.NewAdaptive(name, cfg.TwilioMinRPSPerPod, cfg.TwilioRPSPerPod, cfg.TwilioBurst)
+		l.Increase = cfg.TwilioRateIncreasePerSec
+		l.Decrease = cfg.TwilioRateDecreaseFactor
+		limiters[name] = l
+	}
+	return limiters
+}
+
+// buildProviders registers every provider listed in SMS_PROVIDERS.
+func buildProviders(cfg config.WorkerConfig) (*providers.Registry, error) {
+	registry, err := providers.NewRegistry()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range cfg.SMSProviders {
+		var p providers.Provider
+		switch strings.TrimSpace(name) {
+		case twilio.ProviderName:
+			p = &twilio.Client{
+				AccountSID:          cfg.TwilioAccountSID,
+				AuthToken:           cfg.TwilioAuthToken,
+				HTTP:                &http.Client{Timeout: 8 * time.Second},
+				MessagingServiceSID: cfg.TwilioMessagingServiceSID,
+				FromNumber:          cfg.TwilioFromNumber,
+				BaseURL:             cfg.TwilioBaseURL,
+			}
+		default:
+			return nil, fmt.Errorf("unknown sms provider %q", name)
+		}
+		if err := registry.Register(p); err != nil {
+			return nil, err
+		}
+	}
+	if len(registry.Names()) == 0 {
+		return nil, errors.New("SMS_PROVIDERS is empty")
+	}
+	if cfg.SMSDefaultProvider != "" {
+		if _, ok := registry.Get(cfg.SMSDefaultProvider); !ok {
+			return nil, fmt.Errorf("SMS_DEFAULT_PROVIDER %q is not in SMS_PROVIDERS", cfg.SMSDefaultProvider)
+		}
+	}
+	return registry, nil
+}
+
+// polledFIFO reports whether the worker reads from any FIFO queue.
+func polledFIFO(queueURL string, lanes []sqsqueue.Lane) bool {
+	if len(lanes) == 0 {
+		return sqsqueue.IsFIFO(queueURL)
+	}
+	for _, l := range lanes {
+		if sqsqueue.IsFIFO(l.QueueURL) {
+			return true
+		}
+	}
+	return false
+}
@@ -50,6 +50,8 @@ CREATE TABLE IF NOT EXISTS messages (
 CREATE INDEX IF NOT EXISTS idx_messages_tenant_campaign_created ON messages (tenant_id, campaign_id, created_at);
 CREATE INDEX IF NOT EXISTS idx_messages_tenant_phone_created ON messages (tenant_id, to_phone, created_at);
 CREATE INDEX IF NOT EXISTS idx_messages_provider_msg_id ON messages (provider, provider_msg_id);
+-- Listing API: newest-first cursor pagination over ULID message IDs.
+CREATE INDEX IF NOT EXISTS idx_messages_tenant_id ON messages (tenant_id, id);
 
 CREATE TABLE IF NOT EXISTS provider_attempts (
   id              BIGSERIAL PRIMARY KEY,
@@ -76,4 +78,250 @@ CREATE TABLE IF NOT EXISTS delivery_events (
   received_at   TIMESTAMPTZ NOT NULL DEFAULT now()
 );
 
+ALTER TABLE provider_attempts ADD COLUMN IF NOT EXISTS latency_ms INT NULL; -- provider call duration
+CREATE INDEX IF NOT EXISTS idx_provider_attempts_message ON provider_attempts (message_id, created_at);
+
 CREATE INDEX IF NOT EXISTS idx_delivery_events_provider_msg ON delivery_events (provider, provider_msg_id);
+
+-- Transactional outbox: written in the same transaction as the messages row,
+-- published to SQS by the relay (cmd/api) and marked sent.
+CREATE TABLE IF NOT EXISTS outbox (
+  id              BIGSERIAL PRIMARY KEY,
+  message_id      TEXT NOT NULL REFERENCES messages(id),
+  payload_json    JSONB NOT NULL,   -- SQS SMSJob
+  state           TEXT NOT NULL DEFAULT 'pending', -- pending|sent|canceled
+  attempts        INT NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(), -- also used as the relay lease
+  last_error      TEXT NULL,
+  created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+  sent_at         TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox (next_attempt_at) WHERE state = 'pending';
+
+-- Tenant API keys. Only the SHA-256 of the key is stored; the plaintext is returned once on create/rotate.
+CREATE TABLE IF NOT EXISTS api_keys (
+  id         TEXT PRIMARY KEY,
+  tenant_id  TEXT NOT NULL REFERENCES tenants(id),
+  name       TEXT NOT NULL,
+  key_prefix TEXT NOT NULL,        -- first characters of the key, for identification in UIs/logs
+  key_hash   TEXT NOT NULL UNIQUE, -- hex sha256 of the full key
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  revoked_at TIMESTAMPTZ NULL      -- key stops working at this time (in the future during a rotation grace period)
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_tenant ON api_keys (tenant_id, created_at);
+
+-- Lifecycle events not captured elsewhere (worker claims), for the message timeline API.
+CREATE TABLE IF NOT EXISTS message_events (
+  id         BIGSERIAL PRIMARY KEY,
+  message_id TEXT NOT NULL REFERENCES messages(id),
+  event      TEXT NOT NULL, -- claimed | canceled | expired
+  detail     TEXT NULL,     -- claimed: state the message was claimed from
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_events_message ON message_events (message_id, created_at);
+
+-- Append-only consent history; consents holds only the current status.
+CREATE TABLE IF NOT EXISTS consent_events (
+  id         BIGSERIAL PRIMARY KEY,
+  tenant_id  TEXT NOT NULL,
+  phone      TEXT NOT NULL,
+  channel    TEXT NOT NULL,
+  status     TEXT NOT NULL, -- opted_in | opted_out | deleted
+  source     TEXT NOT NULL, -- api | keyword | import
+  actor      TEXT NULL,     -- who captured the change, as reported by the caller
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_consent_events_phone ON consent_events (tenant_id, phone, channel, created_at);
+
+-- Temporary suppressions stop applying after expires_at (NULL = permanent).
+ALTER TABLE suppression_list ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ NULL;
+
+-- Tenant templates. Each edit cycle is a new version; sends use the most recently published one.
+CREATE TABLE IF NOT EXISTS templates (
+  tenant_id    TEXT NOT NULL REFERENCES tenants(id),
+  template_id  TEXT NOT NULL,
+  version      INT NOT NULL,
+  body         TEXT NOT NULL,
+  status       TEXT NOT NULL DEFAULT 'draft', -- draft|published|archived
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+  published_at TIMESTAMPTZ NULL,
+  PRIMARY KEY (tenant_id, template_id, version)
+);
+
+CREATE INDEX IF NOT EXISTS idx_templates_published ON templates (tenant_id, template_id, published_at DESC) WHERE status = 'published';
+
+-- Rendered body accounting (set when the message is submitted to a provider).
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS encoding TEXT NULL; -- GSM-7 | UCS-2
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS segments INT NULL;
+
+-- Per-template segment limit and GSM-7 transliteration.
+ALTER TABLE templates ADD COLUMN IF NOT EXISTS max_segments INT NULL;
+ALTER TABLE templates ADD COLUMN IF NOT EXISTS transliterate BOOLEAN NOT NULL DEFAULT false;
+
+-- ISO region of to_phone, derived from the E.164 number at accept time (routing and reporting).
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS country TEXT NULL;
+
+-- Rows written before phone numbers were normalized still hold the raw input ("+1 (555)
+-- 123-4567", "0044 20 7946 0958"). Rewrite the international ones to E.164 so sends find them,
+-- merging rows that now collide. National numbers ("5551234567") are left as they are: their
+-- country is the deployment's PHONE_DEFAULT_REGION, which this script can't see, and guessing
+-- would move keys to the wrong country. Once everything is E.164 this only costs a scan.
+CREATE OR REPLACE FUNCTION pg_temp.e164(raw TEXT) RETURNS TEXT LANGUAGE sql IMMUTABLE AS $$
+  SELECT CASE
+    WHEN digits = '' THEN raw
+    WHEN btrim(raw) LIKE '+%' THEN '+' || digits
+    WHEN btrim(raw) LIKE '00%' THEN '+' || substr(digits, 3)
+    ELSE raw
+  END
+  FROM (SELECT regexp_replace(raw, '[^0-9]', '', 'g') AS digits) d
+$$;
+
+-- Suppressions: keep the strongest (permanent, else the latest expiry).
+INSERT INTO suppression_list (tenant_id, phone, reason, created_at, expires_at)
+SELECT DISTINCT ON (tenant_id, pg_temp.e164(phone)) tenant_id, pg_temp.e164(phone), reason, created_at, expires_at
+FROM suppression_list WHERE phone <> pg_temp.e164(phone)
+ORDER BY tenant_id, pg_temp.e164(phone), expires_at DESC NULLS FIRST
+ON CONFLICT (tenant_id, phone) DO UPDATE
+SET reason=EXCLUDED.reason, created_at=EXCLUDED.created_at, expires_at=EXCLUDED.expires_at
+WHERE suppression_list.expires_at IS NOT NULL
+  AND (EXCLUDED.expires_at IS NULL OR EXCLUDED.expires_at > suppression_list.expires_at);
+DELETE FROM suppression_list WHERE phone <> pg_temp.e164(phone);
+
+-- Consents: keep the latest decision; the history moves with it.
+INSERT INTO consents (tenant_id, phone, channel, status, updated_at)
+SELECT DISTINCT ON (tenant_id, pg_temp.e164(phone), channel) tenant_id, pg_temp.e164(phone), channel, status, updated_at
+FROM consents WHERE phone <> pg_temp.e164(phone)
+ORDER BY tenant_id, pg_temp.e164(phone), channel, updated_at DESC
+ON CONFLICT (tenant_id, phone, channel) DO UPDATE
+SET status=EXCLUDED.status, updated_at=EXCLUDED.updated_at
+WHERE EXCLUDED.updated_at > consents.updated_at;
+DELETE FROM consents WHERE phone <> pg_temp.e164(phone);
+UPDATE consent_events SET phone = pg_temp.e164(phone) WHERE phone <> pg_temp.e164(phone);
+
+-- Daily caps: counts for the same number and day add up.
+INSERT INTO send_caps_daily (tenant_id, phone, day, count, updated_at)
+SELECT tenant_id, pg_temp.e164(phone), day, sum(count), max(updated_at)
+FROM send_caps_daily WHERE phone <> pg_temp.e164(phone)
+GROUP BY tenant_id, pg_temp.e164(phone), day
+ON CONFLICT (tenant_id, phone, day) DO UPDATE
+SET count=send_caps_daily.count + EXCLUDED.count, updated_at=GREATEST(send_caps_daily.updated_at, EXCLUDED.updated_at);
+DELETE FROM send_caps_daily WHERE phone <> pg_temp.e164(phone);
+
+-- Per-tenant destination policy (SMS-pumping protection). No row means every destination is allowed.
+CREATE TABLE IF NOT EXISTS country_policies (
+  tenant_id          TEXT PRIMARY KEY REFERENCES tenants(id),
+  allowed_countries  TEXT[] NOT NULL DEFAULT '{}',  -- ISO regions; empty allows all
+  blocked_prefixes   TEXT[] NOT NULL DEFAULT '{}',  -- E.164 prefixes, e.g. '+1900'
+  country_daily_caps JSONB NOT NULL DEFAULT '{}',   -- {"NG": 500}
+  updated_at         TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS send_caps_country_daily (
+  tenant_id  TEXT NOT NULL,
+  country    TEXT NOT NULL,
+  day        DATE NOT NULL,
+  count      INT NOT NULL DEFAULT 0,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (tenant_id, country, day)
+);
+
+-- Delivery windows (quiet hours), evaluated in the recipient's local time. campaign_id '' is the
+-- tenant default; a campaign row overrides it.
+CREATE TABLE IF NOT EXISTS delivery_windows (
+  tenant_id    TEXT NOT NULL REFERENCES tenants(id),
+  campaign_id  TEXT NOT NULL DEFAULT '',
+  start_minute INT NOT NULL,                 -- minutes after local midnight
+  end_minute   INT NOT NULL,                 -- <= start_minute wraps past midnight
+  days         TEXT[] NOT NULL DEFAULT '{}', -- mon..sun; empty = every day
+  timezone     TEXT NOT NULL DEFAULT 'UTC',  -- used when the number's zone is ambiguous
+  updated_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (tenant_id, campaign_id)
+);
+
+-- Transactional templates bypass delivery windows.
+ALTER TABLE templates ADD COLUMN IF NOT EXISTS transactional BOOLEAN NOT NULL DEFAULT false;
+
+-- Scheduled messages are held (their outbox row is not due) until scheduled_at.
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS scheduled_at TIMESTAMPTZ NULL;
+
+-- Messages still pending at expires_at are moved to 'expired' by the worker instead of being sent.
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ NULL;
+
+-- Outbox rows are published highest priority first (2 high, 1 normal, 0 low) so a large campaign
+-- backlog doesn't hold up transactional messages.
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS priority SMALLINT NOT NULL DEFAULT 1;
+CREATE INDEX IF NOT EXISTS idx_outbox_pending_priority ON outbox (priority DESC, next_attempt_at) WHERE state = 'pending';
+
+-- Token buckets shared by all worker pods ("provider:<name>", "tenant:<id>", with a "#<n>" shard
+-- suffix for fast buckets). tokens goes negative while sends are waiting for their reserved slot.
+CREATE TABLE IF NOT EXISTS rate_buckets (
+  key        TEXT PRIMARY KEY,
+  tokens     DOUBLE PRECISION NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL
+);
+
+-- Per-tenant send rate overrides; tenants without a row use the worker's default.
+CREATE TABLE IF NOT EXISTS tenant_rate_limits (
+  tenant_id  TEXT PRIMARY KEY REFERENCES tenants(id),
+  per_second DOUBLE PRECISION NOT NULL,
+  burst      INT NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Normalized failure reasons (invalid_number, landline, carrier_blocked, ...) next to the vendor's
+-- raw error codes, mapped by the provider integration.
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS failure_reason TEXT NULL;
+ALTER TABLE provider_attempts ADD COLUMN IF NOT EXISTS failure_reason TEXT NULL;
+ALTER TABLE delivery_events ADD COLUMN IF NOT EXISTS failure_reason TEXT NULL;
+
+-- Receives that reached a provider and were handed back to SQS. The retry policy counts attempts
+-- from this rather than from SQS receives, which rate limits and open breakers use up too.
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS retries INT NOT NULL DEFAULT 0;
+
+-- Consecutive failed deliveries per number with the same failure reason, for auto-suppression.
+-- A delivery removes the row; last_provider_msg_id keeps repeated callbacks from counting twice.
+CREATE TABLE IF NOT EXISTS delivery_failure_streaks (
+  tenant_id            TEXT NOT NULL,
+  phone                TEXT NOT NULL,
+  failure_reason       TEXT NOT NULL,
+  failures             INT NOT NULL,
+  last_provider_msg_id TEXT NOT NULL,
+  updated_at           TIMESTAMPTZ NOT NULL,
+  PRIMARY KEY (tenant_id, phone)
+);
+
+-- SMS received on our sender numbers. keyword is the STOP/START/HELP keyword recognized in body.
+CREATE TABLE IF NOT EXISTS inbound_messages (
+  id              BIGSERIAL PRIMARY KEY,
+  provider        TEXT NOT NULL,
+  provider_msg_id TEXT NOT NULL,
+  from_phone      TEXT NOT NULL,
+  to_phone        TEXT NOT NULL,
+  body            TEXT NOT NULL,
+  keyword         TEXT NULL, -- stop | start | help
+  received_at     TIMESTAMPTZ NOT NULL,
+  UNIQUE (provider, provider_msg_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_inbound_messages_from ON inbound_messages (from_phone, received_at);
+
+-- Sender numbers are shared, so an inbound keyword applies to every tenant holding a consent for
+-- the number.
+CREATE INDEX IF NOT EXISTS idx_consents_phone ON consents (phone, channel);
+
+-- Keywords are answered as the tenant whose message the number received last.
+CREATE INDEX IF NOT EXISTS idx_messages_phone_created ON messages (to_phone, created_at DESC);
+
+-- Tenant auto-replies to inbound keywords; empty replies use the webhook's defaults.
+CREATE TABLE IF NOT EXISTS keyword_replies (
+  tenant_id  TEXT PRIMARY KEY REFERENCES tenants(id),
+  help_text  TEXT NOT NULL DEFAULT '',
+  stop_text  TEXT NOT NULL DEFAULT '',
+  start_text TEXT NOT NULL DEFAULT '',
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -22,3 +22,8 @@ VALUES
   ('foodapp', '+917777777777', 'manual_block')
 ON CONFLICT (tenant_id, phone)
 DO UPDATE SET reason = EXCLUDED.reason, created_at = now();
+
+-- 4) Templates (used by the k6 load tests)
+INSERT INTO templates (tenant_id, template_id, version, body, transactional, status, published_at)
+VALUES ('foodapp', 'txn_confirm_v1', 1, 'Hi {name}, your request is confirmed. Ref: {ref}. Thanks.', true, 'published', now())
+ON CONFLICT (tenant_id, template_id, version) DO NOTHING;
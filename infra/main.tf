@@ -1098,7 +1098,47 @@ resource "aws_sqs_queue" "main" {
 
   redrive_policy = jsonencode({
     deadLetterTargetArn = aws_sqs_queue.dlq.arn
-    maxReceiveCount     = 5
+    # Rate limits and open breakers hand messages back without a send; keep in sync with
+    # SQS_MAX_RECEIVE_COUNT so the worker fails them before they get here.
+    maxReceiveCount     = 20
+  })
+}
+
+# Priority lanes (SQS_HIGH_PRIORITY_QUEUE_URL / SQS_LOW_PRIORITY_QUEUE_URL): OTPs and password
+# resets skip the campaign backlog on the main queue. Same settings and redrive as main.
+resource "aws_sqs_queue" "high_dlq" {
+  name                        = "${local.name}-send-high-dlq.fifo"
+  fifo_queue                  = true
+  content_based_deduplication = true
+}
+
+resource "aws_sqs_queue" "high" {
+  name                        = "${local.name}-send-high.fifo"
+  fifo_queue                  = true
+  content_based_deduplication = true
+  visibility_timeout_seconds  = 60
+
+  redrive_policy = jsonencode({
+    deadLetterTargetArn = aws_sqs_queue.high_dlq.arn
+    maxReceiveCount     = 20
+  })
+}
+
+resource "aws_sqs_queue" "low_dlq" {
+  name                        = "${local.name}-send-low-dlq.fifo"
+  fifo_queue                  = true
+  content_based_deduplication = true
+}
+
+resource "aws_sqs_queue" "low" {
+  name                        = "${local.name}-send-low.fifo"
+  fifo_queue                  = true
+  content_based_deduplication = true
+  visibility_timeout_seconds  = 60
+
+  redrive_policy = jsonencode({
+    deadLetterTargetArn = aws_sqs_queue.low_dlq.arn
+    maxReceiveCount     = 20
   })
 }
 
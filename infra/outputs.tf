@@ -30,6 +30,10 @@ output "db_password" {
 
 output "sqs_main_url" { value = aws_sqs_queue.main.url }
 output "sqs_dlq_url" { value = aws_sqs_queue.dlq.url }
+output "sqs_high_url" { value = aws_sqs_queue.high.url }
+output "sqs_high_dlq_url" { value = aws_sqs_queue.high_dlq.url }
+output "sqs_low_url" { value = aws_sqs_queue.low.url }
+output "sqs_low_dlq_url" { value = aws_sqs_queue.low_dlq.url }
 output "sqs_webhook_events_url" { value = aws_sqs_queue.webhook_events.url }
 output "sqs_webhook_events_dlq_url" { value = aws_sqs_queue.webhook_events_dlq.url }
 
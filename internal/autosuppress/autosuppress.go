@@ -0,0 +1,138 @@
+// Package autosuppress adds numbers to the suppression list after hard delivery failures, so we
+// stop paying for messages to numbers that can't receive them.
+package autosuppress
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"notif/internal/observability"
+	"notif/internal/providers"
+	"notif/internal/store"
+)
+
+// ReasonPrefix marks suppressions added by this package; the failure reason follows it.
+const ReasonPrefix = "auto:"
+
+// Rule suppresses a number after After consecutive failed deliveries matching Reasons (our
+// failure taxonomy) or ErrorCodes (the provider's codes).
+type Rule struct {
+	Reasons    []string `json:"reasons,omitempty"`
+	ErrorCodes []string `json:"errorCodes,omitempty"`
+	// After defaults to 1.
+	After int `json:"after,omitempty"`
+	// TTLSeconds makes the suppression temporary; 0 suppresses for good.
+	TTLSeconds int `json:"ttlSeconds,omitempty"`
+}
+
+func (r Rule) matches(reason, errorCode string) bool {
+	return slices.Contains(r.Reasons, reason) || (errorCode != "" && slices.Contains(r.ErrorCodes, errorCode))
+}
+
+func (r Rule) after() int {
+	return max(r.After, 1)
+}
+
+// DefaultRules suppress numbers that don't exist or can't take SMS at once, and numbers that
+// keep being unreachable for 30 days.
+var DefaultRules = []Rule{
+	{Reasons: []string{string(providers.ReasonInvalidNumber), string(providers.ReasonLandline)}},
+	{Reasons: []string{string(providers.ReasonUnreachable)}, After: 3, TTLSeconds: 30 * 24 * 3600},
+}
+
+// ParseRules decodes the AUTO_SUPPRESS_RULES JSON array, e.g.
+// [{"reasons":["invalid_number"]},{"reasons":["unreachable"],"after":3,"ttlSeconds":2592000}].
+// Empty input yields DefaultRules; "[]" turns auto-suppression off.
+func ParseRules(raw string) ([]Rule, error) {
+	if strings.TrimSpace(raw) == "" {
+		return DefaultRules, nil
+	}
+	var rules []Rule
+	if err := json.Unmarshal([]byte(raw), &rules); err != nil {
+		return nil, fmt.Errorf("invalid auto-suppress rules: %w", err)
+	}
+	for i, r := range rules {
+		if len(r.Reasons) == 0 && len(r.ErrorCodes) == 0 {
+			return nil, fmt.Errorf("auto-suppress rule %d: no reasons or errorCodes", i)
+		}
+		if r.After < 0 || r.TTLSeconds < 0 {
+			return nil, fmt.Errorf("auto-suppress rule %d: negative after or ttlSeconds", i)
+		}
+	}
+	return rules, nil
+}
+
+// Store is the persistence autosuppress needs; the Postgres store implements it.
+type Store interface {
+	RecordDeliveryFailure(ctx context.Context, provider, providerMsgID, reason string, now time.Time) (store.FailureStreak, bool, error)
+	ResetDeliveryFailures(ctx context.Context, provider, providerMsgID string) error
+	AutoSuppress(ctx context.Context, sp store.Suppression) (bool, error)
+}
+
+// Policy applies Rules to terminal delivery receipts. The first matching rule wins; a failure no
+// rule matches ends the streak like a delivery does, since it breaks the run of consecutive
+// matching failures.
+type Policy struct {
+	Store Store
+	Rules []Rule
+}
+
+// Receipt is a terminal delivery receipt already applied to its message.
+type Receipt struct {
+	Provider      string
+	ProviderMsgID string
+	State         string // delivered | failed
+	ErrorCode     string
+	FailureReason string
+}
+
+// Apply updates the number's failure streak and suppresses it once a rule's threshold is reached.
+func (p *Policy) Apply(ctx context.Context, rc Receipt, now time.Time) error {
+	if p == nil || len(p.Rules) == 0 {
+		return nil
+	}
+	switch rc.State {
+	case "delivered":
+		return p.Store.ResetDeliveryFailures(ctx, rc.Provider, rc.ProviderMsgID)
+	case "failed":
+	default:
+		return nil
+	}
+
+	i := slices.IndexFunc(p.Rules, func(r Rule) bool { return r.matches(rc.FailureReason, rc.ErrorCode) })
+	if i < 0 {
+		return p.Store.ResetDeliveryFailures(ctx, rc.Provider, rc.ProviderMsgID)
+	}
+	rule := p.Rules[i]
+	reason := rc.FailureReason
+	if reason == "" {
+		reason = string(providers.ReasonUnknown)
+	}
+	streak, found, err := p.Store.RecordDeliveryFailure(ctx, rc.Provider, rc.ProviderMsgID, reason, now)
+	if err != nil || !found || streak.Failures < rule.after() {
+		return err
+	}
+
+	sp := store.Suppression{
+		TenantID:  streak.TenantID,
+		Phone:     streak.Phone,
+		Reason:    ReasonPrefix + reason,
+		CreatedAt: now,
+	}
+	if rule.TTLSeconds > 0 {
+		exp := now.Add(time.Duration(rule.TTLSeconds) * time.Second)
+		sp.ExpiresAt = &exp
+	}
+	added, err := p.Store.AutoSuppress(ctx, sp)
+	if err != nil {
+		return err
+	}
+	if added {
+		observability.AutoSuppressions.WithLabelValues(reason).Inc()
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package autosuppress
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"notif/internal/store"
+)
+
+type fakeStore struct {
+	streaks    map[string]store.FailureStreak // by phone
+	lastMsg    map[string]string
+	suppressed []store.Suppression
+}
+
+func (f *fakeStore) RecordDeliveryFailure(ctx context.Context, provider, providerMsgID, reason string, now time.Time) (store.FailureStreak, bool, error) {
+	phone := "+1555" + providerMsgID[:3]
+	st := f.streaks[phone]
+	switch {
+	case st.FailureReason != reason:
+		st = store.FailureStreak{TenantID: "t1", Phone: phone, FailureReason: reason, Failures: 1}
+	case f.lastMsg[phone] != providerMsgID:
+		st.Failures++
+	}
+	f.streaks[phone], f.lastMsg[phone] = st, providerMsgID
+	return st, true, nil
+}
+
+func (f *fakeStore) ResetDeliveryFailures(ctx context.Context, provider, providerMsgID string) error {
+	delete(f.streaks, "+1555"+providerMsgID[:3])
+	return nil
+}
+
+func (f *fakeStore) AutoSuppress(ctx context.Context, sp store.Suppression) (bool, error) {
+	f.suppressed = append(f.suppressed, sp)
+	delete(f.streaks, sp.Phone)
+	return true, nil
+}
+
+func TestPolicy(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fs := &fakeStore{streaks: map[string]store.FailureStreak{}, lastMsg: map[string]string{}}
+	p := &Policy{Store: fs, Rules: DefaultRules}
+	fail := func(msgID, reason string) {
+		t.Helper()
+		if err := p.Apply(ctx, Receipt{Provider: "twilio", ProviderMsgID: msgID, State: "failed", FailureReason: reason}, now); err != nil {
+			t.Fatalf("apply: %v", err)
+		}
+	}
+
+	// One invalid-number receipt is enough, for good.
+	fail("111-a", "invalid_number")
+	if len(fs.suppressed) != 1 || fs.suppressed[0].Reason != "auto:invalid_number" || fs.suppressed[0].ExpiresAt != nil {
+		t.Fatalf("expected a permanent suppression, got %+v", fs.suppressed)
+	}
+
+	// Unreachable takes three consecutive failures; a repeated callback and a delivery don't count.
+	fail("222-a", "unreachable")
+	fail("222-a", "unreachable")
+	fail("222-b", "unreachable")
+	if err := p.Apply(ctx, Receipt{Provider: "twilio", ProviderMsgID: "222-c", State: "delivered"}, now); err != nil {
+		t.Fatalf("apply delivered: %v", err)
+	}
+	fail("222-d", "unreachable")
+	fail("222-e", "unreachable")
+	if len(fs.suppressed) != 1 {
+		t.Fatalf("expected no new suppression yet, got %+v", fs.suppressed)
+	}
+	fail("222-f", "unreachable")
+	if len(fs.suppressed) != 2 || fs.suppressed[1].ExpiresAt == nil || !fs.suppressed[1].ExpiresAt.Equal(now.Add(30*24*time.Hour)) {
+		t.Fatalf("expected a 30 day suppression, got %+v", fs.suppressed)
+	}
+
+	// Suppressing ends the streak: after the suppression lapses it takes three failures again.
+	if _, ok := fs.streaks["+1555222"]; ok {
+		t.Fatal("expected the streak to end with the suppression")
+	}
+
+	// Failures no rule covers don't count, and break a streak like a delivery.
+	fail("333-a", "spam_filtered")
+	if _, ok := fs.streaks["+1555333"]; ok || len(fs.suppressed) != 2 {
+		t.Fatal("expected spam_filtered to be ignored")
+	}
+	fail("444-a", "unreachable")
+	fail("444-b", "unreachable")
+	fail("444-c", "spam_filtered")
+	fail("444-d", "unreachable")
+	if st := fs.streaks["+1555444"]; st.Failures != 1 || len(fs.suppressed) != 2 {
+		t.Fatalf("expected the streak to restart after spam_filtered, got %+v", st)
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	if rules, err := ParseRules(""); err != nil || len(rules) != len(DefaultRules) {
+		t.Fatalf("expected default rules, got %+v %v", rules, err)
+	}
+	if rules, err := ParseRules("[]"); err != nil || len(rules) != 0 {
+		t.Fatalf("expected no rules, got %+v %v", rules, err)
+	}
+	rules, err := ParseRules(`[{"errorCodes":["30005"],"after":2}]`)
+	if err != nil || len(rules) != 1 || !rules[0].matches("unknown", "30005") || rules[0].after() != 2 {
+		t.Fatalf("unexpected rules %+v %v", rules, err)
+	}
+	for _, bad := range []string{`{`, `[{"after":1}]`, `[{"reasons":["x"],"ttlSeconds":-1}]`} {
+		if _, err := ParseRules(bad); err == nil {
+			t.Fatalf("expected error for %s", bad)
+		}
+	}
+}
@@ -13,14 +13,35 @@ type APIConfig struct {
 	MetricsPort             string `envconfig:"METRICS_PORT" default:"9090"`
 	LogFormat               string `envconfig:"LOG_FORMAT" default:"json"`
 
+	// Auth: tenant API keys on /v1, static admin token on /v1/admin (admin routes are off when empty)
+	APIAuthEnabled bool   `envconfig:"API_AUTH_ENABLED" default:"true"`
+	AdminAPIToken  string `envconfig:"ADMIN_API_TOKEN"`
+
 	// multi-tenant rails
 	MaxSMSPerDay int `envconfig:"MAX_SMS_PER_DAY" default:"2"`
+	// Max recipients accepted by POST /v1/sms/messages:batch
+	MaxBatchSize int `envconfig:"SMS_BATCH_MAX_RECIPIENTS" default:"500"`
+	// How long a published template is cached before re-reading it from the DB
+	TemplateCacheTTLSeconds int `envconfig:"TEMPLATE_CACHE_TTL_SECONDS" default:"30"`
+	// Region assumed for phone numbers sent without a country code (ISO 3166-1 alpha-2)
+	PhoneDefaultRegion string `envconfig:"PHONE_DEFAULT_REGION" default:"US"`
 
 	// AWS / SQS
 	AWSRegion          string `envconfig:"AWS_REGION" required:"true"`
 	SQSQueueURL        string `envconfig:"SQS_QUEUE_URL" required:"true"`
 	LocalstackEndpoint string `envconfig:"LOCALSTACK_ENDPOINT"`
 	SQSGroupBuckets    int    `envconfig:"SQS_GROUP_BUCKETS" default:"2000"`
+	// Optional priority lanes; high/low priority messages go to SQS_QUEUE_URL when unset
+	SQSHighPriorityQueueURL string `envconfig:"SQS_HIGH_PRIORITY_QUEUE_URL"`
+	SQSLowPriorityQueueURL  string `envconfig:"SQS_LOW_PRIORITY_QUEUE_URL"`
+
+	// Outbox relay (publishes committed messages to SQS)
+	OutboxRelayEnabled   bool `envconfig:"OUTBOX_RELAY_ENABLED" default:"true"`
+	OutboxBatchSize      int  `envconfig:"OUTBOX_BATCH_SIZE" default:"100"`
+	OutboxPollIntervalMs int  `envconfig:"OUTBOX_POLL_INTERVAL_MS" default:"200"`
+	// Scheduled messages due within this many seconds are published early with SQS DelaySeconds
+	// (standard queues only, max 900); later ones wait in the outbox until due.
+	OutboxDelayHorizonSeconds int `envconfig:"OUTBOX_DELAY_HORIZON_SECONDS" default:"900"`
 }
 
 type WorkerConfig struct {
@@ -41,8 +62,44 @@ type WorkerConfig struct {
 	SQSWaitTime        int32  `envconfig:"SQS_WAIT_TIME" default:"20"`
 	SQSMaxMsgs         int32  `envconfig:"SQS_MAX_MSGS" default:"10"`
 	SQSVizTimeout      int32  `envconfig:"SQS_VISIBILITY_TIMEOUT" default:"60"`
+	// Must match the send queues' redrive maxReceiveCount (infra/main.tf): the worker fails a
+	// message on its last receive rather than let SQS dead-letter it.
+	SQSMaxReceiveCount int `envconfig:"SQS_MAX_RECEIVE_COUNT" default:"20"`
+	// JSON array of priority lanes sharing the worker pool by weight, e.g.
+	// [{"queueUrl":".../sms-high","weight":8},{"queueUrl":".../sms","weight":3},{"queueUrl":".../sms-low","weight":1}]
+	// Empty polls SQS_QUEUE_URL only.
+	SQSLanes string `envconfig:"SQS_LANES"`
+	// FIFO queues only: the longest a retry may hide its message, which holds up the rest of its
+	// message group meanwhile. Longer retries are republished through the outbox when due.
+	SQSFIFORetryVisibilitySeconds int `envconfig:"SQS_FIFO_RETRY_VISIBILITY_SECONDS" default:"5"`
 
 	WorkerConcurrency int `envconfig:"WORKER_CONCURRENCY" default:"20"`
+	// Default retry policy: retryable send failures go back to SQS with a jittered exponential
+	// delay (visibility timeout) until the attempt or age limit (0 = none). Statuses and error codes
+	// override the provider's own retryable/permanent call. Attempts only count receives that reached a provider.
+	WorkerMaxAttempts         int      `envconfig:"WORKER_MAX_ATTEMPTS" default:"5"`
+	WorkerRetryMaxAgeSeconds  int      `envconfig:"WORKER_RETRY_MAX_AGE_SECONDS" default:"0"`
+	WorkerRetryBaseSeconds    float64  `envconfig:"WORKER_RETRY_BASE_SECONDS" default:"2"`
+	WorkerRetryMultiplier     float64  `envconfig:"WORKER_RETRY_MULTIPLIER" default:"2"`
+	WorkerRetryMaxSeconds     float64  `envconfig:"WORKER_RETRY_MAX_SECONDS" default:"300"`
+	WorkerRetryableStatuses   []int    `envconfig:"WORKER_RETRYABLE_STATUSES"`
+	WorkerRetryableErrorCodes []string `envconfig:"WORKER_RETRYABLE_ERROR_CODES"`
+	// JSON array of per tenant/template overrides, first match wins; the worker refuses to start
+	// with attempts or ages SQS_MAX_RECEIVE_COUNT can't honor. E.g.
+	// [{"templateId":"otp","maxAttempts":2,"maxAgeSeconds":120},{"tenantId":"acme","templateId":"invoice","maxAttempts":20,"maxAgeSeconds":7200,"maxDelaySeconds":1800}]
+	SMSRetryRules string `envconfig:"SMS_RETRY_RULES"`
+
+	// Providers (comma-separated registry names; only "twilio" is built in today)
+	SMSProviders       []string `envconfig:"SMS_PROVIDERS" default:"twilio"`
+	SMSDefaultProvider string   `envconfig:"SMS_DEFAULT_PROVIDER" default:"twilio"`
+	// JSON array of routing rules, e.g.
+	// [{"tenantId":"foodapp","country":"IN","split":[{"provider":"twilio","weight":100}],"failover":["other"]}]
+	SMSRoutingRules string `envconfig:"SMS_ROUTING_RULES"`
+	// Region assumed for numbers without a country code when matching "country" routing rules
+	PhoneDefaultRegion string `envconfig:"PHONE_DEFAULT_REGION" default:"US"`
+
+	// Templates are read from the DB and cached per pod; edits are picked up after the TTL
+	TemplateCacheTTLSeconds int `envconfig:"TEMPLATE_CACHE_TTL_SECONDS" default:"30"`
 
 	// Twilio
 	TwilioAccountSID          string  `envconfig:"TWILIO_ACCOUNT_SID" required:"true"`
@@ -52,6 +109,20 @@ type WorkerConfig struct {
 	TwilioBaseURL             string  `envconfig:"TWILIO_BASE_URL" default:"https://api.twilio.com"`
 	TwilioRPSPerPod           float64 `envconfig:"TWILIO_RPS_PER_POD" default:"5"`
 	TwilioBurst               int     `envconfig:"TWILIO_BURST" default:"10"`
+	// Adaptive per-pod rate (AIMD): on 429 the rate is multiplied by the decrease factor (not
+	// below the minimum); while sends succeed it grows by the increase per second, up to TWILIO_RPS_PER_POD.
+	TwilioMinRPSPerPod       float64 `envconfig:"TWILIO_MIN_RPS_PER_POD" default:"0.5"`
+	TwilioRateIncreasePerSec float64 `envconfig:"TWILIO_RATE_INCREASE_PER_SEC" default:"0.25"`
+	TwilioRateDecreaseFactor float64 `envconfig:"TWILIO_RATE_DECREASE_FACTOR" default:"0.5"`
+
+	// Shared rate limits (token buckets in Postgres, enforced across all worker pods; 0 = unlimited)
+	TwilioAccountMPS         float64 `envconfig:"TWILIO_ACCOUNT_MPS" default:"0"`
+	TenantDefaultRPS         float64 `envconfig:"TENANT_DEFAULT_RPS" default:"0"`
+	TenantDefaultBurst       int     `envconfig:"TENANT_DEFAULT_BURST" default:"0"`
+	RateLimitMaxWaitMs       int     `envconfig:"RATE_LIMIT_MAX_WAIT_MS" default:"5000"`
+	RateLimitCacheTTLSeconds int     `envconfig:"RATE_LIMIT_CACHE_TTL_SECONDS" default:"30"`
+	// Fast buckets are split over up to this many rows so sends don't contend on one row lock.
+	RateLimitShards int `envconfig:"RATE_LIMIT_SHARDS" default:"8"`
 }
 
 type WebhookConfig struct {
@@ -75,6 +146,17 @@ type WebhookConfig struct {
 	WebhookEventsQueueURL     string `envconfig:"WEBHOOK_EVENTS_QUEUE_URL"`
 	AWSRegion                 string `envconfig:"AWS_REGION" default:"ap-south-1"`
 	LocalstackEndpoint        string `envconfig:"LOCALSTACK_ENDPOINT"`
+
+	// JSON array of auto-suppression rules for hard-failure receipts (see autosuppress.ParseRules).
+	// Empty uses the defaults; "[]" disables it.
+	AutoSuppressRules string `envconfig:"AUTO_SUPPRESS_RULES"`
+
+	// Incoming SMS (STOP/START/HELP). Enabled when the URL is set; it must match the URL configured
+	// in Twilio exactly. Needs the DB even in queue mode. Tenants can override the replies.
+	PublicInboundWebhookURL string `envconfig:"PUBLIC_INBOUND_WEBHOOK_URL"`
+	InboundHelpReply        string `envconfig:"INBOUND_HELP_REPLY" default:"Reply STOP to unsubscribe or START to resubscribe. Msg&data rates may apply."`
+	InboundStopReply        string `envconfig:"INBOUND_STOP_REPLY" default:"You are unsubscribed and will receive no further messages. Reply START to resubscribe."`
+	InboundStartReply       string `envconfig:"INBOUND_START_REPLY" default:"You are resubscribed. Reply STOP to unsubscribe."`
 }
 
 type WebhookProcessorConfig struct {
@@ -99,6 +181,9 @@ type WebhookProcessorConfig struct {
 	SQSVizTimeout int32 `envconfig:"WEBHOOK_SQS_VISIBILITY_TIMEOUT" default:"60"`
 
 	ProcessorConcurrency      int  `envconfig:"WEBHOOK_PROCESSOR_CONCURRENCY" default:"20"`
+
+	// Same format as the webhook's; receipts enqueued in queue mode are checked here.
+	AutoSuppressRules string `envconfig:"AUTO_SUPPRESS_RULES"`
 }
 
 func LoadAPI() APIConfig {
@@ -1,6 +1,12 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
 
 type MessageState string
 
@@ -11,8 +17,19 @@ const (
 	StateSubmitted  MessageState = "submitted"
 	StateDelivered  MessageState = "delivered"
 	StateFailed     MessageState = "failed"
+	// StateScheduled messages are accepted but held until a later time (sendAt or quiet hours).
+	StateScheduled MessageState = "scheduled"
+	// StateCanceled messages were withdrawn by the tenant before a worker picked them up.
+	StateCanceled MessageState = "canceled"
+	// StateExpired messages reached their expiresAt before a worker could send them.
+	StateExpired MessageState = "expired"
 )
 
+// Pending reports whether a message in this state is waiting to be sent.
+func (s MessageState) Pending() bool {
+	return s == StateQueued || s == StateScheduled
+}
+
 type SendSMSRequest struct {
 	TenantID       string            `json:"tenantId"`
 	IdempotencyKey string            `json:"idempotencyKey"`
@@ -20,18 +37,624 @@ type SendSMSRequest struct {
 	TemplateID     string            `json:"templateId"`
 	Vars           map[string]string `json:"vars"`
 	CampaignID     string            `json:"campaignId,omitempty"`
+	// SendAt schedules the message; a time in the past sends immediately.
+	SendAt *time.Time `json:"sendAt,omitempty"`
+	// ExpiresAt, or TTLSeconds after acceptance, is when an unsent message is dropped instead of
+	// sent late. If both are set the earlier one applies.
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	TTLSeconds int        `json:"ttlSeconds,omitempty"`
+	// Priority picks the queue lane: high, normal or low. Empty means high for transactional
+	// templates and normal otherwise.
+	Priority string `json:"priority,omitempty"`
 }
 
 func (r SendSMSRequest) Validate() error {
 	if r.TenantID == "" || r.IdempotencyKey == "" || r.To == "" || r.TemplateID == "" {
 		return ErrMissingFields
 	}
+	if r.TTLSeconds < 0 {
+		return ErrInvalidExpiry
+	}
+	if !ValidPriority(r.Priority) {
+		return ErrInvalidPriority
+	}
 	return nil
 }
 
-var ErrMissingFields = errors.New("missing required fields")
+// Priorities, highest first. Each maps to its own SQS queue so bulk traffic can't delay
+// password resets and OTPs.
+const (
+	PriorityHigh   = "high"
+	PriorityNormal = "normal"
+	PriorityLow    = "low"
+)
+
+// ValidPriority reports whether p is a known priority or empty.
+func ValidPriority(p string) bool {
+	switch p {
+	case "", PriorityHigh, PriorityNormal, PriorityLow:
+		return true
+	}
+	return false
+}
+
+// Expiry returns when a message accepted at now expires, or nil if it never does.
+func (r SendSMSRequest) Expiry(now time.Time) *time.Time {
+	exp := r.ExpiresAt
+	if r.TTLSeconds > 0 {
+		ttl := now.Add(time.Duration(r.TTLSeconds) * time.Second)
+		if exp == nil || ttl.Before(*exp) {
+			exp = &ttl
+		}
+	}
+	if exp != nil {
+		utc := exp.UTC()
+		exp = &utc
+	}
+	return exp
+}
+
+// MaxScheduleAhead is how far in the future sendAt may be.
+const MaxScheduleAhead = 30 * 24 * time.Hour
+
+var (
+	ErrMissingFields   = errors.New("missing required fields")
+	ErrInvalidPhone    = errors.New("invalid phone number: expected E.164 or a national number of the default region")
+	ErrSendAtTooFar    = errors.New("sendAt is too far in the future")
+	ErrMessageNotFound = errors.New("message not found")
+	ErrNotCancelable   = errors.New("message is no longer scheduled or queued")
+	ErrInvalidExpiry   = errors.New("expiry must be in the future and after sendAt")
+	ErrInvalidPriority = errors.New("priority must be high, normal or low")
+
+	// ErrExpiresBeforeWindow rejects a message its delivery window would hold past its expiry.
+	ErrExpiresBeforeWindow = errors.New("expiry comes before the recipient's delivery window opens")
+)
 
 type CreateResponse struct {
 	MessageID string `json:"messageId"`
 	State     string `json:"state"`
+	// ScheduledAt is when a scheduled message will be released for sending.
+	ScheduledAt *time.Time `json:"scheduledAt,omitempty"`
+}
+
+// SendSMSBatchRequest fans one template out to many recipients. Each recipient carries its own
+// idempotency key so a retried batch only creates the messages that are missing.
+type SendSMSBatchRequest struct {
+	TenantID   string           `json:"tenantId"`
+	TemplateID string           `json:"templateId"`
+	CampaignID string           `json:"campaignId,omitempty"`
+	SendAt     *time.Time       `json:"sendAt,omitempty"`
+	ExpiresAt  *time.Time       `json:"expiresAt,omitempty"`
+	TTLSeconds int              `json:"ttlSeconds,omitempty"`
+	Priority   string           `json:"priority,omitempty"`
+	Recipients []BatchRecipient `json:"recipients"`
+}
+
+type BatchRecipient struct {
+	IdempotencyKey string            `json:"idempotencyKey"`
+	To             string            `json:"to"`
+	Vars           map[string]string `json:"vars"`
+}
+
+func (r SendSMSBatchRequest) Validate(maxRecipients int) error {
+	if r.TenantID == "" || r.TemplateID == "" || len(r.Recipients) == 0 {
+		return ErrMissingFields
+	}
+	if maxRecipients > 0 && len(r.Recipients) > maxRecipients {
+		return ErrBatchTooLarge
+	}
+	if !ValidPriority(r.Priority) {
+		return ErrInvalidPriority
+	}
+	return nil
+}
+
+// Items expands the batch into single send requests (validated per item by the service).
+func (r SendSMSBatchRequest) Items() []SendSMSRequest {
+	out := make([]SendSMSRequest, len(r.Recipients))
+	for i, rc := range r.Recipients {
+		out[i] = SendSMSRequest{
+			TenantID:       r.TenantID,
+			IdempotencyKey: rc.IdempotencyKey,
+			To:             rc.To,
+			TemplateID:     r.TemplateID,
+			Vars:           rc.Vars,
+			CampaignID:     r.CampaignID,
+			SendAt:         r.SendAt,
+			ExpiresAt:      r.ExpiresAt,
+			TTLSeconds:     r.TTLSeconds,
+			Priority:       r.Priority,
+		}
+	}
+	return out
+}
+
+var (
+	ErrBatchTooLarge        = errors.New("too many recipients in batch")
+	ErrDuplicateIdempotency = errors.New("duplicate idempotency key in batch")
+)
+
+const BatchItemError = "error"
+
+// BatchItemResult is the outcome for one recipient, in request order.
+// State is the message state, or "error" with Error set.
+type BatchItemResult struct {
+	Index          int    `json:"index"`
+	IdempotencyKey string `json:"idempotencyKey"`
+	MessageID      string `json:"messageId,omitempty"`
+	State          string `json:"state"`
+	Error          string `json:"error,omitempty"`
+}
+
+func (r *BatchItemResult) Fail(err error) {
+	r.State = BatchItemError
+	r.Error = err.Error()
+}
+
+type BatchResponse struct {
+	Results []BatchItemResult `json:"results"`
+}
+
+var (
+	ErrTenantNotFound = errors.New("tenant not found")
+	ErrAPIKeyNotFound = errors.New("api key not found")
+)
+
+type APIKey struct {
+	ID        string     `json:"id"`
+	TenantID  string     `json:"tenantId"`
+	Name      string     `json:"name"`
+	Prefix    string     `json:"prefix"`
+	CreatedAt time.Time  `json:"createdAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
+
+// CreatedAPIKey carries the plaintext key. It is only ever returned by create/rotate.
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name string `json:"name"`
+}
+
+type RotateAPIKeyRequest struct {
+	// GraceSeconds keeps the old key working for a while so clients can roll over.
+	GraceSeconds int `json:"graceSeconds,omitempty"`
+}
+
+// Timeline event types, in the order they normally occur.
+const (
+	EventAccepted        = "accepted"
+	EventSuppressed      = "suppressed"
+	EventScheduled       = "scheduled"
+	EventCanceled        = "canceled"
+	EventExpired         = "expired"
+	EventClaimed         = "claimed"
+	EventProviderAttempt = "provider_attempt"
+	EventDeliveryStatus  = "delivery_status"
+)
+
+// TimelineEvent is one entry of GET /v1/messages/{id}/events. Fields not relevant to Type are omitted.
+type TimelineEvent struct {
+	Type          string    `json:"type"`
+	At            time.Time `json:"at"`
+	State         string    `json:"state,omitempty"`
+	Reason        string    `json:"reason,omitempty"`
+	Provider      string    `json:"provider,omitempty"`
+	ProviderMsgID string    `json:"providerMsgId,omitempty"`
+	HTTPStatus    int       `json:"httpStatus,omitempty"`
+	ErrorCode     string    `json:"errorCode,omitempty"`
+	FailureReason string    `json:"failureReason,omitempty"`
+	Error         string    `json:"error,omitempty"`
+	LatencyMs     *int      `json:"latencyMs,omitempty"`
+	VendorStatus  string    `json:"vendorStatus,omitempty"`
+	// ScheduledAt is when a scheduled message is released to the workers.
+	ScheduledAt *time.Time `json:"scheduledAt,omitempty"`
+}
+
+type TimelineResponse struct {
+	MessageID string          `json:"messageId"`
+	Events    []TimelineEvent `json:"events"`
+}
+
+type ConsentStatus string
+
+const (
+	ConsentOptedIn  ConsentStatus = "opted_in"
+	ConsentOptedOut ConsentStatus = "opted_out"
+	// ConsentDeleted is reported for a removed consent record and appears in its history.
+	ConsentDeleted ConsentStatus = "deleted"
+)
+
+// Consent sources recorded in consent history.
+const (
+	ConsentSourceAPI     = "api"
+	ConsentSourceKeyword = "keyword"
+	ConsentSourceImport  = "import"
+)
+
+const (
+	ChannelSMS = "sms"
+	// MaxConsentImport caps items per consent import request.
+	MaxConsentImport = 10000
+)
+
+var (
+	ErrInvalidConsentStatus = errors.New("status must be opted_in or opted_out")
+	ErrUnsupportedChannel   = errors.New("unsupported channel")
+	ErrConsentNotFound      = errors.New("consent not found")
+	ErrImportTooLarge       = errors.New("too many items in import")
+)
+
+func (s ConsentStatus) Valid() bool { return s == ConsentOptedIn || s == ConsentOptedOut }
+
+func ValidChannel(c string) bool { return c == ChannelSMS }
+
+type Consent struct {
+	TenantID  string         `json:"tenantId"`
+	Phone     string         `json:"phone"`
+	Channel   string         `json:"channel"`
+	Status    ConsentStatus  `json:"status"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+	History   []ConsentEvent `json:"history,omitempty"`
+}
+
+// ConsentEvent is one append-only history entry: how and by whom a consent changed.
+type ConsentEvent struct {
+	Status ConsentStatus `json:"status"`
+	Source string        `json:"source"`
+	Actor  string        `json:"actor,omitempty"`
+	At     time.Time     `json:"at"`
+}
+
+type PutConsentRequest struct {
+	Status ConsentStatus `json:"status"`
+	// Actor identifies who captured the consent (user, agent, system), for audits.
+	Actor string `json:"actor,omitempty"`
+}
+
+type ImportConsentsRequest struct {
+	TenantID string              `json:"tenantId"`
+	Channel  string              `json:"channel,omitempty"`
+	Actor    string              `json:"actor,omitempty"`
+	Items    []ImportConsentItem `json:"items"`
+}
+
+type ImportConsentItem struct {
+	Phone  string        `json:"phone"`
+	Status ConsentStatus `json:"status"`
+}
+
+// Validate checks every item; the import is all-or-nothing.
+func (r ImportConsentsRequest) Validate() error {
+	if len(r.Items) == 0 {
+		return ErrMissingFields
+	}
+	if len(r.Items) > MaxConsentImport {
+		return ErrImportTooLarge
+	}
+	if r.Channel != "" && !ValidChannel(r.Channel) {
+		return ErrUnsupportedChannel
+	}
+	for i, it := range r.Items {
+		if it.Phone == "" {
+			return fmt.Errorf("item %d: %w", i, ErrMissingFields)
+		}
+		if !it.Status.Valid() {
+			return fmt.Errorf("item %d: %w", i, ErrInvalidConsentStatus)
+		}
+	}
+	return nil
+}
+
+type ImportConsentsResponse struct {
+	Imported int `json:"imported"`
+}
+
+const (
+	// DefaultSuppressionReason is stored when a suppression is added without a reason.
+	DefaultSuppressionReason = "manual"
+	MaxSuppressionImport     = 10000
+)
+
+var (
+	ErrSuppressionNotFound      = errors.New("suppression not found")
+	ErrInvalidSuppressionExpiry = errors.New("suppression expiry must be in the future")
+)
+
+type Suppression struct {
+	TenantID  string     `json:"tenantId"`
+	Phone     string     `json:"phone"`
+	Reason    string     `json:"reason"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// PutSuppressionRequest adds or replaces a suppression. ExpiresAt or TTLSeconds make it temporary;
+// if both are set the earlier one applies, as for send requests.
+type PutSuppressionRequest struct {
+	Reason     string     `json:"reason,omitempty"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	TTLSeconds int        `json:"ttlSeconds,omitempty"`
+}
+
+// Validate rejects a negative TTL and an expiry that has already passed, which would otherwise
+// make the suppression permanent or lapse on creation.
+func (r PutSuppressionRequest) Validate(now time.Time) error {
+	if r.TTLSeconds < 0 || (r.ExpiresAt != nil && !r.ExpiresAt.After(now)) {
+		return ErrInvalidSuppressionExpiry
+	}
+	return nil
+}
+
+// Expiry resolves the request's expiry relative to now (nil means permanent).
+func (r PutSuppressionRequest) Expiry(now time.Time) *time.Time {
+	return SendSMSRequest{ExpiresAt: r.ExpiresAt, TTLSeconds: r.TTLSeconds}.Expiry(now)
+}
+
+type ImportSuppressionsRequest struct {
+	TenantID string                  `json:"tenantId"`
+	Items    []ImportSuppressionItem `json:"items"`
+}
+
+type ImportSuppressionItem struct {
+	Phone string `json:"phone"`
+	PutSuppressionRequest
+}
+
+func (r ImportSuppressionsRequest) Validate() error {
+	if len(r.Items) == 0 {
+		return ErrMissingFields
+	}
+	if len(r.Items) > MaxSuppressionImport {
+		return ErrImportTooLarge
+	}
+	for i, it := range r.Items {
+		if it.Phone == "" {
+			return fmt.Errorf("item %d: %w", i, ErrMissingFields)
+		}
+	}
+	return nil
+}
+
+type SuppressionPage struct {
+	Suppressions []Suppression `json:"suppressions"`
+	NextCursor   string        `json:"nextCursor,omitempty"`
+}
+
+type ImportSuppressionsResponse struct {
+	Imported int `json:"imported"`
+}
+
+type TemplateStatus string
+
+const (
+	TemplateDraft     TemplateStatus = "draft"
+	TemplatePublished TemplateStatus = "published"
+	// TemplateArchived versions are kept for history but are never used for sends.
+	TemplateArchived TemplateStatus = "archived"
+)
+
+var (
+	ErrTemplateNotFound = errors.New("template not found")
+	ErrUnknownTemplate  = errors.New("unknown or unpublished template")
+	ErrTemplateNotDraft = errors.New("only draft template versions can be edited")
+	ErrInvalidTemplate  = errors.New("invalid template")
+)
+
+// Template is one version of a tenant's template. Sends use the most recently published version.
+type Template struct {
+	TenantID   string `json:"tenantId"`
+	TemplateID string `json:"templateId"`
+	Version    int    `json:"version"`
+	Body       string `json:"body"`
+	// RequiredVars must be supplied by every send using this version.
+	RequiredVars  []string       `json:"requiredVars"`
+	MaxSegments   int            `json:"maxSegments,omitempty"`
+	Transliterate bool           `json:"transliterate,omitempty"`
+	Transactional bool           `json:"transactional,omitempty"`
+	Status        TemplateStatus `json:"status"`
+	CreatedAt     time.Time      `json:"createdAt"`
+	UpdatedAt     time.Time      `json:"updatedAt"`
+	PublishedAt   *time.Time     `json:"publishedAt,omitempty"`
+}
+
+// CreateTemplateRequest creates a new draft version (version 1 for a new templateId).
+type CreateTemplateRequest struct {
+	TemplateID    string `json:"templateId"`
+	Body          string `json:"body"`
+	MaxSegments   int    `json:"maxSegments,omitempty"`
+	Transliterate bool   `json:"transliterate,omitempty"`
+	Transactional bool   `json:"transactional,omitempty"`
+}
+
+func (r CreateTemplateRequest) Validate() error {
+	if r.TemplateID == "" {
+		return ErrMissingFields
+	}
+	return UpdateTemplateRequest{Body: r.Body, MaxSegments: r.MaxSegments}.Validate()
+}
+
+type UpdateTemplateRequest struct {
+	Body string `json:"body"`
+	// MaxSegments rejects sends whose rendered body needs more segments (0 = no limit).
+	MaxSegments int `json:"maxSegments,omitempty"`
+	// Transliterate replaces smart quotes, dashes and similar characters with GSM-7 equivalents.
+	Transliterate bool `json:"transliterate,omitempty"`
+	// Transactional templates (OTPs, receipts) are sent immediately, ignoring delivery windows.
+	Transactional bool `json:"transactional,omitempty"`
+}
+
+func (r UpdateTemplateRequest) Validate() error {
+	if r.Body == "" {
+		return ErrMissingFields
+	}
+	if r.MaxSegments < 0 {
+		return ErrInvalidTemplate
+	}
+	return nil
+}
+
+type TemplateList struct {
+	Templates []Template `json:"templates"`
+}
+
+// Reasons stored in last_error when the tenant's country policy suppresses a send.
+const (
+	ReasonCountryBlocked     = "country_blocked"
+	ReasonPrefixBlocked      = "prefix_blocked"
+	ReasonCountryCapExceeded = "country_cap_exceeded"
+)
+
+var (
+	ErrCountryPolicyNotFound = errors.New("country policy not found")
+	ErrInvalidCountryPolicy  = errors.New("invalid country policy")
+)
+
+// CountryPolicy restricts where a tenant's messages may go. Without a policy every destination
+// is allowed.
+type CountryPolicy struct {
+	TenantID string `json:"tenantId"`
+	// AllowedCountries are ISO 3166-1 alpha-2 regions; empty allows every country.
+	AllowedCountries []string `json:"allowedCountries,omitempty"`
+	// BlockedPrefixes are E.164 prefixes (premium-rate ranges such as "+1900") that are never sent to.
+	BlockedPrefixes []string `json:"blockedPrefixes,omitempty"`
+	// CountryDailyCaps limits messages per destination country per UTC day, across all recipients.
+	CountryDailyCaps map[string]int `json:"countryDailyCaps,omitempty"`
+	UpdatedAt        time.Time      `json:"updatedAt"`
+}
+
+type PutCountryPolicyRequest struct {
+	AllowedCountries []string       `json:"allowedCountries,omitempty"`
+	BlockedPrefixes  []string       `json:"blockedPrefixes,omitempty"`
+	CountryDailyCaps map[string]int `json:"countryDailyCaps,omitempty"`
+}
+
+// Validate checks the request and upper-cases region codes in place.
+func (r *PutCountryPolicyRequest) Validate() error {
+	for i, c := range r.AllowedCountries {
+		if !isRegionCode(c) {
+			return fmt.Errorf("%w: allowedCountries[%d] must be a 2-letter region code", ErrInvalidCountryPolicy, i)
+		}
+		r.AllowedCountries[i] = strings.ToUpper(c)
+	}
+	for i, p := range r.BlockedPrefixes {
+		if len(p) < 2 || p[0] != '+' || strings.Trim(p[1:], "0123456789") != "" {
+			return fmt.Errorf("%w: blockedPrefixes[%d] must be \"+\" followed by digits", ErrInvalidCountryPolicy, i)
+		}
+	}
+	caps := make(map[string]int, len(r.CountryDailyCaps))
+	for c, n := range r.CountryDailyCaps {
+		if !isRegionCode(c) || n <= 0 {
+			return fmt.Errorf("%w: countryDailyCaps[%q] needs a 2-letter region code and a positive cap", ErrInvalidCountryPolicy, c)
+		}
+		caps[strings.ToUpper(c)] = n
+	}
+	r.CountryDailyCaps = caps
+	return nil
+}
+
+func isRegionCode(s string) bool {
+	if len(s) != 2 {
+		return false
+	}
+	for _, c := range s {
+		if (c < 'A' || c > 'Z') && (c < 'a' || c > 'z') {
+			return false
+		}
+	}
+	return true
+}
+
+var (
+	ErrDeliveryWindowNotFound = errors.New("delivery window not found")
+	ErrInvalidDeliveryWindow  = errors.New("invalid delivery window")
+)
+
+// DeliveryWindow is when non-transactional messages may reach recipients, in the recipient's
+// local time. A campaign window overrides the tenant's default (empty CampaignID).
+type DeliveryWindow struct {
+	TenantID   string   `json:"tenantId"`
+	CampaignID string   `json:"campaignId,omitempty"`
+	Start      string   `json:"start"` // "09:00"
+	End        string   `json:"end"`   // "21:00"; earlier than Start wraps past midnight
+	Days       []string `json:"days,omitempty"`
+	// Timezone is used when the recipient's zone can't be derived from the number.
+	Timezone  string    `json:"timezone"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type PutDeliveryWindowRequest struct {
+	Start    string   `json:"start"`
+	End      string   `json:"end"`
+	Days     []string `json:"days,omitempty"`
+	Timezone string   `json:"timezone,omitempty"`
+}
+
+var (
+	ErrRateLimitNotFound = errors.New("rate limit not found")
+	ErrInvalidRateLimit  = errors.New("invalid rate limit: perSecond must be positive and burst at least 1")
+)
+
+// TenantRateLimit caps how fast a tenant's messages are sent, across all worker pods.
+type TenantRateLimit struct {
+	TenantID  string  `json:"tenantId"`
+	PerSecond float64 `json:"perSecond"`
+	// Burst is how many messages may go out back to back after the tenant has been idle.
+	Burst     int       `json:"burst"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type PutTenantRateLimitRequest struct {
+	PerSecond float64 `json:"perSecond"`
+	Burst     int     `json:"burst,omitempty"`
+}
+
+// Validate checks the request and defaults Burst to one second's worth of messages.
+func (r *PutTenantRateLimitRequest) Validate() error {
+	if r.PerSecond <= 0 || r.Burst < 0 {
+		return ErrInvalidRateLimit
+	}
+	if r.Burst == 0 {
+		r.Burst = max(1, int(math.Ceil(r.PerSecond)))
+	}
+	return nil
+}
+
+// MaxKeywordReply caps a keyword auto-reply at two GSM-7 segments.
+const MaxKeywordReply = 306
+
+var (
+	ErrKeywordRepliesNotFound = errors.New("keyword replies not found")
+	ErrInvalidKeywordReplies  = errors.New("invalid keyword replies: set at least one reply of at most 306 characters")
+)
+
+// KeywordReplies are a tenant's answers to inbound HELP, STOP and START. Empty replies fall back
+// to the webhook's defaults.
+type KeywordReplies struct {
+	TenantID  string    `json:"tenantId"`
+	Help      string    `json:"help,omitempty"`
+	Stop      string    `json:"stop,omitempty"`
+	Start     string    `json:"start,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type PutKeywordRepliesRequest struct {
+	Help  string `json:"help,omitempty"`
+	Stop  string `json:"stop,omitempty"`
+	Start string `json:"start,omitempty"`
+}
+
+func (r PutKeywordRepliesRequest) Validate() error {
+	if r.Help == "" && r.Stop == "" && r.Start == "" {
+		return ErrInvalidKeywordReplies
+	}
+	for _, s := range []string{r.Help, r.Stop, r.Start} {
+		if len([]rune(s)) > MaxKeywordReply {
+			return ErrInvalidKeywordReplies
+		}
+	}
+	return nil
 }
@@ -0,0 +1,211 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"notif/internal/domain"
+	"notif/internal/service"
+	"notif/internal/util"
+)
+
+// Admin exposes operator-only endpoints. Register it before API so /v1/admin is not
+// swallowed by the tenant-authenticated /v1 routes.
+type Admin struct {
+	Keys *service.APIKeyService
+	// Policies, when set, exposes per-tenant country policy management.
+	Policies *service.CountryPolicyService
+	// RateLimits, when set, exposes per-tenant send rate management.
+	RateLimits *service.RateLimitService
+	Token      string
+}
+
+func (a *Admin) Register(mux *mux.Router) {
+	admin := mux.PathPrefix("/v1/admin").Subrouter()
+	admin.Use(RequireAdminToken(a.Token))
+	admin.HandleFunc("/tenants/{tenantId}/api-keys", a.handleCreateKey).Methods(http.MethodPost)
+	admin.HandleFunc("/tenants/{tenantId}/api-keys", a.handleListKeys).Methods(http.MethodGet)
+	admin.HandleFunc("/tenants/{tenantId}/api-keys/{keyId}:rotate", a.handleRotateKey).Methods(http.MethodPost)
+	admin.HandleFunc("/tenants/{tenantId}/api-keys/{keyId}", a.handleRevokeKey).Methods(http.MethodDelete)
+
+	if a.Policies != nil {
+		admin.HandleFunc("/tenants/{tenantId}/country-policy", a.handlePutCountryPolicy).Methods(http.MethodPut)
+		admin.HandleFunc("/tenants/{tenantId}/country-policy", a.handleGetCountryPolicy).Methods(http.MethodGet)
+		admin.HandleFunc("/tenants/{tenantId}/country-policy", a.handleDeleteCountryPolicy).Methods(http.MethodDelete)
+	}
+	if a.RateLimits != nil {
+		admin.HandleFunc("/tenants/{tenantId}/rate-limit", a.handlePutRateLimit).Methods(http.MethodPut)
+		admin.HandleFunc("/tenants/{tenantId}/rate-limit", a.handleGetRateLimit).Methods(http.MethodGet)
+		admin.HandleFunc("/tenants/{tenantId}/rate-limit", a.handleDeleteRateLimit).Methods(http.MethodDelete)
+	}
+}
+
+func (a *Admin) handleCreateKey(w http.ResponseWriter, r *http.Request) {
+	tenantID := mux.Vars(r)["tenantId"]
+	var req domain.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, ErrInvalidJSON, http.StatusBadRequest)
+		return
+	}
+	if req.Name == "" {
+		http.Error(w, domain.ErrMissingFields.Error(), http.StatusBadRequest)
+		return
+	}
+	key, err := a.Keys.Create(r.Context(), tenantID, req.Name, util.NowUTC())
+	if err != nil {
+		writeAPIKeyError(w, err, "create api key failed", tenantID, "")
+		return
+	}
+	writeJSON(w, http.StatusCreated, key)
+}
+
+func (a *Admin) handleListKeys(w http.ResponseWriter, r *http.Request) {
+	tenantID := mux.Vars(r)["tenantId"]
+	keys, err := a.Keys.List(r.Context(), tenantID)
+	if err != nil {
+		writeAPIKeyError(w, err, "list api keys failed", tenantID, "")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"keys": keys})
+}
+
+func (a *Admin) handleRotateKey(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	var req domain.RotateAPIKeyRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, ErrInvalidJSON, http.StatusBadRequest)
+			return
+		}
+	}
+	grace := time.Duration(max(req.GraceSeconds, 0)) * time.Second
+	key, err := a.Keys.Rotate(r.Context(), vars["tenantId"], vars["keyId"], grace, util.NowUTC())
+	if err != nil {
+		writeAPIKeyError(w, err, "rotate api key failed", vars["tenantId"], vars["keyId"])
+		return
+	}
+	writeJSON(w, http.StatusCreated, key)
+}
+
+func (a *Admin) handleRevokeKey(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if err := a.Keys.Revoke(r.Context(), vars["tenantId"], vars["keyId"], util.NowUTC()); err != nil {
+		writeAPIKeyError(w, err, "revoke api key failed", vars["tenantId"], vars["keyId"])
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeAPIKeyError(w http.ResponseWriter, err error, msg, tenantID, keyID string) {
+	if errors.Is(err, domain.ErrTenantNotFound) || errors.Is(err, domain.ErrAPIKeyNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	slog.Error(msg, "err", err, "tenant_id", tenantID, "key_id", keyID)
+	http.Error(w, ErrDependency, http.StatusBadGateway)
+}
+
+func (a *Admin) handlePutCountryPolicy(w http.ResponseWriter, r *http.Request) {
+	tenantID := mux.Vars(r)["tenantId"]
+	var req domain.PutCountryPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, ErrInvalidJSON, http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	p, err := a.Policies.Put(r.Context(), tenantID, req, util.NowUTC())
+	if err != nil {
+		writeCountryPolicyError(w, err, "put country policy failed", tenantID)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+func (a *Admin) handleGetCountryPolicy(w http.ResponseWriter, r *http.Request) {
+	tenantID := mux.Vars(r)["tenantId"]
+	p, err := a.Policies.Get(r.Context(), tenantID)
+	if err != nil {
+		writeCountryPolicyError(w, err, "get country policy failed", tenantID)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+func (a *Admin) handleDeleteCountryPolicy(w http.ResponseWriter, r *http.Request) {
+	tenantID := mux.Vars(r)["tenantId"]
+	if err := a.Policies.Delete(r.Context(), tenantID); err != nil {
+		writeCountryPolicyError(w, err, "delete country policy failed", tenantID)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeCountryPolicyError(w http.ResponseWriter, err error, msg, tenantID string) {
+	if errors.Is(err, domain.ErrTenantNotFound) || errors.Is(err, domain.ErrCountryPolicyNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	slog.Error(msg, "err", err, "tenant_id", tenantID)
+	http.Error(w, ErrDependency, http.StatusBadGateway)
+}
+
+func (a *Admin) handlePutRateLimit(w http.ResponseWriter, r *http.Request) {
+	tenantID := mux.Vars(r)["tenantId"]
+	var req domain.PutTenantRateLimitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, ErrInvalidJSON, http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	l, err := a.RateLimits.Put(r.Context(), tenantID, req, util.NowUTC())
+	if err != nil {
+		writeRateLimitError(w, err, "put rate limit failed", tenantID)
+		return
+	}
+	writeJSON(w, http.StatusOK, l)
+}
+
+func (a *Admin) handleGetRateLimit(w http.ResponseWriter, r *http.Request) {
+	tenantID := mux.Vars(r)["tenantId"]
+	l, err := a.RateLimits.Get(r.Context(), tenantID)
+	if err != nil {
+		writeRateLimitError(w, err, "get rate limit failed", tenantID)
+		return
+	}
+	writeJSON(w, http.StatusOK, l)
+}
+
+func (a *Admin) handleDeleteRateLimit(w http.ResponseWriter, r *http.Request) {
+	tenantID := mux.Vars(r)["tenantId"]
+	if err := a.RateLimits.Delete(r.Context(), tenantID); err != nil {
+		writeRateLimitError(w, err, "delete rate limit failed", tenantID)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeRateLimitError(w http.ResponseWriter, err error, msg, tenantID string) {
+	if errors.Is(err, domain.ErrTenantNotFound) || errors.Is(err, domain.ErrRateLimitNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	slog.Error(msg, "err", err, "tenant_id", tenantID)
+	http.Error(w, ErrDependency, http.StatusBadGateway)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
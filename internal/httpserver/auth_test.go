@@ -0,0 +1,45 @@
+package httpserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeAuth map[string]string
+
+func (f fakeAuth) Authenticate(ctx context.Context, rawKey string, now time.Time) (string, bool, error) {
+	tenantID, ok := f[rawKey]
+	return tenantID, ok, nil
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	s := New()
+	api := &API{Auth: fakeAuth{"nk_good": "t1"}, IDGen: func() string { return "msg-1" }}
+	api.Register(s.Mux)
+
+	cases := []struct {
+		name   string
+		key    string
+		body   string
+		status int
+	}{
+		{"missing key", "", `{"tenantId":"t1"}`, http.StatusUnauthorized},
+		{"unknown key", "nk_bad", `{"tenantId":"t1"}`, http.StatusUnauthorized},
+		{"tenant mismatch", "nk_good", `{"tenantId":"t2","idempotencyKey":"k","to":"+1","templateId":"x"}`, http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/v1/sms/messages", strings.NewReader(tc.body))
+		if tc.key != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.key)
+		}
+		rr := httptest.NewRecorder()
+		s.Mux.ServeHTTP(rr, req)
+		if rr.Code != tc.status {
+			t.Fatalf("%s: expected %d, got %d", tc.name, tc.status, rr.Code)
+		}
+	}
+}
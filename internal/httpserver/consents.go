@@ -0,0 +1,114 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"notif/internal/domain"
+	"notif/internal/util"
+)
+
+// Consent endpoints take the tenant from the API key (or ?tenantId= with auth disabled)
+// and the channel from ?channel=, defaulting to sms.
+
+func (a *API) handlePutConsent(w http.ResponseWriter, r *http.Request) {
+	tenantID, phone, channel, ok := consentTarget(w, r)
+	if !ok {
+		return
+	}
+	var req domain.PutConsentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, ErrInvalidJSON, http.StatusBadRequest)
+		return
+	}
+	consent, err := a.Consents.Set(r.Context(), tenantID, phone, channel, req.Status, domain.ConsentSourceAPI, req.Actor, util.NowUTC())
+	if err != nil {
+		writeConsentError(w, err, "set consent failed", tenantID)
+		return
+	}
+	writeJSON(w, http.StatusOK, consent)
+}
+
+func (a *API) handleGetConsent(w http.ResponseWriter, r *http.Request) {
+	tenantID, phone, channel, ok := consentTarget(w, r)
+	if !ok {
+		return
+	}
+	consent, err := a.Consents.Get(r.Context(), tenantID, phone, channel)
+	if err != nil {
+		writeConsentError(w, err, "get consent failed", tenantID)
+		return
+	}
+	writeJSON(w, http.StatusOK, consent)
+}
+
+func (a *API) handleDeleteConsent(w http.ResponseWriter, r *http.Request) {
+	tenantID, phone, channel, ok := consentTarget(w, r)
+	if !ok {
+		return
+	}
+	actor := r.URL.Query().Get("actor")
+	if err := a.Consents.Delete(r.Context(), tenantID, phone, channel, domain.ConsentSourceAPI, actor, util.NowUTC()); err != nil {
+		writeConsentError(w, err, "delete consent failed", tenantID)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *API) handleImportConsents(w http.ResponseWriter, r *http.Request) {
+	var req domain.ImportConsentsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, ErrInvalidJSON, http.StatusBadRequest)
+		return
+	}
+	tenantID, ok := requireTenant(w, r, req.TenantID)
+	if !ok {
+		return
+	}
+	req.TenantID = tenantID
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	n, err := a.Consents.Import(r.Context(), req, util.NowUTC())
+	if err != nil {
+		writeConsentError(w, err, "import consents failed", tenantID)
+		return
+	}
+	writeJSON(w, http.StatusOK, domain.ImportConsentsResponse{Imported: n})
+}
+
+func consentTarget(w http.ResponseWriter, r *http.Request) (tenantID, phone, channel string, ok bool) {
+	q := r.URL.Query()
+	tenantID, ok = requireTenant(w, r, q.Get("tenantId"))
+	if !ok {
+		return "", "", "", false
+	}
+	phone = mux.Vars(r)["phone"]
+	if phone == "" {
+		http.Error(w, domain.ErrMissingFields.Error(), http.StatusBadRequest)
+		return "", "", "", false
+	}
+	channel = q.Get("channel")
+	if channel != "" && !domain.ValidChannel(channel) {
+		http.Error(w, domain.ErrUnsupportedChannel.Error(), http.StatusBadRequest)
+		return "", "", "", false
+	}
+	return tenantID, phone, channel, true
+}
+
+func writeConsentError(w http.ResponseWriter, err error, msg, tenantID string) {
+	switch {
+	case errors.Is(err, domain.ErrConsentNotFound):
+		http.Error(w, ErrNotFound, http.StatusNotFound)
+	case errors.Is(err, domain.ErrInvalidConsentStatus), errors.Is(err, domain.ErrInvalidPhone):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		slog.Error(msg, "err", err, "tenant_id", tenantID)
+		http.Error(w, ErrDependency, http.StatusBadGateway)
+	}
+}
@@ -0,0 +1,72 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"notif/internal/domain"
+	"notif/internal/util"
+)
+
+// Delivery windows are addressed by query string: ?campaignId= selects a campaign override,
+// without it the tenant default is used.
+
+func (a *API) handlePutDeliveryWindow(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	tenantID, ok := requireTenant(w, r, q.Get("tenantId"))
+	if !ok {
+		return
+	}
+	var req domain.PutDeliveryWindowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, ErrInvalidJSON, http.StatusBadRequest)
+		return
+	}
+	win, err := a.DeliveryWindows.Put(r.Context(), tenantID, q.Get("campaignId"), req, util.NowUTC())
+	if err != nil {
+		writeDeliveryWindowError(w, err, "put delivery window failed", tenantID)
+		return
+	}
+	writeJSON(w, http.StatusOK, win)
+}
+
+func (a *API) handleGetDeliveryWindow(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	tenantID, ok := requireTenant(w, r, q.Get("tenantId"))
+	if !ok {
+		return
+	}
+	win, err := a.DeliveryWindows.Get(r.Context(), tenantID, q.Get("campaignId"))
+	if err != nil {
+		writeDeliveryWindowError(w, err, "get delivery window failed", tenantID)
+		return
+	}
+	writeJSON(w, http.StatusOK, win)
+}
+
+func (a *API) handleDeleteDeliveryWindow(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	tenantID, ok := requireTenant(w, r, q.Get("tenantId"))
+	if !ok {
+		return
+	}
+	if err := a.DeliveryWindows.Delete(r.Context(), tenantID, q.Get("campaignId")); err != nil {
+		writeDeliveryWindowError(w, err, "delete delivery window failed", tenantID)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeDeliveryWindowError(w http.ResponseWriter, err error, msg, tenantID string) {
+	switch {
+	case errors.Is(err, domain.ErrDeliveryWindowNotFound), errors.Is(err, domain.ErrTenantNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, domain.ErrInvalidDeliveryWindow):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		slog.Error(msg, "err", err, "tenant_id", tenantID)
+		http.Error(w, ErrDependency, http.StatusBadGateway)
+	}
+}
@@ -7,4 +7,7 @@ const (
 	ErrNotFound         = "not found"
 	ErrBadForm          = "bad form"
 	ErrInvalidSignature = "invalid signature"
+	ErrUnauthorized     = "unauthorized"
+	ErrTenantMismatch   = "tenant does not match api key"
+	ErrMissingTenant    = "missing tenantId"
 )
@@ -2,11 +2,19 @@ package httpserver
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"notif/internal/domain"
+	"notif/internal/phone"
 	"notif/internal/service"
+	"notif/internal/store"
 	"notif/internal/util"
 
 	"github.com/gorilla/mux"
@@ -15,11 +23,65 @@ import (
 type API struct {
 	Svc   *service.NotificationService
 	IDGen func() string
+	// MaxBatchSize caps recipients per batch request (<= 0 means unlimited).
+	MaxBatchSize int
+	// Auth resolves tenants from API keys. Nil disables auth (body tenantId is trusted).
+	Auth Authenticator
+	// Consents enables the consent management endpoints when set.
+	Consents *service.ConsentService
+	// Suppressions enables the suppression list endpoints when set.
+	Suppressions *service.SuppressionService
+	// Templates enables the template management endpoints when set.
+	Templates *service.TemplateService
+	// DeliveryWindows enables the quiet-hours endpoints when set.
+	DeliveryWindows *service.DeliveryWindowService
+	// KeywordReplies enables the HELP/STOP/START auto-reply endpoints when set.
+	KeywordReplies *service.KeywordReplyService
 }
 
 func (a *API) Register(mux *mux.Router) {
-	mux.HandleFunc("/v1/sms/messages", a.handleSendSMS).Methods(http.MethodPost)
-	mux.HandleFunc("/v1/messages/{id}", a.handleGetMessage).Methods(http.MethodGet)
+	v1 := mux.PathPrefix("/v1").Subrouter()
+	if a.Auth != nil {
+		v1.Use(RequireAPIKey(a.Auth))
+	}
+	v1.HandleFunc("/sms/messages", a.handleSendSMS).Methods(http.MethodPost)
+	v1.HandleFunc("/sms/messages:batch", a.handleSendSMSBatch).Methods(http.MethodPost)
+	v1.HandleFunc("/messages", a.handleListMessages).Methods(http.MethodGet)
+	v1.HandleFunc("/messages/{id}", a.handleGetMessage).Methods(http.MethodGet)
+	v1.HandleFunc("/messages/{id}/events", a.handleMessageEvents).Methods(http.MethodGet)
+	v1.HandleFunc("/messages/{id}/cancel", a.handleCancelMessage).Methods(http.MethodPost)
+	if a.Consents != nil {
+		v1.HandleFunc("/consents:import", a.handleImportConsents).Methods(http.MethodPost)
+		v1.HandleFunc("/consents/{phone}", a.handlePutConsent).Methods(http.MethodPut)
+		v1.HandleFunc("/consents/{phone}", a.handleGetConsent).Methods(http.MethodGet)
+		v1.HandleFunc("/consents/{phone}", a.handleDeleteConsent).Methods(http.MethodDelete)
+	}
+	if a.Suppressions != nil {
+		v1.HandleFunc("/suppressions:import", a.handleImportSuppressions).Methods(http.MethodPost)
+		v1.HandleFunc("/suppressions", a.handleListSuppressions).Methods(http.MethodGet)
+		v1.HandleFunc("/suppressions/{phone}", a.handlePutSuppression).Methods(http.MethodPut)
+		v1.HandleFunc("/suppressions/{phone}", a.handleGetSuppression).Methods(http.MethodGet)
+		v1.HandleFunc("/suppressions/{phone}", a.handleDeleteSuppression).Methods(http.MethodDelete)
+	}
+	if a.Templates != nil {
+		v1.HandleFunc("/templates", a.handleCreateTemplate).Methods(http.MethodPost)
+		v1.HandleFunc("/templates", a.handleListTemplates).Methods(http.MethodGet)
+		v1.HandleFunc("/templates/{templateId}/versions", a.handleListTemplateVersions).Methods(http.MethodGet)
+		v1.HandleFunc("/templates/{templateId}/versions/{version:[0-9]+}", a.handleGetTemplate).Methods(http.MethodGet)
+		v1.HandleFunc("/templates/{templateId}/versions/{version:[0-9]+}", a.handleUpdateTemplate).Methods(http.MethodPut)
+		v1.HandleFunc("/templates/{templateId}/versions/{version:[0-9]+}", a.handleDeleteTemplate).Methods(http.MethodDelete)
+		v1.HandleFunc("/templates/{templateId}/versions/{version:[0-9]+}:publish", a.handlePublishTemplate).Methods(http.MethodPost)
+	}
+	if a.DeliveryWindows != nil {
+		v1.HandleFunc("/delivery-window", a.handlePutDeliveryWindow).Methods(http.MethodPut)
+		v1.HandleFunc("/delivery-window", a.handleGetDeliveryWindow).Methods(http.MethodGet)
+		v1.HandleFunc("/delivery-window", a.handleDeleteDeliveryWindow).Methods(http.MethodDelete)
+	}
+	if a.KeywordReplies != nil {
+		v1.HandleFunc("/keyword-replies", a.handlePutKeywordReplies).Methods(http.MethodPut)
+		v1.HandleFunc("/keyword-replies", a.handleGetKeywordReplies).Methods(http.MethodGet)
+		v1.HandleFunc("/keyword-replies", a.handleDeleteKeywordReplies).Methods(http.MethodDelete)
+	}
 }
 
 func (a *API) handleSendSMS(w http.ResponseWriter, r *http.Request) {
@@ -28,12 +90,22 @@ func (a *API) handleSendSMS(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, ErrInvalidJSON, http.StatusBadRequest)
 		return
 	}
+	tenantID, ok := callerTenant(r, req.TenantID)
+	if !ok {
+		http.Error(w, ErrTenantMismatch, http.StatusForbidden)
+		return
+	}
+	req.TenantID = tenantID
 	if err := req.Validate(); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	resp, err := a.Svc.CreateAndEnqueueSMS(r.Context(), req, a.IDGen(), util.NowUTC())
+	if service.IsRejection(err) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		slog.Error("create and enqueue sms failed",
 			"err", err,
@@ -51,13 +123,54 @@ func (a *API) handleSendSMS(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(resp)
 }
 
+func (a *API) handleSendSMSBatch(w http.ResponseWriter, r *http.Request) {
+	var req domain.SendSMSBatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, ErrInvalidJSON, http.StatusBadRequest)
+		return
+	}
+	tenantID, ok := callerTenant(r, req.TenantID)
+	if !ok {
+		http.Error(w, ErrTenantMismatch, http.StatusForbidden)
+		return
+	}
+	req.TenantID = tenantID
+	if err := req.Validate(a.MaxBatchSize); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	items := req.Items()
+	ids := make([]string, len(items))
+	for i := range ids {
+		ids[i] = a.IDGen()
+	}
+
+	results, err := a.Svc.CreateAndEnqueueSMSBatch(r.Context(), req.TenantID, items, ids, util.NowUTC())
+	if err != nil {
+		slog.Error("create and enqueue sms batch failed",
+			"err", err,
+			"tenant_id", req.TenantID,
+			"template_id", req.TemplateID,
+			"recipients", len(items),
+		)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(domain.BatchResponse{Results: results})
+}
+
 func (a *API) handleGetMessage(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
 	if id == "" {
 		http.Error(w, ErrMissingID, http.StatusBadRequest)
 		return
 	}
-	msg, found, err := a.Svc.GetMessage(r.Context(), id)
+	// Other tenants' messages are reported as not found rather than forbidden.
+	msg, found, err := a.Svc.GetMessage(r.Context(), TenantFromContext(r.Context()), id)
 	if err != nil {
 		slog.Error("get message failed", "err", err, "id", id)
 		http.Error(w, ErrDependency, http.StatusBadGateway)
@@ -70,3 +183,106 @@ func (a *API) handleGetMessage(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(msg)
 }
+
+func (a *API) handleMessageEvents(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		http.Error(w, ErrMissingID, http.StatusBadRequest)
+		return
+	}
+	events, found, err := a.Svc.Timeline(r.Context(), TenantFromContext(r.Context()), id)
+	if err != nil {
+		slog.Error("get message timeline failed", "err", err, "id", id)
+		http.Error(w, ErrDependency, http.StatusBadGateway)
+		return
+	}
+	if !found {
+		http.Error(w, ErrNotFound, http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, domain.TimelineResponse{MessageID: id, Events: events})
+}
+
+// handleCancelMessage withdraws a message that no worker has picked up yet. Messages already
+// being sent (or finished) get 409.
+func (a *API) handleCancelMessage(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		http.Error(w, ErrMissingID, http.StatusBadRequest)
+		return
+	}
+	resp, err := a.Svc.Cancel(r.Context(), TenantFromContext(r.Context()), id, util.NowUTC())
+	switch {
+	case err == nil:
+		writeJSON(w, http.StatusOK, resp)
+	case errors.Is(err, domain.ErrMessageNotFound):
+		http.Error(w, ErrNotFound, http.StatusNotFound)
+	case errors.Is(err, domain.ErrNotCancelable):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		slog.Error("cancel message failed", "err", err, "id", id)
+		http.Error(w, ErrDependency, http.StatusBadGateway)
+	}
+}
+
+func (a *API) handleListMessages(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	tenantID, ok := requireTenant(w, r, q.Get("tenantId"))
+	if !ok {
+		return
+	}
+	f, err := parseMessageFilter(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.TenantID = tenantID
+
+	page, err := a.Svc.ListMessages(r.Context(), f)
+	if err != nil {
+		slog.Error("list messages failed", "err", err, "tenant_id", tenantID)
+		http.Error(w, ErrDependency, http.StatusBadGateway)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// parseMessageFilter reads listing filters from the query string. Times are RFC 3339;
+// createdFrom is inclusive and createdTo exclusive.
+func parseMessageFilter(q url.Values) (store.MessageFilter, error) {
+	f := store.MessageFilter{
+		CampaignID:    q.Get("campaignId"),
+		ToPhone:       phone.Normalize(q.Get("to")),
+		State:         q.Get("state"),
+		TemplateID:    q.Get("templateId"),
+		Country:       strings.ToUpper(q.Get("country")),
+		FailureReason: q.Get("failureReason"),
+		BeforeID:      q.Get("cursor"),
+	}
+	if f.BeforeID != "" && !strings.HasPrefix(f.BeforeID, "msg_") {
+		return f, errors.New("invalid cursor")
+	}
+	for _, p := range []struct {
+		name string
+		dst  **time.Time
+	}{{"createdFrom", &f.CreatedFrom}, {"createdTo", &f.CreatedTo}} {
+		v := q.Get(p.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return f, fmt.Errorf("invalid %s", p.name)
+		}
+		t = t.UTC()
+		*p.dst = &t
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > service.MaxListLimit {
+			return f, fmt.Errorf("limit must be between 1 and %d", service.MaxListLimit)
+		}
+		f.Limit = n
+	}
+	return f, nil
+}
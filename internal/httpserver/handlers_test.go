@@ -0,0 +1,38 @@
+package httpserver
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestParseMessageFilter(t *testing.T) {
+	q := url.Values{
+		"campaignId":    {"c1"},
+		"to":            {"+1 555 000 1111"},
+		"createdFrom":   {"2024-01-02T03:04:05+02:00"},
+		"cursor":        {"msg_01HZ"},
+		"limit":         {"10"},
+		"failureReason": {"landline"},
+	}
+	f, err := parseMessageFilter(q)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if f.CampaignID != "c1" || f.ToPhone != "+15550001111" || f.BeforeID != "msg_01HZ" || f.Limit != 10 || f.FailureReason != "landline" {
+		t.Fatalf("unexpected filter: %+v", f)
+	}
+	if f.CreatedFrom == nil || f.CreatedFrom.Hour() != 1 || f.CreatedTo != nil {
+		t.Fatalf("unexpected created range: %v %v", f.CreatedFrom, f.CreatedTo)
+	}
+
+	for _, bad := range []url.Values{
+		{"limit": {"0"}},
+		{"limit": {"1000"}},
+		{"createdTo": {"yesterday"}},
+		{"cursor": {"1 OR 1=1"}},
+	} {
+		if _, err := parseMessageFilter(bad); err == nil {
+			t.Fatalf("expected error for %v", bad)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"notif/internal/domain"
+	"notif/internal/util"
+)
+
+func (a *API) handlePutKeywordReplies(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := requireTenant(w, r, r.URL.Query().Get("tenantId"))
+	if !ok {
+		return
+	}
+	var req domain.PutKeywordRepliesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, ErrInvalidJSON, http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	replies, err := a.KeywordReplies.Put(r.Context(), tenantID, req, util.NowUTC())
+	if err != nil {
+		writeKeywordRepliesError(w, err, "put keyword replies failed", tenantID)
+		return
+	}
+	writeJSON(w, http.StatusOK, replies)
+}
+
+func (a *API) handleGetKeywordReplies(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := requireTenant(w, r, r.URL.Query().Get("tenantId"))
+	if !ok {
+		return
+	}
+	replies, err := a.KeywordReplies.Get(r.Context(), tenantID)
+	if err != nil {
+		writeKeywordRepliesError(w, err, "get keyword replies failed", tenantID)
+		return
+	}
+	writeJSON(w, http.StatusOK, replies)
+}
+
+func (a *API) handleDeleteKeywordReplies(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := requireTenant(w, r, r.URL.Query().Get("tenantId"))
+	if !ok {
+		return
+	}
+	if err := a.KeywordReplies.Delete(r.Context(), tenantID); err != nil {
+		writeKeywordRepliesError(w, err, "delete keyword replies failed", tenantID)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeKeywordRepliesError(w http.ResponseWriter, err error, msg, tenantID string) {
+	if errors.Is(err, domain.ErrKeywordRepliesNotFound) || errors.Is(err, domain.ErrTenantNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	slog.Error(msg, "err", err, "tenant_id", tenantID)
+	http.Error(w, ErrDependency, http.StatusBadGateway)
+}
@@ -0,0 +1,127 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"notif/internal/domain"
+	"notif/internal/service"
+	"notif/internal/store"
+	"notif/internal/util"
+)
+
+func (a *API) handlePutSuppression(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := requireTenant(w, r, r.URL.Query().Get("tenantId"))
+	if !ok {
+		return
+	}
+	var req domain.PutSuppressionRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, ErrInvalidJSON, http.StatusBadRequest)
+			return
+		}
+	}
+	sp, err := a.Suppressions.Put(r.Context(), tenantID, mux.Vars(r)["phone"], req, util.NowUTC())
+	if err != nil {
+		writeSuppressionError(w, err, "put suppression failed", tenantID)
+		return
+	}
+	writeJSON(w, http.StatusOK, sp)
+}
+
+func (a *API) handleGetSuppression(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := requireTenant(w, r, r.URL.Query().Get("tenantId"))
+	if !ok {
+		return
+	}
+	sp, err := a.Suppressions.Get(r.Context(), tenantID, mux.Vars(r)["phone"])
+	if err != nil {
+		writeSuppressionError(w, err, "get suppression failed", tenantID)
+		return
+	}
+	writeJSON(w, http.StatusOK, sp)
+}
+
+func (a *API) handleDeleteSuppression(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := requireTenant(w, r, r.URL.Query().Get("tenantId"))
+	if !ok {
+		return
+	}
+	if err := a.Suppressions.Delete(r.Context(), tenantID, mux.Vars(r)["phone"]); err != nil {
+		writeSuppressionError(w, err, "delete suppression failed", tenantID)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleListSuppressions lists active suppressions; ?includeExpired=true also returns lapsed ones.
+func (a *API) handleListSuppressions(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	tenantID, ok := requireTenant(w, r, q.Get("tenantId"))
+	if !ok {
+		return
+	}
+	f := store.SuppressionFilter{
+		TenantID:       tenantID,
+		AfterPhone:     q.Get("cursor"),
+		IncludeExpired: q.Get("includeExpired") == "true",
+		Now:            util.NowUTC(),
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > service.MaxListLimit {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", service.MaxListLimit), http.StatusBadRequest)
+			return
+		}
+		f.Limit = n
+	}
+	page, err := a.Suppressions.List(r.Context(), f)
+	if err != nil {
+		writeSuppressionError(w, err, "list suppressions failed", tenantID)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (a *API) handleImportSuppressions(w http.ResponseWriter, r *http.Request) {
+	var req domain.ImportSuppressionsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, ErrInvalidJSON, http.StatusBadRequest)
+		return
+	}
+	tenantID, ok := requireTenant(w, r, req.TenantID)
+	if !ok {
+		return
+	}
+	req.TenantID = tenantID
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	n, err := a.Suppressions.Import(r.Context(), req, util.NowUTC())
+	if err != nil {
+		writeSuppressionError(w, err, "import suppressions failed", tenantID)
+		return
+	}
+	writeJSON(w, http.StatusOK, domain.ImportSuppressionsResponse{Imported: n})
+}
+
+func writeSuppressionError(w http.ResponseWriter, err error, msg, tenantID string) {
+	if errors.Is(err, domain.ErrSuppressionNotFound) {
+		http.Error(w, ErrNotFound, http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, domain.ErrInvalidPhone) || errors.Is(err, domain.ErrInvalidSuppressionExpiry) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	slog.Error(msg, "err", err, "tenant_id", tenantID)
+	http.Error(w, ErrDependency, http.StatusBadGateway)
+}
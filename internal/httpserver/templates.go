@@ -0,0 +1,148 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"notif/internal/domain"
+	"notif/internal/util"
+)
+
+// Template endpoints are tenant scoped like the rest of /v1: the tenant comes from the API key
+// (or ?tenantId= with auth disabled).
+
+func (a *API) handleCreateTemplate(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := requireTenant(w, r, r.URL.Query().Get("tenantId"))
+	if !ok {
+		return
+	}
+	var req domain.CreateTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, ErrInvalidJSON, http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	tpl, err := a.Templates.Create(r.Context(), tenantID, req, util.NowUTC())
+	if err != nil {
+		writeTemplateError(w, err, "create template failed", tenantID)
+		return
+	}
+	writeJSON(w, http.StatusCreated, tpl)
+}
+
+func (a *API) handleListTemplates(w http.ResponseWriter, r *http.Request) {
+	a.listTemplates(w, r, "")
+}
+
+func (a *API) handleListTemplateVersions(w http.ResponseWriter, r *http.Request) {
+	a.listTemplates(w, r, mux.Vars(r)["templateId"])
+}
+
+func (a *API) listTemplates(w http.ResponseWriter, r *http.Request, templateID string) {
+	tenantID, ok := requireTenant(w, r, r.URL.Query().Get("tenantId"))
+	if !ok {
+		return
+	}
+	tpls, err := a.Templates.List(r.Context(), tenantID, templateID)
+	if err != nil {
+		writeTemplateError(w, err, "list templates failed", tenantID)
+		return
+	}
+	writeJSON(w, http.StatusOK, domain.TemplateList{Templates: tpls})
+}
+
+func (a *API) handleGetTemplate(w http.ResponseWriter, r *http.Request) {
+	tenantID, templateID, version, ok := templateVersionTarget(w, r)
+	if !ok {
+		return
+	}
+	tpl, err := a.Templates.Get(r.Context(), tenantID, templateID, version)
+	if err != nil {
+		writeTemplateError(w, err, "get template failed", tenantID)
+		return
+	}
+	writeJSON(w, http.StatusOK, tpl)
+}
+
+func (a *API) handleUpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	tenantID, templateID, version, ok := templateVersionTarget(w, r)
+	if !ok {
+		return
+	}
+	var req domain.UpdateTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, ErrInvalidJSON, http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	tpl, err := a.Templates.UpdateDraft(r.Context(), tenantID, templateID, version, req, util.NowUTC())
+	if err != nil {
+		writeTemplateError(w, err, "update template failed", tenantID)
+		return
+	}
+	writeJSON(w, http.StatusOK, tpl)
+}
+
+func (a *API) handlePublishTemplate(w http.ResponseWriter, r *http.Request) {
+	tenantID, templateID, version, ok := templateVersionTarget(w, r)
+	if !ok {
+		return
+	}
+	tpl, err := a.Templates.Publish(r.Context(), tenantID, templateID, version, util.NowUTC())
+	if err != nil {
+		writeTemplateError(w, err, "publish template failed", tenantID)
+		return
+	}
+	writeJSON(w, http.StatusOK, tpl)
+}
+
+func (a *API) handleDeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	tenantID, templateID, version, ok := templateVersionTarget(w, r)
+	if !ok {
+		return
+	}
+	if err := a.Templates.Delete(r.Context(), tenantID, templateID, version, util.NowUTC()); err != nil {
+		writeTemplateError(w, err, "delete template failed", tenantID)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func templateVersionTarget(w http.ResponseWriter, r *http.Request) (tenantID, templateID string, version int, ok bool) {
+	tenantID, ok = requireTenant(w, r, r.URL.Query().Get("tenantId"))
+	if !ok {
+		return "", "", 0, false
+	}
+	vars := mux.Vars(r)
+	version, err := strconv.Atoi(vars["version"])
+	if err != nil {
+		http.Error(w, ErrMissingID, http.StatusBadRequest)
+		return "", "", 0, false
+	}
+	return tenantID, vars["templateId"], version, true
+}
+
+func writeTemplateError(w http.ResponseWriter, err error, msg, tenantID string) {
+	switch {
+	case errors.Is(err, domain.ErrTemplateNotFound), errors.Is(err, domain.ErrTenantNotFound):
+		http.Error(w, ErrNotFound, http.StatusNotFound)
+	case errors.Is(err, domain.ErrInvalidTemplate):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, domain.ErrTemplateNotDraft):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		slog.Error(msg, "err", err, "tenant_id", tenantID)
+		http.Error(w, ErrDependency, http.StatusBadGateway)
+	}
+}
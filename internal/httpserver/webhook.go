@@ -1,7 +1,9 @@
 package httpserver
 
 import (
+	"cmp"
 	"context"
+	"encoding/xml"
 	"errors"
 	"log/slog"
 	"net/http"
@@ -10,8 +12,12 @@ import (
 
 	"github.com/gorilla/mux"
 
+	"notif/internal/autosuppress"
 	"notif/internal/observability"
+	"notif/internal/providers"
 	sqsqueue "notif/internal/queue/sqs"
+	"notif/internal/service"
+	"notif/internal/sms"
 	"notif/internal/store"
 	"notif/internal/util"
 )
@@ -29,16 +35,31 @@ type Webhook struct {
 	Store           WebhookStore
 	Enqueuer        WebhookEnqueuer
 	VerifySignature func(authToken, fullURL, provided string, form url.Values) bool
+	ParseStatus     func(form url.Values) providers.StatusUpdate
 	AuthToken       string
 	PublicURL       string
 
+	// AutoSuppress, when set, suppresses numbers after hard-failure receipts. In queue mode the
+	// webhook processor applies it instead.
+	AutoSuppress *autosuppress.Policy
+
 	// If true, this handler becomes "ingest-only": validate signature and enqueue the event to SQS.
 	// This keeps provider callbacks fast and protects the DB during webhook floods.
 	UseQueue bool
+
+	// Inbound, when set, enables the incoming message webhook. It is handled synchronously even
+	// in queue mode, since the keyword reply goes back in the response. InboundURL is the exact
+	// URL configured in Twilio for incoming messages (signatures are computed over it).
+	Inbound      *service.InboundService
+	ParseInbound func(form url.Values) providers.InboundMessage
+	InboundURL   string
 }
 
 func (w *Webhook) Register(mux *mux.Router) {
 	mux.HandleFunc("/v1/webhooks/twilio/status", w.handleTwilioStatus).Methods(http.MethodPost)
+	if w.Inbound != nil {
+		mux.HandleFunc("/v1/webhooks/twilio/inbound", w.handleTwilioInbound).Methods(http.MethodPost)
+	}
 }
 
 func (w *Webhook) handleTwilioStatus(rw http.ResponseWriter, r *http.Request) {
@@ -51,20 +72,19 @@ func (w *Webhook) handleTwilioStatus(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	msgSid := r.PostForm.Get("MessageSid")
-	status := r.PostForm.Get("MessageStatus")
-	errCode := r.PostForm.Get("ErrorCode")
+	if w.ParseStatus == nil {
+		http.Error(rw, ErrDependency, http.StatusInternalServerError)
+		return
+	}
+	update := w.ParseStatus(r.PostForm)
+	msgSid := update.ProviderMsgID
+	status := update.VendorStatus
+	errCode := update.ErrorCode
+	newState := update.State
+	reason := string(update.FailureReason)
 
 	observability.WebhookEvents.WithLabelValues(status).Inc()
 
-	newState := ""
-	switch status {
-	case "delivered":
-		newState = "delivered"
-	case "failed", "undelivered":
-		newState = "failed"
-	}
-
 	if w.UseQueue {
 		if w.Enqueuer == nil {
 			http.Error(rw, ErrDependency, http.StatusInternalServerError)
@@ -78,6 +98,7 @@ func (w *Webhook) handleTwilioStatus(rw http.ResponseWriter, r *http.Request) {
 			ProviderMsgID: msgSid,
 			Status:        status,
 			ErrorCode:     errCode,
+			FailureReason: reason,
 			ReceivedAt:    util.NowUTC(),
 			// Payload intentionally omitted in queue mode (keeps messages small and reduces DB write load).
 		}); err != nil {
@@ -104,6 +125,7 @@ func (w *Webhook) handleTwilioStatus(rw http.ResponseWriter, r *http.Request) {
 		ProviderMsgID: msgSid,
 		VendorStatus:  status,
 		ErrorCode:     errCode,
+		FailureReason: reason,
 		Payload:       r.PostForm,
 		OccurredAt:    nil,
 	}); err != nil {
@@ -134,12 +156,26 @@ func (w *Webhook) handleTwilioStatus(rw http.ResponseWriter, r *http.Request) {
 			ProviderMsgID: msgSid,
 			NewState:      newState,
 			LastError:     errCode,
+			FailureReason: reason,
 			Now:           util.NowUTC(),
 		})
 		if lastUpdateErr != nil {
 			break
 		}
 		if updated {
+			if newState == "failed" {
+				observability.MessageFailures.WithLabelValues("twilio", reason).Inc()
+			}
+			if err := w.AutoSuppress.Apply(dbCtx, autosuppress.Receipt{
+				Provider:      "twilio",
+				ProviderMsgID: msgSid,
+				State:         newState,
+				ErrorCode:     errCode,
+				FailureReason: reason,
+			}, util.NowUTC()); err != nil {
+				// The receipt itself is applied; don't make the provider resend it for this.
+				slog.Error("webhook auto-suppress failed", "err", err, "message_sid", msgSid, "status", status)
+			}
 			rw.WriteHeader(http.StatusOK)
 			return
 		}
@@ -176,3 +212,46 @@ func (w *Webhook) handleTwilioStatus(rw http.ResponseWriter, r *http.Request) {
 	)
 	http.Error(rw, ErrDependency, http.StatusServiceUnavailable)
 }
+
+func (w *Webhook) handleTwilioInbound(rw http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(rw, ErrBadForm, http.StatusBadRequest)
+		return
+	}
+	if w.VerifySignature == nil || !w.VerifySignature(w.AuthToken, w.InboundURL, r.Header.Get("X-Twilio-Signature"), r.PostForm) {
+		http.Error(rw, ErrInvalidSignature, http.StatusUnauthorized)
+		return
+	}
+	if w.ParseInbound == nil {
+		http.Error(rw, ErrDependency, http.StatusInternalServerError)
+		return
+	}
+	msg := w.ParseInbound(r.PostForm)
+	if msg.ProviderMsgID == "" || msg.From == "" {
+		http.Error(rw, ErrBadForm, http.StatusBadRequest)
+		return
+	}
+
+	// Like status callbacks: an opt-out must be recorded even if Twilio hangs up on us.
+	dbCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	reply, err := w.Inbound.Receive(dbCtx, "twilio", msg, util.NowUTC())
+	if err != nil {
+		slog.Error("webhook inbound failed", "err", err, "message_sid", msg.ProviderMsgID)
+		http.Error(rw, ErrDependency, http.StatusServiceUnavailable)
+		return
+	}
+	observability.InboundMessages.WithLabelValues(cmp.Or(string(sms.ParseKeyword(msg.Body)), "none")).Inc()
+
+	// TwiML: Twilio sends the <Message> back to the sender; an empty <Response/> sends nothing.
+	rw.Header().Set("Content-Type", "text/xml")
+	rw.WriteHeader(http.StatusOK)
+	_, _ = rw.Write([]byte(xml.Header))
+	if reply == "" {
+		_, _ = rw.Write([]byte("<Response/>"))
+		return
+	}
+	_, _ = rw.Write([]byte("<Response><Message>"))
+	_ = xml.EscapeText(rw, []byte(reply))
+	_, _ = rw.Write([]byte("</Message></Response>"))
+}
@@ -15,16 +15,17 @@ var (
 		prometheus.CounterOpts{Name: "notif_enqueue_total", Help: "SQS enqueue results"},
 		[]string{"result"},
 	)
-	TwilioSend = prometheus.NewCounterVec(
-		prometheus.CounterOpts{Name: "twilio_send_total", Help: "Twilio send outcomes"},
-		[]string{"result", "http_status"},
+	ProviderSend = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "notif_provider_send_total", Help: "Provider send outcomes, one per provider tried"},
+		[]string{"provider", "result", "http_status"},
 	)
-	TwilioLatency = prometheus.NewHistogram(
+	ProviderLatency = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
-			Name:    "twilio_send_latency_seconds",
-			Help:    "Twilio send latency",
+			Name:    "notif_provider_send_latency_seconds",
+			Help:    "Latency of successful provider send calls",
 			Buckets: []float64{0.05, 0.1, 0.2, 0.5, 1, 2, 3, 5, 8, 13, 21},
 		},
+		[]string{"provider"},
 	)
 	EndToEndLatency = prometheus.NewHistogram(
 		prometheus.HistogramOpts{
@@ -44,6 +45,41 @@ var (
 			Buckets: []float64{0.05, 0.1, 0.2, 0.5, 1, 2, 5, 10, 20, 30, 60, 120, 180},
 		},
 	)
+	SMSSegments = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "notif_sms_segments_total", Help: "SMS segments submitted to providers"},
+		[]string{"provider", "encoding"},
+	)
+	MessagesExpired = prometheus.NewCounter(
+		prometheus.CounterOpts{Name: "notif_messages_expired_total", Help: "Messages dropped unsent because they reached expiresAt"},
+	)
+	MessageFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "notif_message_failures_total", Help: "Messages that ended failed, by provider and normalized failure reason"},
+		[]string{"provider", "reason"},
+	)
+	AutoSuppressions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "notif_auto_suppressions_total", Help: "Numbers suppressed automatically after hard delivery failures, by failure reason"},
+		[]string{"reason"},
+	)
+	InboundMessages = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "notif_inbound_messages_total", Help: "SMS received from recipients, by recognized keyword (none for other messages)"},
+		[]string{"keyword"},
+	)
+	RateLimited = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "notif_rate_limited_total", Help: "Sends refused by the shared rate limiter because the wait was too long"},
+		[]string{"scope"},
+	)
+	RateLimitWaitSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "notif_rate_limit_wait_seconds",
+			Help:    "Time sends waited for a shared rate limit token",
+			Buckets: []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10},
+		},
+		[]string{"scope"},
+	)
+	ProviderSendRate = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: "notif_provider_send_rate", Help: "Current adaptive per-pod send rate (messages/second)"},
+		[]string{"provider"},
+	)
 	WebhookEvents = prometheus.NewCounterVec(
 		prometheus.CounterOpts{Name: "twilio_webhook_events_total", Help: "Webhook events"},
 		[]string{"status"},
@@ -66,11 +102,24 @@ func RegisterAPI(reg prometheus.Registerer) {
 
 func RegisterWorker(reg prometheus.Registerer) {
 	reg.MustRegister(
-		TwilioSend,
-		TwilioLatency,
+		ProviderSend,
+		ProviderLatency,
 		EndToEndLatency,
 		WorkerProcessed,
 		WorkerProcessingSeconds,
+		SMSSegments,
+		MessagesExpired,
+		MessageFailures,
+		RateLimited,
+		RateLimitWaitSeconds,
+		ProviderSendRate,
+	)
+}
+
+func RegisterWebhookProcessor(reg prometheus.Registerer) {
+	reg.MustRegister(
+		MessageFailures,
+		AutoSuppressions,
 	)
 }
 
@@ -79,6 +128,9 @@ func RegisterWebhook(reg prometheus.Registerer) {
 		WebhookRequests,
 		WebhookEvents,
 		WebhookMessageUpdateNotFound,
+		MessageFailures,
+		AutoSuppressions,
+		InboundMessages,
 	)
 
 	// CounterVec does not emit any time series until a label set is used at least once.
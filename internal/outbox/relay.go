@@ -0,0 +1,151 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"notif/internal/observability"
+	sqsqueue "notif/internal/queue/sqs"
+	"notif/internal/store"
+	"notif/internal/util"
+)
+
+type Store interface {
+	ClaimOutbox(ctx context.Context, limit int, now time.Time, lease time.Duration) ([]store.OutboxRow, error)
+	MarkOutboxSent(ctx context.Context, ids []int64, now time.Time) error
+	MarkOutboxRetry(ctx context.Context, id int64, lastError string, nextAttemptAt time.Time) error
+}
+
+type Publisher interface {
+	EnqueueSMSBatch(ctx context.Context, jobs []sqsqueue.SMSJob) []error
+}
+
+// Relay publishes pending outbox rows to SQS. Several relays (one per API pod) can run at once:
+// rows are leased with SKIP LOCKED, and FIFO dedup on the idempotency key absorbs the rare
+// double publish after a lease expires.
+type Relay struct {
+	Store     Store
+	Publisher Publisher
+
+	BatchSize    int
+	PollInterval time.Duration
+	// Lease is how long a claimed row stays invisible to other relays.
+	Lease time.Duration
+	// MaxBackoff caps the exponential retry delay after publish failures.
+	MaxBackoff time.Duration
+	// DelayHorizon publishes scheduled rows this long before they are due, leaving SQS to hold
+	// them back with DelaySeconds. Zero (required for FIFO queues) publishes rows when due.
+	DelayHorizon time.Duration
+}
+
+// Run polls until ctx is canceled. A full batch is followed immediately by another claim so
+// a backlog drains without waiting for the poll interval.
+func (r *Relay) Run(ctx context.Context) error {
+	for {
+		n, err := r.RelayOnce(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			slog.Error("outbox relay failed", "err", err)
+		}
+		if err == nil && n >= r.batchSize() {
+			continue
+		}
+		t := time.NewTimer(r.pollInterval())
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// RelayOnce claims one batch, publishes it and records the outcome. It returns the number of rows claimed.
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	now := util.NowUTC()
+	rows, err := r.Store.ClaimOutbox(ctx, r.batchSize(), now.Add(r.DelayHorizon), r.lease())
+	if err != nil {
+		return 0, err
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	jobs := make([]sqsqueue.SMSJob, 0, len(rows))
+	jobRows := make([]store.OutboxRow, 0, len(rows))
+	var sent []int64
+	for _, row := range rows {
+		var job sqsqueue.SMSJob
+		if err := json.Unmarshal(row.Payload, &job); err != nil {
+			// Can't ever be published; park it far out and keep the error for inspection.
+			slog.Error("outbox payload invalid", "err", err, "outbox_id", row.ID, "message_id", row.MessageID)
+			if err := r.Store.MarkOutboxRetry(ctx, row.ID, "invalid_payload: "+err.Error(), now.Add(24*time.Hour)); err != nil {
+				return len(rows), err
+			}
+			continue
+		}
+		jobs = append(jobs, job)
+		jobRows = append(jobRows, row)
+	}
+
+	for i, err := range r.Publisher.EnqueueSMSBatch(ctx, jobs) {
+		row := jobRows[i]
+		if err == nil {
+			observability.Enqueues.WithLabelValues("ok").Inc()
+			sent = append(sent, row.ID)
+			continue
+		}
+		observability.Enqueues.WithLabelValues("error").Inc()
+		slog.Error("outbox publish failed", "err", err, "outbox_id", row.ID, "message_id", row.MessageID, "attempts", row.Attempts)
+		if err := r.Store.MarkOutboxRetry(ctx, row.ID, err.Error(), util.NowUTC().Add(r.backoff(row.Attempts))); err != nil {
+			return len(rows), err
+		}
+	}
+
+	// If this fails the rows are republished after the lease; FIFO dedup makes that harmless.
+	return len(rows), r.Store.MarkOutboxSent(ctx, sent, util.NowUTC())
+}
+
+// backoff is 1s, 2s, 4s, ... capped at MaxBackoff.
+func (r *Relay) backoff(attempts int) time.Duration {
+	maxBackoff := r.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = 5 * time.Minute
+	}
+	if attempts < 1 {
+		attempts = 1
+	}
+	if attempts > 20 {
+		return maxBackoff
+	}
+	d := time.Second << (attempts - 1)
+	if d > maxBackoff {
+		return maxBackoff
+	}
+	return d
+}
+
+func (r *Relay) batchSize() int {
+	if r.BatchSize <= 0 {
+		return 100
+	}
+	return r.BatchSize
+}
+
+func (r *Relay) pollInterval() time.Duration {
+	if r.PollInterval <= 0 {
+		return 200 * time.Millisecond
+	}
+	return r.PollInterval
+}
+
+func (r *Relay) lease() time.Duration {
+	if r.Lease <= 0 {
+		return 30 * time.Second
+	}
+	return r.Lease
+}
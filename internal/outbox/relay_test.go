@@ -0,0 +1,74 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	sqsqueue "notif/internal/queue/sqs"
+	"notif/internal/store"
+)
+
+type fakeStore struct {
+	rows    []store.OutboxRow
+	sent    []int64
+	retried map[int64]string
+}
+
+func (f *fakeStore) ClaimOutbox(ctx context.Context, limit int, now time.Time, lease time.Duration) ([]store.OutboxRow, error) {
+	out := f.rows
+	f.rows = nil
+	return out, nil
+}
+
+func (f *fakeStore) MarkOutboxSent(ctx context.Context, ids []int64, now time.Time) error {
+	f.sent = append(f.sent, ids...)
+	return nil
+}
+
+func (f *fakeStore) MarkOutboxRetry(ctx context.Context, id int64, lastError string, nextAttemptAt time.Time) error {
+	f.retried[id] = lastError
+	return nil
+}
+
+type fakePublisher struct{ failMessageID string }
+
+func (f fakePublisher) EnqueueSMSBatch(ctx context.Context, jobs []sqsqueue.SMSJob) []error {
+	errs := make([]error, len(jobs))
+	for i, j := range jobs {
+		if j.MessageID == f.failMessageID {
+			errs[i] = errors.New("boom")
+		}
+	}
+	return errs
+}
+
+func TestRelayOnce(t *testing.T) {
+	st := &fakeStore{
+		rows: []store.OutboxRow{
+			{ID: 1, MessageID: "m1", Payload: []byte(`{"messageId":"m1"}`), Attempts: 1},
+			{ID: 2, MessageID: "m2", Payload: []byte(`{"messageId":"m2"}`), Attempts: 1},
+			{ID: 3, MessageID: "m3", Payload: []byte(`not json`), Attempts: 1},
+		},
+		retried: map[int64]string{},
+	}
+	r := &Relay{Store: st, Publisher: fakePublisher{failMessageID: "m2"}}
+
+	n, err := r.RelayOnce(context.Background())
+	if err != nil {
+		t.Fatalf("relay: %v", err)
+	}
+	if n != 3 {
+		t.Fatalf("expected 3 claimed, got %d", n)
+	}
+	if len(st.sent) != 1 || st.sent[0] != 1 {
+		t.Fatalf("expected only row 1 sent, got %v", st.sent)
+	}
+	if st.retried[2] != "boom" {
+		t.Fatalf("expected row 2 scheduled for retry, got %q", st.retried[2])
+	}
+	if _, ok := st.retried[3]; !ok {
+		t.Fatalf("expected invalid row 3 parked")
+	}
+}
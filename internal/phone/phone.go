@@ -0,0 +1,114 @@
+// Package phone normalizes phone numbers to E.164, the form every table keys on.
+package phone
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"time"
+	_ "time/tzdata" // zone names come from libphonenumber, not the host
+
+	"github.com/nyaruka/phonenumbers"
+)
+
+var ErrInvalid = errors.New("invalid phone number")
+
+// Number is a validated phone number.
+type Number struct {
+	E164 string // +15551234567
+	// CountryCode is the calling code (1, 44, 91, ...).
+	CountryCode int
+	// Region is the ISO 3166-1 alpha-2 region ("US", "GB", ...), or "001" for non-geographic numbers.
+	Region string
+}
+
+var defaultRegion atomic.Value // string
+
+func init() { defaultRegion.Store("US") }
+
+// SetDefaultRegion sets the region used for numbers written without a country code
+// ("(555) 123-4567"). Services call it once at startup from config.
+func SetDefaultRegion(region string) {
+	if region != "" {
+		defaultRegion.Store(strings.ToUpper(region))
+	}
+}
+
+func DefaultRegion() string { return defaultRegion.Load().(string) }
+
+// Parse validates raw and returns it in E.164. Numbers without a leading + (or 00 international
+// prefix) are read as national numbers of the default region.
+func Parse(raw string) (Number, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return Number{}, ErrInvalid
+	}
+	if strings.HasPrefix(raw, "00") {
+		raw = "+" + raw[2:]
+	}
+	n, err := phonenumbers.Parse(raw, DefaultRegion())
+	// Possible (right length for the country) rather than valid: number plans change faster than
+	// the metadata, and rejecting a real customer's number is worse than a failed send.
+	if err != nil || !phonenumbers.IsPossibleNumber(n) {
+		return Number{}, ErrInvalid
+	}
+	return Number{
+		E164:        phonenumbers.Format(n, phonenumbers.E164),
+		CountryCode: int(n.GetCountryCode()),
+		Region:      regionOf(n),
+	}, nil
+}
+
+// regionOf falls back to the calling code's main region for numbers outside the known ranges.
+func regionOf(n *phonenumbers.PhoneNumber) string {
+	if region := phonenumbers.GetRegionCodeForNumber(n); region != "" && region != "ZZ" {
+		return region
+	}
+	if region := phonenumbers.GetRegionCodeForCountryCode(int(n.GetCountryCode())); region != "ZZ" {
+		return region
+	}
+	return ""
+}
+
+// Normalize returns the E.164 form of raw if it is valid, otherwise raw with whitespace removed.
+// Use it for lookups (filters, deletes) where an unparseable input should simply not match.
+func Normalize(raw string) string {
+	if n, err := Parse(raw); err == nil {
+		return n.E164
+	}
+	return strings.Join(strings.Fields(raw), "")
+}
+
+// Region returns the region of an E.164 number, or "" if it cannot be determined.
+func Region(e164 string) string {
+	n, err := phonenumbers.Parse(e164, DefaultRegion())
+	if err != nil {
+		return ""
+	}
+	return regionOf(n)
+}
+
+// Location returns the recipient's time zone at t. Numbers whose prefix maps to zones with
+// different UTC offsets (a US number without a known area code, most of Russia, ...) are
+// ambiguous and return false, leaving the choice of fallback to the caller.
+func Location(e164 string, t time.Time) (*time.Location, bool) {
+	zones, err := phonenumbers.GetTimezonesForPrefix(e164)
+	if err != nil || len(zones) == 0 {
+		return nil, false
+	}
+	var loc *time.Location
+	var offset int
+	for _, name := range zones {
+		l, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, false // includes libphonenumber's "Etc/Unknown"
+		}
+		_, off := t.In(l).Zone()
+		if loc == nil {
+			loc, offset = l, off
+		} else if off != offset {
+			return nil, false
+		}
+	}
+	return loc, true
+}
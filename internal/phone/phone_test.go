@@ -0,0 +1,70 @@
+package phone
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		in     string
+		e164   string
+		cc     int
+		region string
+	}{
+		{"+1 (415) 555-2671", "+14155552671", 1, "US"},
+		{"4155552671", "+14155552671", 1, "US"},
+		{"(415) 555-2671", "+14155552671", 1, "US"},
+		{"0044 20 7946 0958", "+442079460958", 44, "GB"},
+		{"+44 (0)20 7946 0958", "+442079460958", 44, "GB"},
+		{"+91 98765 43210", "+919876543210", 91, "IN"},
+		{"+1 999 000 0001", "+19990000001", 1, "US"}, // test range, possible but not assigned
+	}
+	for _, tc := range cases {
+		n, err := Parse(tc.in)
+		if err != nil {
+			t.Fatalf("%q: %v", tc.in, err)
+		}
+		if n.E164 != tc.e164 || n.CountryCode != tc.cc || n.Region != tc.region {
+			t.Fatalf("%q: expected %s/%d/%s, got %+v", tc.in, tc.e164, tc.cc, tc.region, n)
+		}
+	}
+
+	for _, bad := range []string{"", "abc", "123", "+1 555", "+999 1234567"} {
+		if _, err := Parse(bad); err != ErrInvalid {
+			t.Fatalf("%q: expected ErrInvalid, got %v", bad, err)
+		}
+	}
+}
+
+func TestDefaultRegion(t *testing.T) {
+	defer SetDefaultRegion("US")
+	SetDefaultRegion("gb")
+	n, err := Parse("020 7946 0958")
+	if err != nil || n.E164 != "+442079460958" {
+		t.Fatalf("expected GB national number, got %+v err=%v", n, err)
+	}
+	if Normalize(" not a number ") != "notanumber" {
+		t.Fatalf("expected whitespace-stripped fallback")
+	}
+}
+
+func TestLocation(t *testing.T) {
+	at := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		in   string
+		zone string
+		ok   bool
+	}{
+		{"+12125550100", "America/New_York", true},
+		{"+14155550100", "America/Los_Angeles", true},
+		{"+447700900123", "Europe/Guernsey", true}, // same offset as London
+		{"+15550001111", "", false},                // 555 is not tied to one area
+	}
+	for _, tc := range cases {
+		loc, ok := Location(tc.in, at)
+		if ok != tc.ok || (ok && loc.String() != tc.zone) {
+			t.Fatalf("%s: expected %q/%v, got %v/%v", tc.in, tc.zone, tc.ok, loc, ok)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package providers
+
+// FailureReason is a provider-neutral cause of a failed send or delivery, so tenants can act on
+// it without knowing each vendor's error codes. It is stored in messages.failure_reason and
+// provider_attempts.failure_reason.
+type FailureReason string
+
+const (
+	// ReasonInvalidNumber: the number doesn't exist or isn't a valid phone number.
+	ReasonInvalidNumber FailureReason = "invalid_number"
+	// ReasonLandline: the number can't receive SMS (landline, VoIP without SMS).
+	ReasonLandline FailureReason = "landline"
+	// ReasonCarrierBlocked: the carrier refused the message.
+	ReasonCarrierBlocked FailureReason = "carrier_blocked"
+	// ReasonUnreachable: the handset is off, out of coverage or roaming where we can't reach it.
+	ReasonUnreachable FailureReason = "unreachable"
+	// ReasonSpamFiltered: the carrier filtered the content as spam.
+	ReasonSpamFiltered FailureReason = "spam_filtered"
+	// ReasonOptedOut: the recipient unsubscribed at the provider (e.g. replied STOP).
+	ReasonOptedOut FailureReason = "opted_out"
+	// ReasonContentRejected: the body itself was refused (empty, too long, malformed).
+	ReasonContentRejected FailureReason = "content_rejected"
+	// ReasonInvalidTemplate: the template was missing or could not be rendered at send time.
+	ReasonInvalidTemplate FailureReason = "invalid_template"
+	// ReasonAccountIssue: our account or sender isn't allowed to send this (auth, suspension,
+	// unregistered sender, region not enabled).
+	ReasonAccountIssue FailureReason = "account_issue"
+	// ReasonRateLimited: the provider or carrier throttled us.
+	ReasonRateLimited FailureReason = "rate_limited"
+	// ReasonProviderError: the provider failed or was unavailable (5xx, timeout, open breaker).
+	ReasonProviderError FailureReason = "provider_error"
+	// ReasonUnknown: the provider gave no usable detail.
+	ReasonUnknown FailureReason = "unknown"
+)
+
+// ReasonForStatus classifies a failed send from its HTTP status alone, for providers or codes
+// without a more specific mapping. Status 0 means the request never got a response.
+func ReasonForStatus(httpStatus int) FailureReason {
+	switch {
+	case httpStatus == 0 || httpStatus == 408 || httpStatus >= 500:
+		return ReasonProviderError
+	case httpStatus == 429:
+		return ReasonRateLimited
+	case httpStatus == 401 || httpStatus == 403:
+		return ReasonAccountIssue
+	default:
+		return ReasonUnknown
+	}
+}
+
+// Reason returns the failure reason of a failed send: the provider's own mapping when it set
+// one, otherwise one derived from the HTTP status.
+func (r SendResult) Reason() FailureReason {
+	if r.FailureReason != "" {
+		return r.FailureReason
+	}
+	return ReasonForStatus(r.HTTPStatus)
+}
@@ -0,0 +1,124 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Provider is an outbound SMS vendor. The worker only talks to vendors through this interface,
+// so adding a new one means implementing it and registering it in cmd/worker.
+type Provider interface {
+	// Name is the stable identifier recorded in messages.provider and provider_attempts.provider.
+	Name() string
+	Send(ctx context.Context, req SendRequest) (SendResult, error)
+	// Classify decides whether a failed Send is worth retrying.
+	Classify(err error, res SendResult) ErrorClass
+	ParseStatusCallback(form url.Values) StatusUpdate
+}
+
+type SendRequest struct {
+	To                string
+	Body              string
+	StatusCallbackURL string
+}
+
+// SendResult is populated on success and, as far as the vendor told us, on failure too.
+type SendResult struct {
+	ProviderMsgID string
+	Status        string
+	HTTPStatus    int
+	ErrorCode     string
+	Raw           []byte
+	// RetryAfter is the vendor's Retry-After on throttling responses (zero if absent).
+	RetryAfter time.Duration
+	// FailureReason is the vendor's error mapped to our taxonomy; see SendResult.Reason.
+	FailureReason FailureReason
+}
+
+type ErrorClass int
+
+const (
+	ErrorPermanent ErrorClass = iota
+	ErrorRetryable
+)
+
+// StatusUpdate is a vendor delivery callback normalized to our message states.
+// State is empty for non-terminal statuses (queued/sent/etc).
+type StatusUpdate struct {
+	ProviderMsgID string
+	VendorStatus  string
+	ErrorCode     string
+	State         string
+	// FailureReason is set for failed deliveries.
+	FailureReason FailureReason
+}
+
+// InboundMessage is an SMS a recipient sent to one of our numbers.
+type InboundMessage struct {
+	ProviderMsgID string
+	From          string
+	To            string
+	Body          string
+}
+
+// ParseRetryAfter reads a Retry-After header value, either delay-seconds or an HTTP date.
+// Missing, malformed and past values yield zero.
+func ParseRetryAfter(v string, now time.Time) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return max(0, time.Duration(secs)*time.Second)
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(0, t.Sub(now))
+	}
+	return 0
+}
+
+var ErrDuplicateProvider = errors.New("duplicate provider")
+
+// Registry holds the configured providers keyed by name, in registration order.
+// It is built once at startup and read-only afterwards.
+type Registry struct {
+	byName map[string]Provider
+	order  []string
+}
+
+func NewRegistry(ps ...Provider) (*Registry, error) {
+	r := &Registry{byName: make(map[string]Provider, len(ps))}
+	for _, p := range ps {
+		if err := r.Register(p); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func (r *Registry) Register(p Provider) error {
+	if _, ok := r.byName[p.Name()]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateProvider, p.Name())
+	}
+	r.byName[p.Name()] = p
+	r.order = append(r.order, p.Name())
+	return nil
+}
+
+func (r *Registry) Get(name string) (Provider, bool) {
+	p, ok := r.byName[name]
+	return p, ok
+}
+
+// Names returns provider names in registration order.
+func (r *Registry) Names() []string {
+	out := make([]string, len(r.order))
+	copy(out, r.order)
+	return out
+}
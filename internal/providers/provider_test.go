@@ -0,0 +1,43 @@
+package providers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cases := map[string]time.Duration{
+		"":                              0,
+		"3":                             3 * time.Second,
+		" 10 ":                          10 * time.Second,
+		"-5":                            0,
+		"soon":                          0,
+		"Mon, 01 Jan 2024 12:00:30 GMT": 30 * time.Second,
+		"Mon, 01 Jan 2024 11:59:00 GMT": 0,
+	}
+	for in, want := range cases {
+		if got := ParseRetryAfter(in, now); got != want {
+			t.Fatalf("ParseRetryAfter(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestSendResultReason(t *testing.T) {
+	cases := []struct {
+		res  SendResult
+		want FailureReason
+	}{
+		{SendResult{HTTPStatus: 400, FailureReason: ReasonLandline}, ReasonLandline},
+		{SendResult{HTTPStatus: 429}, ReasonRateLimited},
+		{SendResult{HTTPStatus: 503}, ReasonProviderError},
+		{SendResult{}, ReasonProviderError},
+		{SendResult{HTTPStatus: 401}, ReasonAccountIssue},
+		{SendResult{HTTPStatus: 400}, ReasonUnknown},
+	}
+	for _, tc := range cases {
+		if got := tc.res.Reason(); got != tc.want {
+			t.Fatalf("Reason(%+v) = %s, want %s", tc.res, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"notif/internal/phone"
+)
+
+// WeightedProvider is one leg of a weighted split.
+type WeightedProvider struct {
+	Provider string `json:"provider"`
+	Weight   int    `json:"weight"`
+}
+
+// Rule routes messages for a tenant and/or destination. Country is an ISO region ("US", "GB")
+// derived from the E.164 number; Prefix matches the raw number. Empty fields match anything.
+// The primary is picked from Split by weight; the remaining Split providers and then Failover
+// are tried in order if it can't send.
+type Rule struct {
+	TenantID string             `json:"tenantId,omitempty"`
+	Country  string             `json:"country,omitempty"`
+	Prefix   string             `json:"prefix,omitempty"`
+	Split    []WeightedProvider `json:"split"`
+	Failover []string           `json:"failover,omitempty"`
+}
+
+func (r Rule) matches(tenantID, to string) bool {
+	if r.TenantID != "" && r.TenantID != tenantID {
+		return false
+	}
+	if r.Country != "" && !strings.EqualFold(r.Country, phone.Region(to)) {
+		return false
+	}
+	if r.Prefix != "" && !strings.HasPrefix(to, r.Prefix) {
+		return false
+	}
+	return true
+}
+
+// ParseRules decodes the SMS_ROUTING_RULES JSON array. Empty input means no rules.
+func ParseRules(raw string) ([]Rule, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var rules []Rule
+	if err := json.Unmarshal([]byte(raw), &rules); err != nil {
+		return nil, fmt.Errorf("invalid routing rules: %w", err)
+	}
+	return rules, nil
+}
+
+// Router turns (tenant, destination) into an ordered list of provider names to try.
+// Rules are evaluated in order and the first match wins.
+type Router struct {
+	Rules []Rule
+	// Default is used when no rule matches.
+	Default []string
+	// Rand returns a value in [0,1). Nil uses math/rand.
+	Rand func() float64
+}
+
+// NewRouter validates rules against the registry. With no matching rule, messages go to
+// defaultProvider first and fail over to the other registered providers in registration order.
+func NewRouter(reg *Registry, defaultProvider string, rules []Rule) (*Router, error) {
+	for i, rule := range rules {
+		if len(rule.Split) == 0 {
+			return nil, fmt.Errorf("routing rule %d: split is empty", i)
+		}
+		for _, wp := range rule.Split {
+			if _, ok := reg.Get(wp.Provider); !ok {
+				return nil, fmt.Errorf("routing rule %d: unknown provider %q", i, wp.Provider)
+			}
+			if wp.Weight < 0 {
+				return nil, fmt.Errorf("routing rule %d: negative weight for %q", i, wp.Provider)
+			}
+		}
+		for _, name := range rule.Failover {
+			if _, ok := reg.Get(name); !ok {
+				return nil, fmt.Errorf("routing rule %d: unknown failover provider %q", i, name)
+			}
+		}
+	}
+	return &Router{Rules: rules, Default: DefaultOrder(reg, defaultProvider)}, nil
+}
+
+// DefaultOrder puts defaultProvider first followed by every other registered provider.
+func DefaultOrder(reg *Registry, defaultProvider string) []string {
+	names := reg.Names()
+	if _, ok := reg.Get(defaultProvider); !ok {
+		return names
+	}
+	out := []string{defaultProvider}
+	for _, n := range names {
+		if n != defaultProvider {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+func (r *Router) Plan(tenantID, to string) []string {
+	for _, rule := range r.Rules {
+		if rule.matches(tenantID, to) {
+			return r.planFor(rule)
+		}
+	}
+	return append([]string(nil), r.Default...)
+}
+
+func (r *Router) planFor(rule Rule) []string {
+	primary := r.pickWeighted(rule.Split)
+
+	seen := map[string]bool{}
+	out := make([]string, 0, len(rule.Split)+len(rule.Failover))
+	add := func(name string) {
+		if name == "" || seen[name] {
+			return
+		}
+		seen[name] = true
+		out = append(out, name)
+	}
+	add(primary)
+	for _, wp := range rule.Split {
+		add(wp.Provider)
+	}
+	for _, name := range rule.Failover {
+		add(name)
+	}
+	return out
+}
+
+func (r *Router) pickWeighted(split []WeightedProvider) string {
+	total := 0
+	for _, wp := range split {
+		total += wp.Weight
+	}
+	if total <= 0 {
+		return split[0].Provider
+	}
+	rnd := rand.Float64
+	if r.Rand != nil {
+		rnd = r.Rand
+	}
+	target := rnd() * float64(total)
+	cumulative := 0.0
+	for _, wp := range split {
+		cumulative += float64(wp.Weight)
+		if target < cumulative {
+			return wp.Provider
+		}
+	}
+	return split[len(split)-1].Provider
+}
@@ -0,0 +1,61 @@
+package providers
+
+import (
+	"context"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+type stubProvider string
+
+func (s stubProvider) Name() string { return string(s) }
+func (s stubProvider) Send(ctx context.Context, req SendRequest) (SendResult, error) {
+	return SendResult{}, nil
+}
+func (s stubProvider) Classify(err error, res SendResult) ErrorClass { return ErrorPermanent }
+func (s stubProvider) ParseStatusCallback(form url.Values) StatusUpdate {
+	return StatusUpdate{}
+}
+
+func TestRouterPlan(t *testing.T) {
+	reg, err := NewRegistry(stubProvider("a"), stubProvider("b"), stubProvider("c"))
+	if err != nil {
+		t.Fatalf("registry: %v", err)
+	}
+	rules, err := ParseRules(`[
+		{"tenantId":"t1","prefix":"+91","split":[{"provider":"b","weight":80},{"provider":"c","weight":20}],"failover":["a"]},
+		{"prefix":"+44","split":[{"provider":"c","weight":1}]},
+		{"country":"CA","split":[{"provider":"a","weight":1}]}
+	]`)
+	if err != nil {
+		t.Fatalf("parse rules: %v", err)
+	}
+	r, err := NewRouter(reg, "b", rules)
+	if err != nil {
+		t.Fatalf("router: %v", err)
+	}
+
+	r.Rand = func() float64 { return 0.9 }
+	if got, want := r.Plan("t1", "+919999999999"), []string{"c", "b", "a"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("weighted plan: got %v want %v", got, want)
+	}
+	r.Rand = func() float64 { return 0.1 }
+	if got, want := r.Plan("t1", "+919999999999"), []string{"b", "c", "a"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("weighted plan: got %v want %v", got, want)
+	}
+	if got, want := r.Plan("t2", "+447700900000"), []string{"c"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("prefix plan: got %v want %v", got, want)
+	}
+	// Canada shares +1 with the US, so only the region tells them apart.
+	if got, want := r.Plan("t2", "+12045550123"), []string{"a"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("country plan: got %v want %v", got, want)
+	}
+	if got, want := r.Plan("t2", "+15550001111"), []string{"b", "a", "c"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("default plan: got %v want %v", got, want)
+	}
+
+	if _, err := NewRouter(reg, "a", []Rule{{Split: []WeightedProvider{{Provider: "zzz", Weight: 1}}}}); err == nil {
+		t.Fatalf("expected unknown provider error")
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"net/url"
 	"strings"
 	"time"
+
+	"notif/internal/providers"
 )
 
 type Client struct {
@@ -33,6 +35,8 @@ type SendResponse struct {
 	Status    string `json:"status"`
 	ErrorCode *int   `json:"error_code"`
 	Message   string `json:"message"`
+	// RetryAfter comes from the Retry-After header Twilio sends with 429s.
+	RetryAfter time.Duration `json:"-"`
 }
 
 func (c *Client) SendSMS(ctx context.Context, req SendRequest) (SendResponse, int, []byte, error) {
@@ -66,6 +70,7 @@ func (c *Client) SendSMS(ctx context.Context, req SendRequest) (SendResponse, in
 
 	var out SendResponse
 	_ = json.Unmarshal(b, &out)
+	out.RetryAfter = providers.ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
 
 	// Twilio returns 201 for created; treat 2xx as success
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
@@ -77,8 +82,16 @@ func (c *Client) SendSMS(ctx context.Context, req SendRequest) (SendResponse, in
 	return out, resp.StatusCode, b, nil
 }
 
-// Retry decision for transient errors
+// Retry decision for transient errors.
+// HTTP status is checked first: SendSMS returns a non-nil error for every non-2xx response,
+// so checking err first would never retry 429/5xx.
 func ShouldRetry(err error, httpStatus int) bool {
+	if httpStatus == 429 || httpStatus == 408 {
+		return true
+	}
+	if httpStatus >= 500 && httpStatus <= 599 {
+		return true
+	}
 	if err != nil {
 		if errors.Is(err, context.DeadlineExceeded) {
 			return true
@@ -87,25 +100,6 @@ func ShouldRetry(err error, httpStatus int) bool {
 		if errors.As(err, &ne) && ne.Timeout() {
 			return true
 		}
-		return false
-	}
-	if httpStatus == 429 || httpStatus == 408 {
-		return true
-	}
-	if httpStatus >= 500 && httpStatus <= 599 {
-		return true
 	}
 	return false
 }
-
-func Backoff(attempt int) time.Duration {
-	// 200ms, 600ms, 1400ms approx (with small jitter)
-	base := []time.Duration{200 * time.Millisecond, 600 * time.Millisecond, 1400 * time.Millisecond}
-	if attempt <= 0 {
-		return base[0]
-	}
-	if attempt >= len(base) {
-		return base[len(base)-1]
-	}
-	return base[attempt]
-}
@@ -0,0 +1,51 @@
+package twilio
+
+import "notif/internal/providers"
+
+// errorReasons maps Twilio error codes (API errors on send, ErrorCode on status callbacks) to
+// our failure taxonomy. Codes not listed fall back to the HTTP status.
+var errorReasons = map[string]providers.FailureReason{
+	// Send API errors
+	"20003": providers.ReasonAccountIssue,    // authentication failed
+	"20429": providers.ReasonRateLimited,     // too many requests
+	"21211": providers.ReasonInvalidNumber,   // invalid 'To' phone number
+	"21408": providers.ReasonAccountIssue,    // permission to send to this region not enabled
+	"21602": providers.ReasonContentRejected, // message body is required
+	"21606": providers.ReasonAccountIssue,    // 'From' number can't send SMS
+	"21610": providers.ReasonOptedOut,        // recipient unsubscribed (STOP)
+	"21611": providers.ReasonRateLimited,     // sender queue full
+	"21612": providers.ReasonUnreachable,     // can't route to this number
+	"21614": providers.ReasonLandline,        // 'To' is not a mobile number
+	"21617": providers.ReasonContentRejected, // body exceeds 1600 characters
+
+	// Delivery errors
+	"30001": providers.ReasonRateLimited,    // queue overflow
+	"30002": providers.ReasonAccountIssue,   // account suspended
+	"30003": providers.ReasonUnreachable,    // handset unreachable
+	"30004": providers.ReasonCarrierBlocked, // message blocked
+	"30005": providers.ReasonInvalidNumber,  // unknown destination handset
+	"30006": providers.ReasonLandline,       // landline or unreachable carrier
+	"30007": providers.ReasonSpamFiltered,   // carrier filtering
+	"30008": providers.ReasonUnknown,        // unknown error
+	"30022": providers.ReasonRateLimited,    // 10DLC throughput exceeded
+	"30023": providers.ReasonRateLimited,    // 10DLC daily cap reached
+	"30032": providers.ReasonAccountIssue,   // toll-free number not verified
+	"30034": providers.ReasonAccountIssue,   // unregistered 10DLC sender
+	"30410": providers.ReasonProviderError,  // provider timeout
+}
+
+// SendFailureReason maps the error of a failed send, falling back to its HTTP status.
+func SendFailureReason(code string, httpStatus int) providers.FailureReason {
+	if r, ok := errorReasons[code]; ok {
+		return r
+	}
+	return providers.ReasonForStatus(httpStatus)
+}
+
+// DeliveryFailureReason maps the ErrorCode of a failed or undelivered status callback.
+func DeliveryFailureReason(code string) providers.FailureReason {
+	if r, ok := errorReasons[code]; ok {
+		return r
+	}
+	return providers.ReasonUnknown
+}
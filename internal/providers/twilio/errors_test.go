@@ -0,0 +1,29 @@
+package twilio
+
+import (
+	"net/url"
+	"testing"
+
+	"notif/internal/providers"
+)
+
+func TestFailureReasons(t *testing.T) {
+	if r := SendFailureReason("21614", 400); r != providers.ReasonLandline {
+		t.Fatalf("expected landline for 21614, got %s", r)
+	}
+	if r := SendFailureReason("", 503); r != providers.ReasonProviderError {
+		t.Fatalf("expected provider_error for a bare 503, got %s", r)
+	}
+	if r := DeliveryFailureReason("99999"); r != providers.ReasonUnknown {
+		t.Fatalf("expected unknown for an unmapped code, got %s", r)
+	}
+
+	st := ParseStatusCallback(url.Values{"MessageSid": {"SM1"}, "MessageStatus": {"undelivered"}, "ErrorCode": {"30007"}})
+	if st.State != "failed" || st.FailureReason != providers.ReasonSpamFiltered {
+		t.Fatalf("unexpected update %+v", st)
+	}
+	st = ParseStatusCallback(url.Values{"MessageSid": {"SM1"}, "MessageStatus": {"delivered"}})
+	if st.FailureReason != "" {
+		t.Fatalf("expected no reason for a delivery, got %+v", st)
+	}
+}
@@ -0,0 +1,75 @@
+package twilio
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+
+	"notif/internal/providers"
+)
+
+const ProviderName = "twilio"
+
+var _ providers.Provider = (*Client)(nil)
+
+func (c *Client) Name() string { return ProviderName }
+
+func (c *Client) Send(ctx context.Context, req providers.SendRequest) (providers.SendResult, error) {
+	resp, httpStatus, raw, err := c.SendSMS(ctx, SendRequest{
+		To:                req.To,
+		Body:              req.Body,
+		StatusCallbackURL: req.StatusCallbackURL,
+	})
+	res := providers.SendResult{
+		ProviderMsgID: resp.Sid,
+		Status:        resp.Status,
+		HTTPStatus:    httpStatus,
+		Raw:           raw,
+		RetryAfter:    resp.RetryAfter,
+	}
+	if resp.ErrorCode != nil {
+		res.ErrorCode = strconv.Itoa(*resp.ErrorCode)
+	}
+	if err != nil {
+		res.FailureReason = SendFailureReason(res.ErrorCode, httpStatus)
+	}
+	return res, err
+}
+
+func (c *Client) Classify(err error, res providers.SendResult) providers.ErrorClass {
+	if ShouldRetry(err, res.HTTPStatus) {
+		return providers.ErrorRetryable
+	}
+	return providers.ErrorPermanent
+}
+
+func (c *Client) ParseStatusCallback(form url.Values) providers.StatusUpdate {
+	return ParseStatusCallback(form)
+}
+
+// ParseStatusCallback maps a Twilio status callback form to a normalized update.
+func ParseStatusCallback(form url.Values) providers.StatusUpdate {
+	st := providers.StatusUpdate{
+		ProviderMsgID: form.Get("MessageSid"),
+		VendorStatus:  form.Get("MessageStatus"),
+		ErrorCode:     form.Get("ErrorCode"),
+	}
+	switch st.VendorStatus {
+	case "delivered":
+		st.State = "delivered"
+	case "failed", "undelivered":
+		st.State = "failed"
+		st.FailureReason = DeliveryFailureReason(st.ErrorCode)
+	}
+	return st
+}
+
+// ParseInboundMessage reads Twilio's incoming message webhook form.
+func ParseInboundMessage(form url.Values) providers.InboundMessage {
+	return providers.InboundMessage{
+		ProviderMsgID: form.Get("MessageSid"),
+		From:          form.Get("From"),
+		To:            form.Get("To"),
+		Body:          form.Get("Body"),
+	}
+}
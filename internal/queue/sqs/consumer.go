@@ -3,6 +3,7 @@ package sqsqueue
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"sync"
 	"time"
@@ -28,42 +29,14 @@ func (c *Consumer) Poll(ctx context.Context, handler Handler) error {
 			return ctx.Err()
 		}
 
-		out, err := c.SQS.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
-			QueueUrl:            &c.QueueURL,
-			MaxNumberOfMessages: c.MaxMessages,
-			WaitTimeSeconds:     c.WaitTimeSeconds,
-			VisibilityTimeout:   c.VisibilityTimeout,
-		})
+		out, err := c.SQS.ReceiveMessage(ctx, c.receiveInput())
 		if err != nil {
 			slog.Error("sqs receive message failed", "err", err)
 			time.Sleep(500 * time.Millisecond)
 			continue
 		}
 		for _, m := range out.Messages {
-			var job SMSJob
-			if m.Body == nil {
-				continue
-			}
-			if err := json.Unmarshal([]byte(*m.Body), &job); err != nil {
-				// bad payload => delete to avoid endless redrive
-				_, _ = c.SQS.DeleteMessage(ctx, &sqs.DeleteMessageInput{
-					QueueUrl:      &c.QueueURL,
-					ReceiptHandle: m.ReceiptHandle,
-				})
-				continue
-			}
-
-			err := handler(ctx, job)
-			if err == nil {
-				_, _ = c.SQS.DeleteMessage(ctx, &sqs.DeleteMessageInput{
-					QueueUrl:      &c.QueueURL,
-					ReceiptHandle: m.ReceiptHandle,
-				})
-			} else {
-				// If err != nil: do NOT delete => SQS redrive/DLQ handles it
-				slog.Error("sqs handler error", "err", err)
-			}
-
+			c.handle(ctx, m, handler)
 		}
 	}
 }
@@ -90,31 +63,7 @@ func (c *Consumer) PollConcurrent(ctx context.Context, workers int, handler Hand
 		go func() {
 			defer wg.Done()
 			for m := range jobs {
-				// Always handle poison / invalid messages so they don't loop forever
-				if m.Body == nil {
-					_, _ = c.SQS.DeleteMessage(ctx, &sqs.DeleteMessageInput{
-						QueueUrl:      &c.QueueURL,
-						ReceiptHandle: m.ReceiptHandle,
-					})
-					continue
-				}
-
-				var job SMSJob
-				if err := json.Unmarshal([]byte(*m.Body), &job); err != nil {
-					_, _ = c.SQS.DeleteMessage(ctx, &sqs.DeleteMessageInput{
-						QueueUrl:      &c.QueueURL,
-						ReceiptHandle: m.ReceiptHandle,
-					})
-					continue
-				}
-
-				if err := handler(ctx, job); err == nil {
-					_, _ = c.SQS.DeleteMessage(ctx, &sqs.DeleteMessageInput{
-						QueueUrl:      &c.QueueURL,
-						ReceiptHandle: m.ReceiptHandle,
-					})
-				}
-				// If err != nil: do NOT delete => SQS redrive/DLQ handles it
+				c.handle(ctx, m, handler)
 			}
 		}()
 	}
@@ -129,12 +78,7 @@ func (c *Consumer) PollConcurrent(ctx context.Context, workers int, handler Hand
 				return
 			}
 
-			out, err := c.SQS.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
-				QueueUrl:            &c.QueueURL,
-				MaxNumberOfMessages: c.MaxMessages,
-				WaitTimeSeconds:     c.WaitTimeSeconds,
-				VisibilityTimeout:   c.VisibilityTimeout,
-			})
+			out, err := c.SQS.ReceiveMessage(ctx, c.receiveInput())
 			if err != nil {
 				slog.Error("sqs receive message failed", "err", err)
 				time.Sleep(500 * time.Millisecond)
@@ -159,3 +103,51 @@ func (c *Consumer) PollConcurrent(ctx context.Context, workers int, handler Hand
 	wg.Wait()
 	return err
 }
+
+// handle runs handler for one received message and deletes it on success. A RetryLater error
+// hides the message for its delay instead.
+func (c *Consumer) handle(ctx context.Context, m types.Message, handler Handler) {
+	// Always handle poison / invalid messages so they don't loop forever
+	if m.Body == nil {
+		_, _ = c.SQS.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+			QueueUrl:      &c.QueueURL,
+			ReceiptHandle: m.ReceiptHandle,
+		})
+		return
+	}
+
+	var job SMSJob
+	if err := json.Unmarshal([]byte(*m.Body), &job); err != nil {
+		_, _ = c.SQS.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+			QueueUrl:      &c.QueueURL,
+			ReceiptHandle: m.ReceiptHandle,
+		})
+		return
+	}
+
+	job.ReceiveCount = receiveCount(m)
+
+	err := handler(ctx, job)
+	if err == nil {
+		_, _ = c.SQS.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+			QueueUrl:      &c.QueueURL,
+			ReceiptHandle: m.ReceiptHandle,
+		})
+		return
+	}
+	var rl *RetryLater
+	if errors.As(err, &rl) {
+		c.retryLater(ctx, m, rl)
+	}
+	// Otherwise do NOT delete => SQS redrive/DLQ handles it
+}
+
+func (c *Consumer) receiveInput() *sqs.ReceiveMessageInput {
+	return &sqs.ReceiveMessageInput{
+		QueueUrl:                    &c.QueueURL,
+		MaxNumberOfMessages:         c.MaxMessages,
+		WaitTimeSeconds:             c.WaitTimeSeconds,
+		VisibilityTimeout:           c.VisibilityTimeout,
+		MessageSystemAttributeNames: []types.MessageSystemAttributeName{types.MessageSystemAttributeNameApproximateReceiveCount},
+	}
+}
@@ -0,0 +1,190 @@
+package sqsqueue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// Lane is one queue polled by a LaneConsumer.
+type Lane struct {
+	QueueURL string `json:"queueUrl"`
+	// Weight is the lane's share of the workers while several lanes have messages waiting.
+	// A lane with nothing waiting leaves its share to the others.
+	Weight int `json:"weight"`
+}
+
+// ParseLanes parses a JSON array of lanes, e.g.
+// [{"queueUrl":".../sms-high","weight":8},{"queueUrl":".../sms-low","weight":1}].
+// An empty string yields no lanes.
+func ParseLanes(s string) ([]Lane, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var lanes []Lane
+	if err := json.Unmarshal([]byte(s), &lanes); err != nil {
+		return nil, fmt.Errorf("invalid lanes: %w", err)
+	}
+	for i, l := range lanes {
+		if l.QueueURL == "" {
+			return nil, fmt.Errorf("lane %d: missing queueUrl", i)
+		}
+		if l.Weight <= 0 {
+			return nil, fmt.Errorf("lane %d: weight must be positive", i)
+		}
+	}
+	return lanes, nil
+}
+
+// LaneConsumer polls several queues and shares one worker pool between them. Whenever a worker
+// frees up it takes a message from the lane furthest behind its weighted share, among the lanes
+// with messages waiting, so a high-weight lane is served first without starving the others.
+type LaneConsumer struct {
+	SQS   *sqs.Client
+	Lanes []Lane
+
+	WaitTimeSeconds   int32
+	MaxMessages       int32
+	VisibilityTimeout int32
+}
+
+// PollConcurrent processes messages from all lanes with up to workers handlers at a time.
+// Messages are deleted only after handler completes.
+func (c *LaneConsumer) PollConcurrent(ctx context.Context, workers int, handler Handler) error {
+	if workers <= 0 {
+		workers = 1
+	}
+	consumers := make([]*Consumer, len(c.Lanes))
+	buffers := make([]chan types.Message, len(c.Lanes))
+	weights := make([]int, len(c.Lanes))
+	for i, l := range c.Lanes {
+		consumers[i] = &Consumer{
+			SQS:               c.SQS,
+			QueueURL:          l.QueueURL,
+			WaitTimeSeconds:   c.WaitTimeSeconds,
+			MaxMessages:       c.MaxMessages,
+			VisibilityTimeout: c.VisibilityTimeout,
+		}
+		// Keep lane buffers small: buffered messages are invisible on SQS but nobody works on them.
+		buffers[i] = make(chan types.Message, max(c.MaxMessages, 1))
+		weights[i] = l.Weight
+	}
+	ready := make(chan struct{}, 1)
+
+	var receivers sync.WaitGroup
+	for i := range consumers {
+		receivers.Add(1)
+		go func(i int) {
+			defer receivers.Done()
+			consumers[i].receive(ctx, buffers[i], ready)
+		}(i)
+	}
+
+	sched := newWRR(weights)
+	sem := make(chan struct{}, workers)
+	var inflight sync.WaitGroup
+	for {
+		// Wait for a free worker before picking a lane, so the pick reflects what is waiting now.
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		lane, m, ok := nextMessage(ctx, sched, buffers, ready)
+		if !ok {
+			break
+		}
+		inflight.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				inflight.Done()
+			}()
+			consumers[lane].handle(ctx, m, handler)
+		}()
+	}
+
+	inflight.Wait()
+	receivers.Wait()
+	return ctx.Err()
+}
+
+// nextMessage blocks until some lane has a message and takes one from the lane sched picks.
+func nextMessage(ctx context.Context, sched *wrr, buffers []chan types.Message, ready <-chan struct{}) (int, types.Message, bool) {
+	for {
+		lane := sched.next(func(i int) bool { return len(buffers[i]) > 0 })
+		if lane >= 0 {
+			// Only the dispatcher reads the buffers, so this can't block.
+			return lane, <-buffers[lane], true
+		}
+		select {
+		case <-ready:
+		case <-ctx.Done():
+			return 0, types.Message{}, false
+		}
+	}
+}
+
+// receive long-polls the queue into out until ctx is canceled, signalling ready after each message.
+func (c *Consumer) receive(ctx context.Context, out chan<- types.Message, ready chan<- struct{}) {
+	for ctx.Err() == nil {
+		res, err := c.SQS.ReceiveMessage(ctx, c.receiveInput())
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.Error("sqs receive message failed", "err", err, "queue_url", c.QueueURL)
+				time.Sleep(500 * time.Millisecond)
+			}
+			continue
+		}
+		for _, m := range res.Messages {
+			select {
+			case out <- m:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case ready <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// wrr is smooth weighted round robin (as in nginx) over the lanes eligible at each pick. Lanes
+// that are not eligible don't build up credit while idle.
+type wrr struct {
+	weights []int
+	current []int
+}
+
+func newWRR(weights []int) *wrr {
+	return &wrr{weights: weights, current: make([]int, len(weights))}
+}
+
+// next returns the lane to serve, or -1 if no lane is eligible.
+func (w *wrr) next(eligible func(int) bool) int {
+	best, total := -1, 0
+	for i, wt := range w.weights {
+		if !eligible(i) {
+			continue
+		}
+		w.current[i] += wt
+		total += wt
+		if best < 0 || w.current[i] > w.current[best] {
+			best = i
+		}
+	}
+	if best >= 0 {
+		w.current[best] -= total
+	}
+	return best
+}
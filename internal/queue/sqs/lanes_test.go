@@ -0,0 +1,55 @@
+package sqsqueue
+
+import "testing"
+
+func TestWRR(t *testing.T) {
+	all := func(int) bool { return true }
+
+	w := newWRR([]int{3, 1})
+	counts := make([]int, 2)
+	var first int
+	for i := 0; i < 8; i++ {
+		lane := w.next(all)
+		if i == 0 {
+			first = lane
+		}
+		counts[lane]++
+	}
+	if first != 0 || counts[0] != 6 || counts[1] != 2 {
+		t.Fatalf("expected lane 0 first and a 6/2 split, got first=%d counts=%v", first, counts)
+	}
+
+	// An idle high lane leaves all capacity to the low lane, and doesn't bank credit meanwhile.
+	w = newWRR([]int{3, 1})
+	for i := 0; i < 5; i++ {
+		if lane := w.next(func(i int) bool { return i == 1 }); lane != 1 {
+			t.Fatalf("expected lane 1 while lane 0 is idle, got %d", lane)
+		}
+	}
+	counts = make([]int, 2)
+	for i := 0; i < 4; i++ {
+		counts[w.next(all)]++
+	}
+	if counts[0] != 3 || counts[1] != 1 {
+		t.Fatalf("expected 3/1 split after idling, got %v", counts)
+	}
+
+	if lane := w.next(func(int) bool { return false }); lane != -1 {
+		t.Fatalf("expected -1 with nothing eligible, got %d", lane)
+	}
+}
+
+func TestParseLanes(t *testing.T) {
+	lanes, err := ParseLanes(`[{"queueUrl":"q-high","weight":8},{"queueUrl":"q-low","weight":1}]`)
+	if err != nil || len(lanes) != 2 || lanes[0].QueueURL != "q-high" || lanes[1].Weight != 1 {
+		t.Fatalf("unexpected lanes %+v %v", lanes, err)
+	}
+	if lanes, err := ParseLanes(" "); err != nil || lanes != nil {
+		t.Fatalf("expected no lanes for empty config, got %+v %v", lanes, err)
+	}
+	for _, bad := range []string{`[{"queueUrl":"q","weight":0}]`, `[{"weight":1}]`, `{`} {
+		if _, err := ParseLanes(bad); err == nil {
+			t.Fatalf("expected error for %s", bad)
+		}
+	}
+}
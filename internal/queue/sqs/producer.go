@@ -5,13 +5,21 @@ import (
 	"encoding/json"
 	"fmt"
 	"hash/fnv"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
 )
 
 type Producer struct {
 	SQS      *sqs.Client
 	QueueURL string
+	// QueueURLs maps a job's Priority to its own queue (lane). Jobs whose priority isn't listed
+	// go to QueueURL.
+	QueueURLs map[string]string
 
 	// For FIFO: use bounded, bucketed MessageGroupIds to allow parallelism without exploding cardinality.
 	// Example: tenantA:b1234, where bucket = hash(to) % GroupBuckets.
@@ -27,6 +35,14 @@ type SMSJob struct {
 	TemplateID     string            `json:"templateId"`
 	Vars           map[string]string `json:"vars"`
 	CampaignID     string            `json:"campaignId,omitempty"`
+	// SendAt is set for scheduled messages; see Producer.SupportsDelay.
+	SendAt   *time.Time `json:"sendAt,omitempty"`
+	Priority string     `json:"priority,omitempty"`
+	// ReceiveCount is set by the consumer from ApproximateReceiveCount; it is not sent.
+	ReceiveCount int `json:"-"`
+	// EarlierReceives counts the receives of earlier SQS messages for this job when a retry was
+	// republished through the outbox.
+	EarlierReceives int `json:"earlierReceives,omitempty"`
 }
 
 func (p *Producer) EnqueueSMS(ctx context.Context, tenantID, messageID, idempotencyKey, to, templateID string, vars map[string]string, campaignID string) error {
@@ -49,6 +65,116 @@ func (p *Producer) EnqueueSMS(ctx context.Context, tenantID, messageID, idempote
 	return err
 }
 
+// sendBatchMax is the SQS limit on entries per SendMessageBatch call.
+const sendBatchMax = 10
+
+// EnqueueSMSBatch sends jobs with SendMessageBatch, grouped by queue in chunks of 10.
+// The returned slice is aligned with jobs; a nil entry means that job was enqueued.
+func (p *Producer) EnqueueSMSBatch(ctx context.Context, jobs []SMSJob) []error {
+	errs := make([]error, len(jobs))
+	var queues []string
+	byQueue := make(map[string][]int)
+	for i := range jobs {
+		q := p.queueURL(jobs[i].Priority)
+		if _, ok := byQueue[q]; !ok {
+			queues = append(queues, q)
+		}
+		byQueue[q] = append(byQueue[q], i)
+	}
+	for _, q := range queues {
+		idx := byQueue[q]
+		for start := 0; start < len(idx); start += sendBatchMax {
+			p.sendBatch(ctx, q, jobs, idx[start:min(start+sendBatchMax, len(idx))], errs)
+		}
+	}
+	return errs
+}
+
+// sendBatch sends jobs[idx] (at most 10) to queueURL, recording failures in errs.
+func (p *Producer) sendBatch(ctx context.Context, queueURL string, jobs []SMSJob, idx []int, errs []error) {
+	entries := make([]types.SendMessageBatchRequestEntry, 0, len(idx))
+	for _, i := range idx {
+		body, err := json.Marshal(jobs[i])
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		entry := types.SendMessageBatchRequestEntry{
+			// Entry IDs only need to be unique within the call; use the job index.
+			Id:          str(strconv.Itoa(i)),
+			MessageBody: str(string(body)),
+		}
+		if IsFIFO(queueURL) {
+			entry.MessageGroupId = str(messageGroupIDBucketed(jobs[i].TenantID, jobs[i].To, p.GroupBuckets))
+			entry.MessageDeduplicationId = str(jobs[i].IdempotencyKey)
+		} else {
+			entry.DelaySeconds = delaySeconds(jobs[i].SendAt, time.Now())
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		return
+	}
+
+	out, err := p.SQS.SendMessageBatch(ctx, &sqs.SendMessageBatchInput{
+		QueueUrl: &queueURL,
+		Entries:  entries,
+	})
+	if err != nil {
+		for _, e := range entries {
+			i, _ := strconv.Atoi(*e.Id)
+			errs[i] = err
+		}
+		return
+	}
+	for _, f := range out.Failed {
+		i, convErr := strconv.Atoi(aws.ToString(f.Id))
+		if convErr != nil || i < 0 || i >= len(jobs) {
+			continue
+		}
+		errs[i] = fmt.Errorf("sqs batch entry failed: %s: %s", aws.ToString(f.Code), aws.ToString(f.Message))
+	}
+}
+
+// queueURL returns the queue for a job's priority.
+func (p *Producer) queueURL(priority string) string {
+	if q := p.QueueURLs[priority]; q != "" {
+		return q
+	}
+	return p.QueueURL
+}
+
+// MaxDelay is the longest per-message delay SQS accepts.
+const MaxDelay = 15 * time.Minute
+
+// SupportsDelay reports whether jobs can be held back with per-message DelaySeconds. FIFO queues
+// only support a queue-wide delay, so scheduled jobs for them must be published when due.
+func (p *Producer) SupportsDelay() bool {
+	if IsFIFO(p.QueueURL) {
+		return false
+	}
+	for _, q := range p.QueueURLs {
+		if IsFIFO(q) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsFIFO reports whether queueURL names a FIFO queue.
+func IsFIFO(queueURL string) bool {
+	return strings.HasSuffix(queueURL, ".fifo")
+}
+
+// delaySeconds is how long SQS should hide a job scheduled for sendAt, capped at MaxDelay.
+func delaySeconds(sendAt *time.Time, now time.Time) int32 {
+	if sendAt == nil || !sendAt.After(now) {
+		return 0
+	}
+	d := min(sendAt.Sub(now), MaxDelay)
+	return int32((d + time.Second - 1) / time.Second)
+}
+
 func str(s string) *string { return &s }
 
 func messageGroupIDBucketed(tenantID, to string, buckets int) string {
@@ -1,6 +1,9 @@
 package sqsqueue
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestMessageGroupIDBucketed(t *testing.T) {
 	tenant := "t1"
@@ -21,3 +24,30 @@ func TestMessageGroupIDBucketed(t *testing.T) {
 		t.Fatalf("expected non-empty group id for default buckets")
 	}
 }
+
+func TestDelaySeconds(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time { t := now.Add(d); return &t }
+	cases := []struct {
+		sendAt *time.Time
+		want   int32
+	}{
+		{nil, 0},
+		{at(-time.Minute), 0},
+		{at(1500 * time.Millisecond), 2},
+		{at(5 * time.Minute), 300},
+		{at(time.Hour), 900},
+	}
+	for _, tc := range cases {
+		if got := delaySeconds(tc.sendAt, now); got != tc.want {
+			t.Fatalf("sendAt %v: got %d want %d", tc.sendAt, got, tc.want)
+		}
+	}
+
+	if (&Producer{QueueURL: "https://sqs/123/sms.fifo"}).SupportsDelay() {
+		t.Fatalf("fifo queues must not use per-message delays")
+	}
+	if !(&Producer{QueueURL: "https://sqs/123/sms"}).SupportsDelay() {
+		t.Fatalf("standard queues support per-message delays")
+	}
+}
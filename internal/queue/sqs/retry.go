@@ -0,0 +1,62 @@
+package sqsqueue
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// MaxVisibilityTimeout is the longest SQS can hide a received message.
+const MaxVisibilityTimeout = 12 * time.Hour
+
+// RetryLater is returned by a Handler to have the message received again after Delay instead of
+// after the queue's visibility timeout. The receive count, and so the attempt number, carries
+// over in SMSJob.ReceiveCount.
+//
+// The message stays in flight while hidden. On a FIFO queue that holds up every later message
+// in its group and counts towards the queue's in-flight limit, so handlers should keep Delay to
+// a few seconds there and hold longer retries elsewhere (the worker republishes them through
+// the outbox). That costs a database round trip and a new SQS message per retry.
+type RetryLater struct {
+	Delay time.Duration
+	Err   error
+}
+
+func (e *RetryLater) Error() string {
+	return "retry in " + e.Delay.String() + ": " + e.Err.Error()
+}
+
+func (e *RetryLater) Unwrap() error { return e.Err }
+
+// receiveCount reads ApproximateReceiveCount (1 on the first delivery).
+func receiveCount(m types.Message) int {
+	n, err := strconv.Atoi(m.Attributes[string(types.MessageSystemAttributeNameApproximateReceiveCount)])
+	if err != nil || n < 1 {
+		return 1
+	}
+	return n
+}
+
+// visibilitySeconds rounds d up to whole seconds within what SQS accepts.
+func visibilitySeconds(d time.Duration) int32 {
+	d = min(max(d, 0), MaxVisibilityTimeout)
+	return int32(math.Ceil(d.Seconds()))
+}
+
+// retryLater makes the message visible again after rl.Delay.
+func (c *Consumer) retryLater(ctx context.Context, m types.Message, rl *RetryLater) {
+	_, err := c.SQS.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          &c.QueueURL,
+		ReceiptHandle:     m.ReceiptHandle,
+		VisibilityTimeout: visibilitySeconds(rl.Delay),
+	})
+	if err != nil {
+		// The message still comes back once the current visibility timeout runs out.
+		slog.Error("sqs change message visibility failed", "err", err, "queue_url", c.QueueURL)
+	}
+}
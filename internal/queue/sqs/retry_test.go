@@ -0,0 +1,31 @@
+package sqsqueue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+func TestReceiveCount(t *testing.T) {
+	m := types.Message{Attributes: map[string]string{"ApproximateReceiveCount": "3"}}
+	if n := receiveCount(m); n != 3 {
+		t.Fatalf("expected 3, got %d", n)
+	}
+	if n := receiveCount(types.Message{}); n != 1 {
+		t.Fatalf("expected 1 without the attribute, got %d", n)
+	}
+}
+
+func TestVisibilitySeconds(t *testing.T) {
+	for d, want := range map[time.Duration]int32{
+		-time.Second:            0,
+		1500 * time.Millisecond: 2,
+		time.Minute:             60,
+		24 * time.Hour:          43200,
+	} {
+		if got := visibilitySeconds(d); got != want {
+			t.Fatalf("visibilitySeconds(%s) = %d, want %d", d, got, want)
+		}
+	}
+}
@@ -18,6 +18,7 @@ type WebhookEvent struct {
 	ProviderMsgID string              `json:"providerMsgId"`
 	Status        string              `json:"status"`
 	ErrorCode     string              `json:"errorCode,omitempty"`
+	FailureReason string              `json:"failureReason,omitempty"`
 	Payload       map[string][]string `json:"payload,omitempty"`
 	ReceivedAt    time.Time           `json:"receivedAt"`
 }
@@ -0,0 +1,132 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"notif/internal/observability"
+)
+
+// cutCooldown keeps a burst of 429s from requests already in flight from cutting the rate more
+// than once.
+const cutCooldown = time.Second
+
+// Adaptive paces provider calls within one pod and follows provider feedback (AIMD): each
+// throttling response multiplies the rate by Decrease, and successes add it back at about
+// Increase messages/second per second. A Retry-After also pauses all sends until it passes.
+type Adaptive struct {
+	// Name labels the rate gauge, normally the provider name.
+	Name string
+	// Min and Max bound the rate in messages/second; the limiter starts at Max. Min defaults to
+	// Max/10.
+	Min, Max float64
+	// Increase is how fast the rate recovers while sends succeed (default Max/20 per second).
+	Increase float64
+	// Decrease is the factor applied on throttling (default 0.5).
+	Decrease float64
+
+	limiter *rate.Limiter
+	now     func() time.Time
+
+	mu          sync.Mutex
+	rate        float64
+	lastCut     time.Time
+	pausedUntil time.Time
+}
+
+func NewAdaptive(name string, minRate, maxRate float64, burst int) *Adaptive {
+	a := &Adaptive{
+		Name:    name,
+		Min:     minRate,
+		Max:     maxRate,
+		limiter: rate.NewLimiter(rate.Limit(maxRate), burst),
+		now:     time.Now,
+		rate:    maxRate,
+	}
+	observability.ProviderSendRate.WithLabelValues(name).Set(maxRate)
+	return a
+}
+
+// Wait blocks until a send may go out: after any Retry-After pause, then at the current rate.
+func (a *Adaptive) Wait(ctx context.Context) error {
+	a.mu.Lock()
+	pause := a.pausedUntil.Sub(a.now())
+	a.mu.Unlock()
+	if pause > 0 {
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < pause {
+			return context.DeadlineExceeded
+		}
+		t := time.NewTimer(pause)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+	}
+	return a.limiter.Wait(ctx)
+}
+
+// Throttled records a 429 (or similar) response.
+func (a *Adaptive) Throttled(retryAfter time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := a.now()
+	if retryAfter > 0 && now.Add(retryAfter).After(a.pausedUntil) {
+		a.pausedUntil = now.Add(retryAfter)
+	}
+	if now.Sub(a.lastCut) < cutCooldown {
+		return
+	}
+	a.lastCut = now
+	a.setRate(a.rate * a.decrease())
+}
+
+// Succeeded records an accepted send.
+func (a *Adaptive) Succeeded() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.rate >= a.Max {
+		return
+	}
+	// Adding Increase/rate per send grows the rate by about Increase every second.
+	a.setRate(a.rate + a.increase()/a.rate)
+}
+
+// Rate returns the current rate in messages/second.
+func (a *Adaptive) Rate() float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.rate
+}
+
+func (a *Adaptive) setRate(r float64) {
+	r = min(max(r, a.minRate()), a.Max)
+	a.rate = r
+	a.limiter.SetLimit(rate.Limit(r))
+	observability.ProviderSendRate.WithLabelValues(a.Name).Set(r)
+}
+
+func (a *Adaptive) minRate() float64 {
+	if a.Min <= 0 {
+		return a.Max / 10
+	}
+	return a.Min
+}
+
+func (a *Adaptive) increase() float64 {
+	if a.Increase <= 0 {
+		return a.Max / 20
+	}
+	return a.Increase
+}
+
+func (a *Adaptive) decrease() float64 {
+	if a.Decrease <= 0 || a.Decrease >= 1 {
+		return 0.5
+	}
+	return a.Decrease
+}
@@ -0,0 +1,56 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestAdaptive(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	a := NewAdaptive("test", 1, 8, 1)
+	a.Increase = 1
+	a.now = func() time.Time { return now }
+
+	a.Throttled(0)
+	if a.Rate() != 4 {
+		t.Fatalf("expected rate halved to 4, got %v", a.Rate())
+	}
+	// 429s from requests already in flight don't compound within the cooldown.
+	a.Throttled(0)
+	if a.Rate() != 4 {
+		t.Fatalf("expected a single cut within the cooldown, got %v", a.Rate())
+	}
+	for range 3 {
+		now = now.Add(cutCooldown)
+		a.Throttled(0)
+	}
+	if a.Rate() != 1 {
+		t.Fatalf("expected the rate floored at Min, got %v", a.Rate())
+	}
+
+	// Sending at rate r for a second (r successes) adds about Increase.
+	a.Succeeded()
+	if math.Abs(a.Rate()-2) > 1e-9 {
+		t.Fatalf("expected additive recovery to 2, got %v", a.Rate())
+	}
+	for range 100 {
+		a.Succeeded()
+	}
+	if a.Rate() != 8 {
+		t.Fatalf("expected recovery capped at Max, got %v", a.Rate())
+	}
+
+	// Retry-After pauses sends; a caller that can't wait that long gives up right away.
+	a.Throttled(time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := a.Wait(ctx); err == nil {
+		t.Fatalf("expected Wait to fail during a Retry-After pause")
+	}
+	now = now.Add(time.Minute)
+	if err := a.Wait(context.Background()); err != nil {
+		t.Fatalf("wait after pause: %v", err)
+	}
+}
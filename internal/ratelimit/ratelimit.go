@@ -0,0 +1,166 @@
+// Package ratelimit enforces send rates shared by every worker pod.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"sync"
+	"time"
+
+	"notif/internal/observability"
+	"notif/internal/store"
+)
+
+// ErrLimited means the wait for a token would exceed Limiter.MaxWait. Nothing was reserved.
+var ErrLimited = errors.New("rate limited")
+
+// Limit is a token bucket refilled at PerSecond up to Burst. A zero PerSecond means unlimited.
+type Limit struct {
+	PerSecond float64
+	// Burst defaults to one second's worth of tokens.
+	Burst int
+}
+
+func (l Limit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return max(1, int(math.Ceil(l.PerSecond)))
+}
+
+type Store interface {
+	ReserveToken(ctx context.Context, key string, perSecond float64, burst int, maxWait time.Duration) (time.Duration, bool, error)
+	RefundToken(ctx context.Context, key string, burst int) error
+	GetTenantRateLimit(ctx context.Context, tenantID string) (store.TenantRateLimit, bool, error)
+}
+
+// Limiter enforces token buckets kept in Postgres: one per provider (the account's
+// messages-per-second ceiling) and one per tenant. Each send reserves a token and sleeps until
+// its slot, so the combined rate stays the same however many pods are running. A fast bucket is
+// split into shards, each a row with its share of the rate, so sends don't all queue on one row lock.
+type Limiter struct {
+	Store Store
+	// Providers holds the global limit per provider name; providers not listed are unlimited.
+	Providers map[string]Limit
+	// TenantDefault applies to tenants without their own limit.
+	TenantDefault Limit
+	// MaxWait is the longest a send may wait for a token before ErrLimited (default 5s).
+	MaxWait time.Duration
+	// TenantTTL is how long tenant limits are cached (default 30s).
+	TenantTTL time.Duration
+	// Shards caps how many rows a bucket is split into (default 1). A bucket gets at most one
+	// shard per message/second of its rate, so slow buckets stay whole.
+	Shards int
+
+	mu      sync.Mutex
+	tenants map[string]cachedLimit
+}
+
+type cachedLimit struct {
+	limit   Limit
+	expires time.Time
+}
+
+// Token is a reserved token, to hand back with Refund if the message wasn't sent after all.
+// The zero Token (unlimited bucket) refunds nothing.
+type Token struct {
+	key   string
+	burst int
+}
+
+// WaitTenant blocks until the tenant may send another message.
+func (l *Limiter) WaitTenant(ctx context.Context, tenantID string) (Token, error) {
+	lim, err := l.tenantLimit(ctx, tenantID)
+	if err != nil {
+		return Token{}, err
+	}
+	return l.wait(ctx, "tenant", "tenant:"+tenantID, lim)
+}
+
+// WaitProvider blocks until the provider's account may take another message.
+func (l *Limiter) WaitProvider(ctx context.Context, provider string) (Token, error) {
+	return l.wait(ctx, "provider", "provider:"+provider, l.Providers[provider])
+}
+
+// Refund returns an unused token to its bucket.
+func (l *Limiter) Refund(ctx context.Context, tok Token) error {
+	if tok.key == "" {
+		return nil
+	}
+	return l.Store.RefundToken(ctx, tok.key, tok.burst)
+}
+
+func (l *Limiter) wait(ctx context.Context, scope, key string, lim Limit) (Token, error) {
+	if lim.PerSecond <= 0 {
+		return Token{}, nil
+	}
+	perSecond, burst := lim.PerSecond, lim.burst()
+	if n := min(l.Shards, int(perSecond)); n > 1 {
+		key += "#" + strconv.Itoa(rand.IntN(n))
+		perSecond, burst = perSecond/float64(n), max(1, burst/n)
+	}
+	d, ok, err := l.Store.ReserveToken(ctx, key, perSecond, burst, l.maxWait())
+	if err != nil {
+		return Token{}, err
+	}
+	if !ok {
+		observability.RateLimited.WithLabelValues(scope).Inc()
+		return Token{}, ErrLimited
+	}
+	tok := Token{key: key, burst: burst}
+	if d <= 0 {
+		return tok, nil
+	}
+	observability.RateLimitWaitSeconds.WithLabelValues(scope).Observe(d.Seconds())
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return tok, nil
+	case <-ctx.Done():
+		return tok, ctx.Err()
+	}
+}
+
+func (l *Limiter) tenantLimit(ctx context.Context, tenantID string) (Limit, error) {
+	now := time.Now()
+	l.mu.Lock()
+	c, ok := l.tenants[tenantID]
+	l.mu.Unlock()
+	if ok && now.Before(c.expires) {
+		return c.limit, nil
+	}
+
+	row, found, err := l.Store.GetTenantRateLimit(ctx, tenantID)
+	if err != nil {
+		return Limit{}, err
+	}
+	lim := l.TenantDefault
+	if found {
+		lim = Limit{PerSecond: row.PerSecond, Burst: row.Burst}
+	}
+	l.mu.Lock()
+	if l.tenants == nil {
+		l.tenants = make(map[string]cachedLimit)
+	}
+	l.tenants[tenantID] = cachedLimit{limit: lim, expires: now.Add(l.tenantTTL())}
+	l.mu.Unlock()
+	return lim, nil
+}
+
+func (l *Limiter) maxWait() time.Duration {
+	if l.MaxWait <= 0 {
+		return 5 * time.Second
+	}
+	return l.MaxWait
+}
+
+func (l *Limiter) tenantTTL() time.Duration {
+	if l.TenantTTL <= 0 {
+		return 30 * time.Second
+	}
+	return l.TenantTTL
+}
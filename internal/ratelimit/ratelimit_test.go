@@ -0,0 +1,134 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"notif/internal/store"
+)
+
+type reservation struct {
+	key       string
+	perSecond float64
+	burst     int
+}
+
+type fakeStore struct {
+	limits   map[string]store.TenantRateLimit
+	lookups  int
+	reserved []reservation
+	wait     time.Duration
+	refuse   bool
+	refunded []string
+}
+
+func (f *fakeStore) ReserveToken(ctx context.Context, key string, perSecond float64, burst int, maxWait time.Duration) (time.Duration, bool, error) {
+	if f.refuse {
+		return 0, false, nil
+	}
+	f.reserved = append(f.reserved, reservation{key, perSecond, burst})
+	return f.wait, true, nil
+}
+
+func (f *fakeStore) RefundToken(ctx context.Context, key string, burst int) error {
+	f.refunded = append(f.refunded, key)
+	return nil
+}
+
+func (f *fakeStore) GetTenantRateLimit(ctx context.Context, tenantID string) (store.TenantRateLimit, bool, error) {
+	f.lookups++
+	l, ok := f.limits[tenantID]
+	return l, ok, nil
+}
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+	fs := &fakeStore{limits: map[string]store.TenantRateLimit{"big": {TenantID: "big", PerSecond: 50, Burst: 100}}}
+	l := &Limiter{
+		Store:         fs,
+		Providers:     map[string]Limit{"twilio": {PerSecond: 30}},
+		TenantDefault: Limit{PerSecond: 2.5},
+	}
+
+	for _, tenant := range []string{"big", "small", "big"} {
+		if _, err := l.WaitTenant(ctx, tenant); err != nil {
+			t.Fatalf("wait %s: %v", tenant, err)
+		}
+	}
+	if _, err := l.WaitProvider(ctx, "twilio"); err != nil {
+		t.Fatalf("wait provider: %v", err)
+	}
+	if _, err := l.WaitProvider(ctx, "unlimited"); err != nil {
+		t.Fatalf("wait unlimited provider: %v", err)
+	}
+
+	want := []reservation{
+		{"tenant:big", 50, 100},
+		{"tenant:small", 2.5, 3},
+		{"tenant:big", 50, 100},
+		{"provider:twilio", 30, 30},
+	}
+	if len(fs.reserved) != len(want) {
+		t.Fatalf("expected %d reservations, got %+v", len(want), fs.reserved)
+	}
+	for i := range want {
+		if fs.reserved[i] != want[i] {
+			t.Fatalf("reservation %d: got %+v want %+v", i, fs.reserved[i], want[i])
+		}
+	}
+	if fs.lookups != 2 {
+		t.Fatalf("expected tenant limits to be cached, got %d lookups", fs.lookups)
+	}
+
+	fs.refuse = true
+	if _, err := l.WaitProvider(ctx, "twilio"); !errors.Is(err, ErrLimited) {
+		t.Fatalf("expected ErrLimited, got %v", err)
+	}
+
+	fs.refuse, fs.wait = false, time.Hour
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := l.WaitProvider(cctx, "twilio"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the wait to stop on cancel, got %v", err)
+	}
+}
+
+func TestLimiterShardsAndRefund(t *testing.T) {
+	ctx := context.Background()
+	fs := &fakeStore{}
+	l := &Limiter{
+		Store:         fs,
+		Providers:     map[string]Limit{"twilio": {PerSecond: 100, Burst: 40}},
+		TenantDefault: Limit{PerSecond: 2.5},
+		Shards:        4,
+	}
+
+	tok, err := l.WaitProvider(ctx, "twilio")
+	if err != nil {
+		t.Fatalf("wait provider: %v", err)
+	}
+	r := fs.reserved[0]
+	if !strings.HasPrefix(r.key, "provider:twilio#") || r.perSecond != 25 || r.burst != 10 {
+		t.Fatalf("expected a quarter of the bucket on one shard, got %+v", r)
+	}
+	if err := l.Refund(ctx, tok); err != nil || len(fs.refunded) != 1 || fs.refunded[0] != r.key {
+		t.Fatalf("expected the token refunded to its shard, got %v %v", fs.refunded, err)
+	}
+
+	// A bucket gets no more shards than messages per second: 2.5/s splits in two.
+	if _, err := l.WaitTenant(ctx, "small"); err != nil {
+		t.Fatalf("wait tenant: %v", err)
+	}
+	if r := fs.reserved[1]; !strings.HasPrefix(r.key, "tenant:small#") || r.perSecond != 1.25 || r.burst != 1 {
+		t.Fatalf("unexpected tenant shard %+v", r)
+	}
+
+	// Unlimited buckets reserve nothing, so there is nothing to refund.
+	tok, _ = l.WaitProvider(ctx, "unlimited")
+	if err := l.Refund(ctx, tok); err != nil || len(fs.refunded) != 1 {
+		t.Fatalf("expected no refund for an unlimited bucket, got %v %v", fs.refunded, err)
+	}
+}
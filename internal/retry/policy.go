@@ -0,0 +1,197 @@
+// Package retry decides whether, when and for how long the worker retries a failed send.
+package retry
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"strings"
+	"time"
+
+	"notif/internal/providers"
+)
+
+// Policy is a declarative retry policy. In rules, zero fields inherit from the default policy.
+type Policy struct {
+	// MaxAttempts counts sends of a message, the first one included.
+	MaxAttempts int `json:"maxAttempts,omitempty"`
+	// MaxAgeSeconds stops retrying once the message is this old (0 = no limit).
+	MaxAgeSeconds int `json:"maxAgeSeconds,omitempty"`
+	// The delay before attempt n+1 is BaseDelaySeconds * Multiplier^(n-1), capped at
+	// MaxDelaySeconds and jittered over its upper half. A longer Retry-After wins.
+	BaseDelaySeconds float64 `json:"baseDelaySeconds,omitempty"`
+	Multiplier       float64 `json:"multiplier,omitempty"`
+	MaxDelaySeconds  float64 `json:"maxDelaySeconds,omitempty"`
+	// RetryableStatuses, when set, decides which HTTP error statuses are retried instead of the
+	// provider's own classification. Failures without a status (timeouts) are left to the provider.
+	RetryableStatuses []int `json:"retryableStatuses,omitempty"`
+	// RetryableErrorCodes lists provider error codes that are retried whatever the status.
+	RetryableErrorCodes []string `json:"retryableErrorCodes,omitempty"`
+}
+
+// Defaults is used for anything the configured default policy leaves unset.
+var Defaults = Policy{MaxAttempts: 5, BaseDelaySeconds: 2, Multiplier: 2, MaxDelaySeconds: 300}
+
+// With returns p with the fields set in o replacing its own.
+func (p Policy) With(o Policy) Policy {
+	if o.MaxAttempts > 0 {
+		p.MaxAttempts = o.MaxAttempts
+	}
+	if o.MaxAgeSeconds > 0 {
+		p.MaxAgeSeconds = o.MaxAgeSeconds
+	}
+	if o.BaseDelaySeconds > 0 {
+		p.BaseDelaySeconds = o.BaseDelaySeconds
+	}
+	if o.Multiplier > 0 {
+		p.Multiplier = o.Multiplier
+	}
+	if o.MaxDelaySeconds > 0 {
+		p.MaxDelaySeconds = o.MaxDelaySeconds
+	}
+	if o.RetryableStatuses != nil {
+		p.RetryableStatuses = o.RetryableStatuses
+	}
+	if o.RetryableErrorCodes != nil {
+		p.RetryableErrorCodes = o.RetryableErrorCodes
+	}
+	return p
+}
+
+// Retryable reports whether a failed send is worth another attempt, given the provider's own
+// classification of it.
+func (p Policy) Retryable(class providers.ErrorClass, res providers.SendResult) bool {
+	if res.ErrorCode != "" && slices.Contains(p.RetryableErrorCodes, res.ErrorCode) {
+		return true
+	}
+	if res.HTTPStatus != 0 && p.RetryableStatuses != nil {
+		return slices.Contains(p.RetryableStatuses, res.HTTPStatus)
+	}
+	return class == providers.ErrorRetryable
+}
+
+// LastAttempt reports whether attempt (1-based) is the last one allowed.
+func (p Policy) LastAttempt(attempt int) bool {
+	return attempt >= p.MaxAttempts
+}
+
+// Expired reports whether a message of this age is past retrying.
+func (p Policy) Expired(age time.Duration) bool {
+	return p.MaxAgeSeconds > 0 && age >= time.Duration(p.MaxAgeSeconds)*time.Second
+}
+
+// Delay is the wait after failed attempt number attempt (1-based).
+func (p Policy) Delay(attempt int, retryAfter time.Duration) time.Duration {
+	d := p.backoff(attempt)
+	if d > 0 {
+		d = d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+	}
+	return max(d, retryAfter)
+}
+
+// backoff is the delay after attempt before jitter.
+func (p Policy) backoff(attempt int) time.Duration {
+	exp := p.BaseDelaySeconds * math.Pow(max(p.Multiplier, 1), float64(max(attempt, 1)-1))
+	return min(seconds(exp), seconds(p.MaxDelaySeconds))
+}
+
+// minSpan is the least time receives deliveries can cover with this backoff: the jittered lower
+// bound of each delay, within the 12h SQS can defer a message.
+func (p Policy) minSpan(receives int) time.Duration {
+	var span time.Duration
+	for n := 1; n < receives; n++ {
+		span += min(p.backoff(n), 12*time.Hour) / 2
+	}
+	return span
+}
+
+func (p Policy) validate(maxReceives int) error {
+	if p.MaxAttempts > maxReceives {
+		return fmt.Errorf("maxAttempts %d exceeds the queue's %d receives", p.MaxAttempts, maxReceives)
+	}
+	if span := p.minSpan(maxReceives); p.MaxAgeSeconds > 0 && seconds(float64(p.MaxAgeSeconds)) > span {
+		return fmt.Errorf("maxAgeSeconds %d outlasts the queue's %d receives, which may span only %s; raise maxDelaySeconds",
+			p.MaxAgeSeconds, maxReceives, span)
+	}
+	return nil
+}
+
+func seconds(s float64) time.Duration {
+	// Clamp before converting: a large exponent overflows time.Duration.
+	return time.Duration(min(s, 365*24*3600) * float64(time.Second))
+}
+
+// Rule overrides the default policy for a tenant and/or template. Empty fields match anything.
+type Rule struct {
+	TenantID   string `json:"tenantId,omitempty"`
+	TemplateID string `json:"templateId,omitempty"`
+	Policy
+}
+
+func (r Rule) matches(tenantID, templateID string) bool {
+	return (r.TenantID == "" || r.TenantID == tenantID) && (r.TemplateID == "" || r.TemplateID == templateID)
+}
+
+// ParseRules decodes the SMS_RETRY_RULES JSON array, e.g.
+// [{"templateId":"otp","maxAttempts":2,"maxAgeSeconds":120},{"tenantId":"acme","templateId":"invoice","maxAgeSeconds":7200,"maxDelaySeconds":1800}].
+// Empty input means no rules. Policies.Validate checks them against the queue.
+func ParseRules(raw string) ([]Rule, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var rules []Rule
+	if err := json.Unmarshal([]byte(raw), &rules); err != nil {
+		return nil, fmt.Errorf("invalid retry rules: %w", err)
+	}
+	for i, r := range rules {
+		if r.MaxAttempts < 0 || r.MaxAgeSeconds < 0 || r.BaseDelaySeconds < 0 || r.MaxDelaySeconds < 0 {
+			return nil, fmt.Errorf("retry rule %d: negative limit", i)
+		}
+		if r.Multiplier != 0 && r.Multiplier < 1 {
+			return nil, fmt.Errorf("retry rule %d: multiplier must be at least 1", i)
+		}
+	}
+	return rules, nil
+}
+
+// Policies resolves the policy for a message. Rules are evaluated in order and the first
+// match wins.
+type Policies struct {
+	Default Policy
+	Rules   []Rule
+}
+
+// Validate rejects policies a queue that delivers a message at most maxReceives times can't
+// honor: more attempts than receives, or a max age its backoff may not reach before SQS
+// dead-letters the message. maxReceives <= 0 means no limit.
+func (ps *Policies) Validate(maxReceives int) error {
+	if ps == nil || maxReceives <= 0 {
+		return nil
+	}
+	def := Defaults.With(ps.Default)
+	if err := def.validate(maxReceives); err != nil {
+		return fmt.Errorf("default retry policy: %w", err)
+	}
+	for i, r := range ps.Rules {
+		if err := def.With(r.Policy).validate(maxReceives); err != nil {
+			return fmt.Errorf("retry rule %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (ps *Policies) For(tenantID, templateID string) Policy {
+	p := Defaults
+	if ps == nil {
+		return p
+	}
+	p = p.With(ps.Default)
+	for _, r := range ps.Rules {
+		if r.matches(tenantID, templateID) {
+			return p.With(r.Policy)
+		}
+	}
+	return p
+}
@@ -0,0 +1,112 @@
+package retry
+
+import (
+	"testing"
+	"time"
+
+	"notif/internal/providers"
+)
+
+func TestDelay(t *testing.T) {
+	p := Defaults.With(Policy{BaseDelaySeconds: 2, MaxDelaySeconds: 60})
+
+	for _, tc := range []struct {
+		attempt    int
+		retryAfter time.Duration
+		lo, hi     time.Duration
+	}{
+		{attempt: 0, lo: time.Second, hi: 2 * time.Second},
+		{attempt: 1, lo: time.Second, hi: 2 * time.Second},
+		{attempt: 3, lo: 4 * time.Second, hi: 8 * time.Second},
+		{attempt: 10, lo: 30 * time.Second, hi: time.Minute},
+		{attempt: 5000, lo: 30 * time.Second, hi: time.Minute},
+		{attempt: 1, retryAfter: 90 * time.Second, lo: 90 * time.Second, hi: 90 * time.Second},
+	} {
+		for range 50 {
+			if d := p.Delay(tc.attempt, tc.retryAfter); d < tc.lo || d > tc.hi {
+				t.Fatalf("attempt %d retry-after %s: delay %s outside [%s, %s]", tc.attempt, tc.retryAfter, d, tc.lo, tc.hi)
+			}
+		}
+	}
+}
+
+func TestPoliciesFor(t *testing.T) {
+	rules, err := ParseRules(`[
+		{"tenantId":"acme","templateId":"invoice","maxAttempts":20,"maxAgeSeconds":21600},
+		{"templateId":"otp","maxAttempts":2,"maxAgeSeconds":120,"retryableStatuses":[]}
+	]`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	ps := &Policies{Default: Policy{MaxAttempts: 4, RetryableErrorCodes: []string{"30001"}}, Rules: rules}
+
+	otp := ps.For("acme", "otp")
+	if otp.MaxAttempts != 2 || !otp.Expired(3*time.Minute) || otp.Multiplier != 2 || otp.RetryableErrorCodes[0] != "30001" {
+		t.Fatalf("unexpected otp policy %+v", otp)
+	}
+	invoice := ps.For("acme", "invoice")
+	if invoice.MaxAttempts != 20 || invoice.Expired(5*time.Hour) || invoice.LastAttempt(19) || !invoice.LastAttempt(20) {
+		t.Fatalf("unexpected invoice policy %+v", invoice)
+	}
+	other := ps.For("other", "invoice")
+	if other.MaxAttempts != 4 || other.Expired(1000*time.Hour) {
+		t.Fatalf("expected the default policy, got %+v", other)
+	}
+	if (*Policies)(nil).For("t", "otp").MaxAttempts != Defaults.MaxAttempts {
+		t.Fatal("expected defaults from nil policies")
+	}
+
+	for _, bad := range []string{`{`, `[{"maxAttempts":-1}]`, `[{"multiplier":0.5}]`} {
+		if _, err := ParseRules(bad); err == nil {
+			t.Fatalf("expected error for %s", bad)
+		}
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	def := Defaults
+	if !def.Retryable(providers.ErrorRetryable, providers.SendResult{HTTPStatus: 503}) {
+		t.Fatal("expected the provider's classification without overrides")
+	}
+	if def.Retryable(providers.ErrorPermanent, providers.SendResult{HTTPStatus: 400}) {
+		t.Fatal("expected a permanent error to stay permanent")
+	}
+
+	p := Defaults.With(Policy{RetryableStatuses: []int{503}, RetryableErrorCodes: []string{"30001"}})
+	for _, tc := range []struct {
+		class providers.ErrorClass
+		res   providers.SendResult
+		want  bool
+	}{
+		{providers.ErrorRetryable, providers.SendResult{HTTPStatus: 503}, true},
+		{providers.ErrorRetryable, providers.SendResult{HTTPStatus: 429}, false},
+		{providers.ErrorPermanent, providers.SendResult{HTTPStatus: 400, ErrorCode: "30001"}, true},
+		{providers.ErrorRetryable, providers.SendResult{}, true},
+	} {
+		if got := p.Retryable(tc.class, tc.res); got != tc.want {
+			t.Fatalf("Retryable(%v, %+v) = %v, want %v", tc.class, tc.res, got, tc.want)
+		}
+	}
+}
+
+func TestPoliciesValidate(t *testing.T) {
+	rules, err := ParseRules(`[{"tenantId":"acme","templateId":"invoice","maxAgeSeconds":7200,"maxDelaySeconds":1800}]`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if err := (&Policies{Rules: rules}).Validate(20); err != nil {
+		t.Fatalf("expected the documented rule to fit 20 receives: %v", err)
+	}
+	if err := (&Policies{Rules: rules}).Validate(5); err == nil {
+		t.Fatal("expected 2h to outlast 5 receives")
+	}
+	if err := (&Policies{Default: Policy{MaxAttempts: 6}}).Validate(5); err == nil {
+		t.Fatal("expected more attempts than receives to be rejected")
+	}
+	if err := (&Policies{Rules: []Rule{{Policy: Policy{MaxAgeSeconds: 21600}}}}).Validate(20); err == nil {
+		t.Fatal("expected 6h to outlast the default backoff")
+	}
+	if err := (&Policies{Default: Policy{MaxAttempts: 50}}).Validate(0); err != nil {
+		t.Fatalf("expected no limit without a redrive policy: %v", err)
+	}
+}
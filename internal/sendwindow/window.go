@@ -0,0 +1,96 @@
+// Package sendwindow computes when a message may be delivered given a daily local-time window.
+package sendwindow
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrInvalid = errors.New("invalid delivery window")
+
+// Window allows delivery between Start and End (minutes after local midnight) on Days.
+// End <= Start wraps past midnight (22:00-06:00); Start == End allows the whole day.
+// A window belongs to the day it opens on.
+type Window struct {
+	Start int
+	End   int
+	// Days the window opens on; empty means every day.
+	Days []time.Weekday
+}
+
+// Next returns t if t is inside the window in loc, otherwise the next time the window opens.
+func (w Window) Next(t time.Time, loc *time.Location) time.Time {
+	local := t.In(loc)
+	y, m, d := local.Date()
+	// Start a day early so a window that opened yesterday and wraps past midnight is seen.
+	for i := -1; i <= 7; i++ {
+		day := time.Date(y, m, d+i, 0, 0, 0, 0, loc)
+		if !w.opensOn(day.Weekday()) {
+			continue
+		}
+		open := atMinute(day, w.Start)
+		end := atMinute(day, w.End)
+		if w.End <= w.Start {
+			end = atMinute(time.Date(y, m, d+i+1, 0, 0, 0, 0, loc), w.End)
+		}
+		if !t.Before(end) {
+			continue
+		}
+		if t.Before(open) {
+			return open
+		}
+		return t
+	}
+	return t // no allowed days; Validate rejects this
+}
+
+func (w Window) opensOn(wd time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if d == wd {
+			return true
+		}
+	}
+	return false
+}
+
+// atMinute builds the wall-clock time so DST transitions shift the window with local time.
+func atMinute(day time.Time, minute int) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), minute/60, minute%60, 0, 0, day.Location())
+}
+
+// ParseClock parses "HH:MM" (24h) into minutes after midnight.
+func ParseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q is not HH:MM", ErrInvalid, s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// FormatClock is the inverse of ParseClock.
+func FormatClock(minute int) string {
+	return fmt.Sprintf("%02d:%02d", minute/60, minute%60)
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// ParseDays parses day names ("mon", "tue", ...).
+func ParseDays(names []string) ([]time.Weekday, error) {
+	out := make([]time.Weekday, 0, len(names))
+	for _, n := range names {
+		wd, ok := weekdays[strings.ToLower(n)]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown day %q", ErrInvalid, n)
+		}
+		out = append(out, wd)
+	}
+	return out, nil
+}
@@ -0,0 +1,57 @@
+package sendwindow
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := Window{Start: 9 * 60, End: 21 * 60}
+	night := Window{Start: 22 * 60, End: 6 * 60}
+	weekdays := Window{Start: 9 * 60, End: 17 * 60, Days: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}}
+
+	at := func(y int, m time.Month, d, h, min int) time.Time { return time.Date(y, m, d, h, min, 0, 0, ny) }
+	cases := []struct {
+		name string
+		w    Window
+		t    time.Time
+		want time.Time
+	}{
+		{"inside", day, at(2024, 7, 3, 12, 0), at(2024, 7, 3, 12, 0)},
+		{"before open", day, at(2024, 7, 3, 3, 0), at(2024, 7, 3, 9, 0)},
+		{"after close", day, at(2024, 7, 3, 21, 0), at(2024, 7, 4, 9, 0)},
+		{"overnight inside after midnight", night, at(2024, 7, 3, 2, 0), at(2024, 7, 3, 2, 0)},
+		{"overnight closed", night, at(2024, 7, 3, 12, 0), at(2024, 7, 3, 22, 0)},
+		{"friday evening to monday", weekdays, at(2024, 7, 5, 18, 0), at(2024, 7, 8, 9, 0)},
+		// 2024-03-10 is the spring-forward day in New York; 09:00 is still 09:00 local.
+		{"dst", day, at(2024, 3, 10, 1, 0), at(2024, 3, 10, 9, 0)},
+	}
+	for _, tc := range cases {
+		got := tc.w.Next(tc.t.UTC(), ny)
+		if !got.Equal(tc.want) {
+			t.Fatalf("%s: got %s want %s", tc.name, got.In(ny), tc.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	if m, err := ParseClock("08:30"); err != nil || m != 510 || FormatClock(m) != "08:30" {
+		t.Fatalf("clock: %d %v", m, err)
+	}
+	for _, bad := range []string{"8", "24:00", "12:60", ""} {
+		if _, err := ParseClock(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+	days, err := ParseDays([]string{"Mon", "sat"})
+	if err != nil || len(days) != 2 || days[0] != time.Monday || days[1] != time.Saturday {
+		t.Fatalf("days: %v %v", days, err)
+	}
+	if _, err := ParseDays([]string{"someday"}); err == nil {
+		t.Fatalf("expected error for unknown day")
+	}
+}
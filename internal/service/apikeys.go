@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"notif/internal/domain"
+	"notif/internal/store"
+	"notif/internal/util"
+)
+
+type APIKeyStore interface {
+	InsertAPIKey(ctx context.Context, in store.APIKeyInsert) (bool, error)
+	FindActiveAPIKeyByHash(ctx context.Context, hash string, now time.Time) (store.APIKey, bool, error)
+	ListAPIKeys(ctx context.Context, tenantID string) ([]store.APIKey, error)
+	RevokeAPIKey(ctx context.Context, tenantID, keyID string, revokeAt time.Time) (bool, error)
+	RotateAPIKey(ctx context.Context, oldKeyID string, in store.APIKeyInsert, revokeOldAt time.Time) (name string, ok bool, err error)
+}
+
+// APIKeyService issues and verifies tenant API keys. Keys look like "nk_<random>"; only their
+// SHA-256 is persisted.
+type APIKeyService struct {
+	Store APIKeyStore
+}
+
+const (
+	apiKeyPrefix     = "nk_"
+	apiKeyShownChars = 10
+)
+
+func (s *APIKeyService) Create(ctx context.Context, tenantID, name string, now time.Time) (domain.CreatedAPIKey, error) {
+	key, in, err := newAPIKey(tenantID, name, now)
+	if err != nil {
+		return domain.CreatedAPIKey{}, err
+	}
+	ok, err := s.Store.InsertAPIKey(ctx, in)
+	if err != nil {
+		return domain.CreatedAPIKey{}, err
+	}
+	if !ok {
+		return domain.CreatedAPIKey{}, domain.ErrTenantNotFound
+	}
+	return key, nil
+}
+
+// Rotate issues a replacement for keyID. The old key keeps working for grace, then stops.
+func (s *APIKeyService) Rotate(ctx context.Context, tenantID, keyID string, grace time.Duration, now time.Time) (domain.CreatedAPIKey, error) {
+	key, in, err := newAPIKey(tenantID, "", now)
+	if err != nil {
+		return domain.CreatedAPIKey{}, err
+	}
+	name, ok, err := s.Store.RotateAPIKey(ctx, keyID, in, now.Add(grace))
+	if err != nil {
+		return domain.CreatedAPIKey{}, err
+	}
+	if !ok {
+		return domain.CreatedAPIKey{}, domain.ErrAPIKeyNotFound
+	}
+	key.Name = name
+	return key, nil
+}
+
+func (s *APIKeyService) Revoke(ctx context.Context, tenantID, keyID string, now time.Time) error {
+	ok, err := s.Store.RevokeAPIKey(ctx, tenantID, keyID, now)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return domain.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+func (s *APIKeyService) List(ctx context.Context, tenantID string) ([]domain.APIKey, error) {
+	keys, err := s.Store.ListAPIKeys(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]domain.APIKey, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, toDomainAPIKey(k))
+	}
+	return out, nil
+}
+
+// Authenticate resolves a presented key to its tenant.
+func (s *APIKeyService) Authenticate(ctx context.Context, rawKey string, now time.Time) (string, bool, error) {
+	if rawKey == "" {
+		return "", false, nil
+	}
+	k, found, err := s.Store.FindActiveAPIKeyByHash(ctx, hashAPIKey(rawKey), now)
+	if err != nil || !found {
+		return "", false, err
+	}
+	return k.TenantID, true, nil
+}
+
+func newAPIKey(tenantID, name string, now time.Time) (domain.CreatedAPIKey, store.APIKeyInsert, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return domain.CreatedAPIKey{}, store.APIKeyInsert{}, err
+	}
+	raw := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	in := store.APIKeyInsert{
+		ID:       util.NewAPIKeyID(),
+		TenantID: tenantID,
+		Name:     name,
+		Prefix:   raw[:apiKeyShownChars],
+		Hash:     hashAPIKey(raw),
+		Now:      now,
+	}
+	return domain.CreatedAPIKey{
+		APIKey: domain.APIKey{ID: in.ID, TenantID: tenantID, Name: name, Prefix: in.Prefix, CreatedAt: now},
+		Key:    raw,
+	}, in, nil
+}
+
+func hashAPIKey(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+func toDomainAPIKey(k store.APIKey) domain.APIKey {
+	return domain.APIKey{
+		ID:        k.ID,
+		TenantID:  k.TenantID,
+		Name:      k.Name,
+		Prefix:    k.Prefix,
+		CreatedAt: k.CreatedAt,
+		RevokedAt: k.RevokedAt,
+	}
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"notif/internal/domain"
+	"notif/internal/phone"
+	"notif/internal/store"
+)
+
+type ConsentStore interface {
+	ApplyConsentChanges(ctx context.Context, changes []store.ConsentChange) (int, error)
+	GetConsent(ctx context.Context, tenantID, phone, channel string) (store.Consent, bool, error)
+	ListConsentEvents(ctx context.Context, tenantID, phone, channel string) ([]store.ConsentEvent, error)
+}
+
+// ConsentService writes consents together with their audit history.
+type ConsentService struct {
+	Store ConsentStore
+}
+
+// Set records a consent decision for phone. source says how it was captured (api, keyword, import).
+func (s *ConsentService) Set(ctx context.Context, tenantID, phone, channel string, status domain.ConsentStatus, source, actor string, now time.Time) (domain.Consent, error) {
+	if !status.Valid() {
+		return domain.Consent{}, domain.ErrInvalidConsentStatus
+	}
+	c, err := consentChange(tenantID, phone, channel, status, source, actor, now)
+	if err != nil {
+		return domain.Consent{}, err
+	}
+	if _, err := s.Store.ApplyConsentChanges(ctx, []store.ConsentChange{c}); err != nil {
+		return domain.Consent{}, err
+	}
+	return domain.Consent{TenantID: tenantID, Phone: c.Phone, Channel: c.Channel, Status: status, UpdatedAt: now}, nil
+}
+
+// Delete removes the consent record (the phone is then treated as not opted in) and records it in history.
+func (s *ConsentService) Delete(ctx context.Context, tenantID, phoneNum, channel, source, actor string, now time.Time) error {
+	c := store.ConsentChange{
+		TenantID: tenantID,
+		Phone:    phone.Normalize(phoneNum),
+		Channel:  channelOrDefault(channel),
+		Status:   string(domain.ConsentDeleted),
+		Source:   source,
+		Actor:    actor,
+		Now:      now,
+	}
+	n, err := s.Store.ApplyConsentChanges(ctx, []store.ConsentChange{c})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrConsentNotFound
+	}
+	return nil
+}
+
+// Get returns the current consent with its full history. A deleted consent is reported as
+// deleted with the history that led to it; only a phone without any history is not found.
+func (s *ConsentService) Get(ctx context.Context, tenantID, phoneNum, channel string) (domain.Consent, error) {
+	phoneNum, channel = phone.Normalize(phoneNum), channelOrDefault(channel)
+	c, found, err := s.Store.GetConsent(ctx, tenantID, phoneNum, channel)
+	if err != nil {
+		return domain.Consent{}, err
+	}
+	events, err := s.Store.ListConsentEvents(ctx, tenantID, phoneNum, channel)
+	if err != nil {
+		return domain.Consent{}, err
+	}
+	if !found {
+		if len(events) == 0 {
+			return domain.Consent{}, domain.ErrConsentNotFound
+		}
+		last := events[len(events)-1]
+		c = store.Consent{TenantID: tenantID, Phone: phoneNum, Channel: channel, Status: string(domain.ConsentDeleted), UpdatedAt: last.CreatedAt}
+	}
+	out := domain.Consent{
+		TenantID:  c.TenantID,
+		Phone:     c.Phone,
+		Channel:   c.Channel,
+		Status:    domain.ConsentStatus(c.Status),
+		UpdatedAt: c.UpdatedAt,
+		History:   make([]domain.ConsentEvent, 0, len(events)),
+	}
+	for _, e := range events {
+		out.History = append(out.History, domain.ConsentEvent{
+			Status: domain.ConsentStatus(e.Status),
+			Source: e.Source,
+			Actor:  e.Actor,
+			At:     e.CreatedAt,
+		})
+	}
+	return out, nil
+}
+
+// Import applies all items of a validated import request atomically.
+func (s *ConsentService) Import(ctx context.Context, req domain.ImportConsentsRequest, now time.Time) (int, error) {
+	changes := make([]store.ConsentChange, len(req.Items))
+	for i, it := range req.Items {
+		c, err := consentChange(req.TenantID, it.Phone, req.Channel, it.Status, domain.ConsentSourceImport, req.Actor, now)
+		if err != nil {
+			return 0, fmt.Errorf("item %d: %w", i, err)
+		}
+		changes[i] = c
+	}
+	return s.Store.ApplyConsentChanges(ctx, changes)
+}
+
+// consentChange rejects numbers that are not valid E.164 so a consent can't be stored under a key
+// that sends would never look up.
+func consentChange(tenantID, phoneNum, channel string, status domain.ConsentStatus, source, actor string, now time.Time) (store.ConsentChange, error) {
+	num, err := phone.Parse(phoneNum)
+	if err != nil {
+		return store.ConsentChange{}, domain.ErrInvalidPhone
+	}
+	return store.ConsentChange{
+		TenantID: tenantID,
+		Phone:    num.E164,
+		Channel:  channelOrDefault(channel),
+		Status:   string(status),
+		Source:   source,
+		Actor:    actor,
+		Now:      now,
+	}, nil
+}
+
+func channelOrDefault(channel string) string {
+	if channel == "" {
+		return domain.ChannelSMS
+	}
+	return channel
+}
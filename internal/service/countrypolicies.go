@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"notif/internal/domain"
+	"notif/internal/store"
+)
+
+type CountryPolicyStore interface {
+	UpsertCountryPolicy(ctx context.Context, in store.CountryPolicy) (bool, error)
+	GetCountryPolicy(ctx context.Context, tenantID string) (store.CountryPolicy, bool, error)
+	DeleteCountryPolicy(ctx context.Context, tenantID string) (bool, error)
+}
+
+// CountryPolicyService manages the per-tenant destination policy enforced at send time.
+type CountryPolicyService struct {
+	Store CountryPolicyStore
+}
+
+// Put replaces the tenant's policy with a validated request.
+func (s *CountryPolicyService) Put(ctx context.Context, tenantID string, req domain.PutCountryPolicyRequest, now time.Time) (domain.CountryPolicy, error) {
+	p := store.CountryPolicy{
+		TenantID:         tenantID,
+		AllowedCountries: req.AllowedCountries,
+		BlockedPrefixes:  req.BlockedPrefixes,
+		CountryDailyCaps: req.CountryDailyCaps,
+		UpdatedAt:        now,
+	}
+	ok, err := s.Store.UpsertCountryPolicy(ctx, p)
+	if err != nil {
+		return domain.CountryPolicy{}, err
+	}
+	if !ok {
+		return domain.CountryPolicy{}, domain.ErrTenantNotFound
+	}
+	return toDomainCountryPolicy(p), nil
+}
+
+func (s *CountryPolicyService) Get(ctx context.Context, tenantID string) (domain.CountryPolicy, error) {
+	p, found, err := s.Store.GetCountryPolicy(ctx, tenantID)
+	if err != nil {
+		return domain.CountryPolicy{}, err
+	}
+	if !found {
+		return domain.CountryPolicy{}, domain.ErrCountryPolicyNotFound
+	}
+	return toDomainCountryPolicy(p), nil
+}
+
+// Delete removes the policy, allowing every destination again.
+func (s *CountryPolicyService) Delete(ctx context.Context, tenantID string) error {
+	ok, err := s.Store.DeleteCountryPolicy(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return domain.ErrCountryPolicyNotFound
+	}
+	return nil
+}
+
+func toDomainCountryPolicy(p store.CountryPolicy) domain.CountryPolicy {
+	return domain.CountryPolicy{
+		TenantID:         p.TenantID,
+		AllowedCountries: p.AllowedCountries,
+		BlockedPrefixes:  p.BlockedPrefixes,
+		CountryDailyCaps: p.CountryDailyCaps,
+		UpdatedAt:        p.UpdatedAt,
+	}
+}
+
+// countryBlockReason returns the suppression reason the policy gives an E.164 destination in
+// country, or "" if it may be sent to. Daily caps are checked separately since they need the store.
+func countryBlockReason(p store.CountryPolicy, to, country string) string {
+	for _, prefix := range p.BlockedPrefixes {
+		if strings.HasPrefix(to, prefix) {
+			return domain.ReasonPrefixBlocked
+		}
+	}
+	if len(p.AllowedCountries) > 0 && !slices.Contains(p.AllowedCountries, country) {
+		return domain.ReasonCountryBlocked
+	}
+	return ""
+}
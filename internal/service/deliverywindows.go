@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"notif/internal/domain"
+	"notif/internal/phone"
+	"notif/internal/sendwindow"
+	"notif/internal/store"
+)
+
+type DeliveryWindowStore interface {
+	UpsertDeliveryWindow(ctx context.Context, in store.DeliveryWindow) (bool, error)
+	GetDeliveryWindow(ctx context.Context, tenantID, campaignID string) (store.DeliveryWindow, bool, error)
+	DeleteDeliveryWindow(ctx context.Context, tenantID, campaignID string) (bool, error)
+}
+
+// DeliveryWindowService manages quiet hours. An empty campaignID addresses the tenant default.
+type DeliveryWindowService struct {
+	Store DeliveryWindowStore
+}
+
+func (s *DeliveryWindowService) Put(ctx context.Context, tenantID, campaignID string, req domain.PutDeliveryWindowRequest, now time.Time) (domain.DeliveryWindow, error) {
+	w, err := deliveryWindowRow(tenantID, campaignID, req, now)
+	if err != nil {
+		return domain.DeliveryWindow{}, err
+	}
+	ok, err := s.Store.UpsertDeliveryWindow(ctx, w)
+	if err != nil {
+		return domain.DeliveryWindow{}, err
+	}
+	if !ok {
+		return domain.DeliveryWindow{}, domain.ErrTenantNotFound
+	}
+	return toDomainDeliveryWindow(w), nil
+}
+
+func (s *DeliveryWindowService) Get(ctx context.Context, tenantID, campaignID string) (domain.DeliveryWindow, error) {
+	w, found, err := s.Store.GetDeliveryWindow(ctx, tenantID, campaignID)
+	if err != nil {
+		return domain.DeliveryWindow{}, err
+	}
+	if !found {
+		return domain.DeliveryWindow{}, domain.ErrDeliveryWindowNotFound
+	}
+	return toDomainDeliveryWindow(w), nil
+}
+
+func (s *DeliveryWindowService) Delete(ctx context.Context, tenantID, campaignID string) error {
+	ok, err := s.Store.DeleteDeliveryWindow(ctx, tenantID, campaignID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return domain.ErrDeliveryWindowNotFound
+	}
+	return nil
+}
+
+func deliveryWindowRow(tenantID, campaignID string, req domain.PutDeliveryWindowRequest, now time.Time) (store.DeliveryWindow, error) {
+	start, err := sendwindow.ParseClock(req.Start)
+	if err != nil {
+		return store.DeliveryWindow{}, fmt.Errorf("%w: start: %v", domain.ErrInvalidDeliveryWindow, err)
+	}
+	end, err := sendwindow.ParseClock(req.End)
+	if err != nil {
+		return store.DeliveryWindow{}, fmt.Errorf("%w: end: %v", domain.ErrInvalidDeliveryWindow, err)
+	}
+	if _, err := sendwindow.ParseDays(req.Days); err != nil {
+		return store.DeliveryWindow{}, fmt.Errorf("%w: %v", domain.ErrInvalidDeliveryWindow, err)
+	}
+	tz := req.Timezone
+	if tz == "" {
+		tz = "UTC"
+	}
+	if _, err := time.LoadLocation(tz); err != nil {
+		return store.DeliveryWindow{}, fmt.Errorf("%w: unknown timezone %q", domain.ErrInvalidDeliveryWindow, tz)
+	}
+	return store.DeliveryWindow{
+		TenantID:    tenantID,
+		CampaignID:  campaignID,
+		StartMinute: start,
+		EndMinute:   end,
+		Days:        req.Days,
+		Timezone:    tz,
+		UpdatedAt:   now,
+	}, nil
+}
+
+func toDomainDeliveryWindow(w store.DeliveryWindow) domain.DeliveryWindow {
+	return domain.DeliveryWindow{
+		TenantID:   w.TenantID,
+		CampaignID: w.CampaignID,
+		Start:      sendwindow.FormatClock(w.StartMinute),
+		End:        sendwindow.FormatClock(w.EndMinute),
+		Days:       w.Days,
+		Timezone:   w.Timezone,
+		UpdatedAt:  w.UpdatedAt,
+	}
+}
+
+// NextDelivery returns when a message to the E.164 number to may be delivered under w: now if
+// the window is open in the recipient's time zone, otherwise when it next opens. The window's
+// own time zone is used when the number doesn't pin one down.
+func NextDelivery(w store.DeliveryWindow, to string, now time.Time) time.Time {
+	loc, ok := phone.Location(to, now)
+	if !ok {
+		var err error
+		if loc, err = time.LoadLocation(w.Timezone); err != nil {
+			loc = time.UTC
+		}
+	}
+	days, _ := sendwindow.ParseDays(w.Days)
+	return sendwindow.Window{Start: w.StartMinute, End: w.EndMinute, Days: days}.Next(now, loc)
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"time"
+
+	"notif/internal/domain"
+	"notif/internal/phone"
+	"notif/internal/providers"
+	"notif/internal/sms"
+	"notif/internal/store"
+)
+
+type InboundStore interface {
+	InsertInboundMessage(ctx context.Context, in store.InboundMessage) error
+	ListPhoneConsents(ctx context.Context, phone, channel string) ([]store.Consent, error)
+	ApplyConsentChanges(ctx context.Context, changes []store.ConsentChange) (int, error)
+	GetKeywordReplies(ctx context.Context, tenantID string) (store.KeywordReplies, bool, error)
+	LastSenderTenant(ctx context.Context, phone string) (store.SenderTenant, bool, error)
+}
+
+// InboundService handles SMS sent to our numbers. Sender numbers are shared between tenants, so
+// STOP and START apply to every tenant holding a consent for the number.
+type InboundService struct {
+	Store InboundStore
+	// Defaults answers keywords for tenants without their own replies, prefixed with the tenant's
+	// name, and as is for numbers no tenant has messaged. An empty reply sends nothing.
+	Defaults domain.KeywordReplies
+}
+
+// Receive stores the message and applies its keyword, if any. It returns the auto-reply to send
+// back, or "" for none. Applying a keyword twice changes nothing, so provider redeliveries are safe.
+func (s *InboundService) Receive(ctx context.Context, provider string, in providers.InboundMessage, now time.Time) (string, error) {
+	from := phone.Normalize(in.From)
+	kw := sms.ParseKeyword(in.Body)
+	if err := s.Store.InsertInboundMessage(ctx, store.InboundMessage{
+		Provider:      provider,
+		ProviderMsgID: in.ProviderMsgID,
+		From:          from,
+		To:            in.To,
+		Body:          in.Body,
+		Keyword:       string(kw),
+		ReceivedAt:    now,
+	}); err != nil {
+		return "", err
+	}
+	if kw == "" {
+		return "", nil
+	}
+
+	consents, err := s.Store.ListPhoneConsents(ctx, from, domain.ChannelSMS)
+	if err != nil {
+		return "", err
+	}
+	if changes := keywordChanges(kw, consents, provider+":"+in.ProviderMsgID, now); len(changes) > 0 {
+		if _, err := s.Store.ApplyConsentChanges(ctx, changes); err != nil {
+			return "", err
+		}
+	}
+
+	// Answer as the tenant whose message the number received last. The sender number is shared,
+	// so in.To doesn't tell tenants apart, and that tenant may hold no consent row by now.
+	sender, found, err := s.Store.LastSenderTenant(ctx, from)
+	if err != nil {
+		return "", err
+	}
+	replies := store.KeywordReplies{}
+	if found {
+		if replies, _, err = s.Store.GetKeywordReplies(ctx, sender.ID); err != nil {
+			return "", err
+		}
+	}
+	switch kw {
+	case sms.KeywordStop:
+		return brandReply(replies.Stop, s.Defaults.Stop, sender.Name), nil
+	case sms.KeywordStart:
+		return brandReply(replies.Start, s.Defaults.Start, sender.Name), nil
+	default:
+		return brandReply(replies.Help, s.Defaults.Help, sender.Name), nil
+	}
+}
+
+// brandReply is the tenant's own reply, or the default prefixed with the tenant's name: carriers
+// expect keyword replies to identify the program.
+func brandReply(own, def, tenantName string) string {
+	if own != "" || def == "" || tenantName == "" {
+		return cmp.Or(own, def)
+	}
+	return tenantName + ": " + def
+}
+
+// keywordChanges opts the number out of every tenant on STOP. START only reverses opt-outs that
+// came from a keyword: an opt-out recorded through the API or an import stands.
+func keywordChanges(kw sms.Keyword, consents []store.Consent, actor string, now time.Time) []store.ConsentChange {
+	var changes []store.ConsentChange
+	for _, c := range consents {
+		var status domain.ConsentStatus
+		switch {
+		case kw == sms.KeywordStop && c.Status == string(domain.ConsentOptedIn):
+			status = domain.ConsentOptedOut
+		case kw == sms.KeywordStart && c.Status == string(domain.ConsentOptedOut) && c.Source == domain.ConsentSourceKeyword:
+			status = domain.ConsentOptedIn
+		default:
+			continue
+		}
+		changes = append(changes, store.ConsentChange{
+			TenantID: c.TenantID,
+			Phone:    c.Phone,
+			Channel:  c.Channel,
+			Status:   string(status),
+			Source:   domain.ConsentSourceKeyword,
+			Actor:    actor,
+			Now:      now,
+		})
+	}
+	return changes
+}
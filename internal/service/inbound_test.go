@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"notif/internal/domain"
+	"notif/internal/providers"
+	"notif/internal/sms"
+	"notif/internal/store"
+)
+
+func TestKeywordChanges(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	consents := []store.Consent{
+		{TenantID: "in", Phone: "+15550001111", Channel: "sms", Status: "opted_in", Source: domain.ConsentSourceAPI},
+		{TenantID: "kw-out", Phone: "+15550001111", Channel: "sms", Status: "opted_out", Source: domain.ConsentSourceKeyword},
+		{TenantID: "api-out", Phone: "+15550001111", Channel: "sms", Status: "opted_out", Source: domain.ConsentSourceAPI},
+		{TenantID: "import-out", Phone: "+15550001111", Channel: "sms", Status: "opted_out", Source: domain.ConsentSourceImport},
+	}
+	changed := func(kw sms.Keyword) map[string]string {
+		out := map[string]string{}
+		for _, c := range keywordChanges(kw, consents, "twilio:SM1", now) {
+			if c.Source != domain.ConsentSourceKeyword || c.Actor != "twilio:SM1" || !c.Now.Equal(now) {
+				t.Fatalf("unexpected change %+v", c)
+			}
+			out[c.TenantID] = c.Status
+		}
+		return out
+	}
+
+	// STOP only touches opted-in tenants; the others are already out.
+	if got := changed(sms.KeywordStop); len(got) != 1 || got["in"] != "opted_out" {
+		t.Fatalf("STOP: unexpected changes %v", got)
+	}
+	// START undoes keyword opt-outs only: API and import opt-outs stand.
+	if got := changed(sms.KeywordStart); len(got) != 1 || got["kw-out"] != "opted_in" {
+		t.Fatalf("START: unexpected changes %v", got)
+	}
+	if got := changed(sms.KeywordHelp); len(got) != 0 {
+		t.Fatalf("HELP: unexpected changes %v", got)
+	}
+}
+
+type fakeInboundStore struct {
+	sender  *store.SenderTenant
+	replies map[string]store.KeywordReplies
+}
+
+func (f *fakeInboundStore) InsertInboundMessage(ctx context.Context, in store.InboundMessage) error {
+	return nil
+}
+
+func (f *fakeInboundStore) ListPhoneConsents(ctx context.Context, phone, channel string) ([]store.Consent, error) {
+	return nil, nil
+}
+
+func (f *fakeInboundStore) ApplyConsentChanges(ctx context.Context, changes []store.ConsentChange) (int, error) {
+	return len(changes), nil
+}
+
+func (f *fakeInboundStore) GetKeywordReplies(ctx context.Context, tenantID string) (store.KeywordReplies, bool, error) {
+	r, ok := f.replies[tenantID]
+	return r, ok, nil
+}
+
+func (f *fakeInboundStore) LastSenderTenant(ctx context.Context, phone string) (store.SenderTenant, bool, error) {
+	if f.sender == nil {
+		return store.SenderTenant{}, false, nil
+	}
+	return *f.sender, true, nil
+}
+
+func TestReceiveRepliesAsLastSender(t *testing.T) {
+	st := &fakeInboundStore{replies: map[string]store.KeywordReplies{"acme": {Stop: "Acme: bye."}}}
+	s := &InboundService{Store: st, Defaults: domain.KeywordReplies{Help: "Reply STOP to opt out.", Stop: "Unsubscribed."}}
+	receive := func(body string) string {
+		t.Helper()
+		reply, err := s.Receive(context.Background(), "twilio", providers.InboundMessage{ProviderMsgID: "SM1", From: "+15550001111", Body: body}, time.Now())
+		if err != nil {
+			t.Fatalf("receive: %v", err)
+		}
+		return reply
+	}
+
+	// A number no tenant has messaged gets the defaults as configured.
+	if got := receive("HELP"); got != "Reply STOP to opt out." {
+		t.Fatalf("unexpected reply %q", got)
+	}
+
+	// Otherwise the last sender answers, with its own reply or a default under its name.
+	st.sender = &store.SenderTenant{ID: "acme", Name: "Acme"}
+	if got := receive("STOP"); got != "Acme: bye." {
+		t.Fatalf("unexpected reply %q", got)
+	}
+	if got := receive("HELP"); got != "Acme: Reply STOP to opt out." {
+		t.Fatalf("unexpected reply %q", got)
+	}
+	if got := receive("START"); got != "" {
+		t.Fatalf("expected no reply without a START text, got %q", got)
+	}
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"notif/internal/domain"
+	"notif/internal/store"
+)
+
+type KeywordReplyStore interface {
+	UpsertKeywordReplies(ctx context.Context, in store.KeywordReplies) (bool, error)
+	GetKeywordReplies(ctx context.Context, tenantID string) (store.KeywordReplies, bool, error)
+	DeleteKeywordReplies(ctx context.Context, tenantID string) (bool, error)
+}
+
+// KeywordReplyService manages what a tenant answers to inbound HELP, STOP and START.
+type KeywordReplyService struct {
+	Store KeywordReplyStore
+}
+
+func (s *KeywordReplyService) Put(ctx context.Context, tenantID string, req domain.PutKeywordRepliesRequest, now time.Time) (domain.KeywordReplies, error) {
+	r := store.KeywordReplies{TenantID: tenantID, Help: req.Help, Stop: req.Stop, Start: req.Start, UpdatedAt: now}
+	ok, err := s.Store.UpsertKeywordReplies(ctx, r)
+	if err != nil {
+		return domain.KeywordReplies{}, err
+	}
+	if !ok {
+		return domain.KeywordReplies{}, domain.ErrTenantNotFound
+	}
+	return toDomainKeywordReplies(r), nil
+}
+
+func (s *KeywordReplyService) Get(ctx context.Context, tenantID string) (domain.KeywordReplies, error) {
+	r, found, err := s.Store.GetKeywordReplies(ctx, tenantID)
+	if err != nil {
+		return domain.KeywordReplies{}, err
+	}
+	if !found {
+		return domain.KeywordReplies{}, domain.ErrKeywordRepliesNotFound
+	}
+	return toDomainKeywordReplies(r), nil
+}
+
+// Delete removes the tenant's replies; inbound keywords are then answered with the defaults.
+func (s *KeywordReplyService) Delete(ctx context.Context, tenantID string) error {
+	ok, err := s.Store.DeleteKeywordReplies(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return domain.ErrKeywordRepliesNotFound
+	}
+	return nil
+}
+
+func toDomainKeywordReplies(r store.KeywordReplies) domain.KeywordReplies {
+	return domain.KeywordReplies{TenantID: r.TenantID, Help: r.Help, Stop: r.Stop, Start: r.Start, UpdatedAt: r.UpdatedAt}
+}
@@ -2,36 +2,53 @@ package service
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"notif/internal/domain"
-	"notif/internal/observability"
+	"notif/internal/phone"
+	sqsqueue "notif/internal/queue/sqs"
 	"notif/internal/store"
-	"notif/internal/util"
+	"notif/internal/templates"
 )
 
 type Store interface {
 	FindMessageByIdempotency(ctx context.Context, tenantID, idemKey string) (store.IdempotencyResult, error)
+	FindMessagesByIdempotency(ctx context.Context, tenantID string, idemKeys []string) (map[string]store.IdempotencyResult, error)
 	InsertMessage(ctx context.Context, in store.MessageInsert) error
-	MarkMessageState(ctx context.Context, in store.MessageStateUpdate) error
-	GetMessage(ctx context.Context, msgID string) (store.Message, bool, error)
-	IsSuppressed(ctx context.Context, tenantID, phone string) (bool, error)
+	InsertMessages(ctx context.Context, in []store.MessageInsert) (map[string]bool, error)
+	GetMessage(ctx context.Context, tenantID, msgID string) (store.Message, bool, error)
+	ListMessages(ctx context.Context, f store.MessageFilter) ([]store.Message, error)
+	ListMessageHistory(ctx context.Context, msgID string) ([]store.HistoryEvent, error)
+	IsSuppressed(ctx context.Context, tenantID, phone string, now time.Time) (bool, error)
 	IsOptedIn(ctx context.Context, tenantID, phone string) (bool, error)
 	IncrementDailyCap(ctx context.Context, tenantID, phone string, day time.Time, maxPerDay int) (allowed bool, newCount int, err error)
+	ReleaseDailyCap(ctx context.Context, tenantID, phone string, day time.Time) error
+	GetCountryPolicy(ctx context.Context, tenantID string) (store.CountryPolicy, bool, error)
+	IncrementCountryCap(ctx context.Context, tenantID, country string, day time.Time, maxPerDay int) (bool, error)
+	ReleaseCountryCap(ctx context.Context, tenantID, country string, day time.Time) error
+	ResolveDeliveryWindow(ctx context.Context, tenantID, campaignID string) (store.DeliveryWindow, bool, error)
+	CancelMessage(ctx context.Context, tenantID, msgID string, now time.Time) (bool, error)
 }
 
-type Queue interface {
-	EnqueueSMS(ctx context.Context, tenantID, messageID, idempotencyKey, to, templateID string, vars map[string]string, campaignID string) error
-}
-
+// NotificationService accepts sends. It never talks to SQS directly: queued messages are written
+// together with an outbox row and published by the outbox relay.
 type NotificationService struct {
 	Store     Store
-	Queue     Queue
 	MaxPerDay int
+	// Templates, when set, rejects sends whose template has no published version.
+	Templates templates.Resolver
 }
 
 func (s *NotificationService) CreateAndEnqueueSMS(ctx context.Context, req domain.SendSMSRequest, messageID string, now time.Time) (domain.CreateResponse, error) {
-	req.To = util.NormalizePhone(req.To)
+	num, err := phone.Parse(req.To)
+	if err != nil {
+		return domain.CreateResponse{}, domain.ErrInvalidPhone
+	}
+	req.To = num.E164
+	if err := checkTiming(req, now); err != nil {
+		return domain.CreateResponse{}, err
+	}
 
 	// 1) idempotency
 	if res, err := s.Store.FindMessageByIdempotency(ctx, req.TenantID, req.IdempotencyKey); err != nil {
@@ -40,84 +57,508 @@ func (s *NotificationService) CreateAndEnqueueSMS(ctx context.Context, req domai
 		return domain.CreateResponse{MessageID: res.MessageID, State: res.State}, nil
 	}
 
-	// 2) create message row
-	if err := s.Store.InsertMessage(ctx, store.MessageInsert{
-		ID:         messageID,
-		TenantID:   req.TenantID,
-		IdemKey:    req.IdempotencyKey,
-		To:         req.To,
-		TemplateID: req.TemplateID,
-		Vars:       req.Vars,
-		CampaignID: req.CampaignID,
-		State:      string(domain.StateQueued),
-		Now:        now,
-	}); err != nil {
+	tpl, err := s.checkTemplate(ctx, req)
+	if err != nil {
 		return domain.CreateResponse{}, err
 	}
+	req.Priority = priority(req, tpl)
 
-	// 3) suppression
-	isSup, err := s.Store.IsSuppressed(ctx, req.TenantID, req.To)
+	// 2-4) country policy, suppression, consent, caps
+	pol, _, err := s.Store.GetCountryPolicy(ctx, req.TenantID)
 	if err != nil {
 		return domain.CreateResponse{}, err
-	} else if isSup {
-		if err := s.Store.MarkMessageState(ctx, store.MessageStateUpdate{
-			ID:        messageID,
-			State:     string(domain.StateSuppressed),
-			LastError: "suppressed",
-			Now:       now,
-		}); err != nil {
+	}
+	state, reason, err := s.admit(ctx, req, pol, now)
+	if err != nil {
+		return domain.CreateResponse{}, err
+	}
+
+	// sendAt and quiet hours: hold the message until it may be delivered
+	var sendAt *time.Time
+	if state == domain.StateQueued {
+		if sendAt, err = s.releaseAt(ctx, req, tpl, now, nil); err != nil {
+			s.releaseCap(ctx, req, pol, now)
 			return domain.CreateResponse{}, err
 		}
-		return domain.CreateResponse{MessageID: messageID, State: string(domain.StateSuppressed)}, nil
+		if sendAt != nil {
+			state = domain.StateScheduled
+		}
 	}
 
-	// 4) consent
-	if ok, err := s.Store.IsOptedIn(ctx, req.TenantID, req.To); err != nil {
+	// 5) create message row (+ outbox row when pending) atomically
+	if err := s.Store.InsertMessage(ctx, messageInsert(req, messageID, state, reason, sendAt, now)); err != nil {
+		if state.Pending() {
+			s.releaseCap(ctx, req, pol, now)
+		}
 		return domain.CreateResponse{}, err
-	} else if !ok {
-		if err := s.Store.MarkMessageState(ctx, store.MessageStateUpdate{
-			ID:        messageID,
-			State:     string(domain.StateSuppressed),
-			LastError: "not_opted_in",
-			Now:       now,
-		}); err != nil {
+	}
+
+	return domain.CreateResponse{MessageID: messageID, State: string(state), ScheduledAt: sendAt}, nil
+}
+
+// CreateAndEnqueueSMSBatch runs the single-send pipeline for many recipients of one tenant, using
+// multi-row lookups/inserts. messageIDs must be aligned with reqs.
+// Per-item problems are reported in the results; the error is only for whole-batch failures.
+func (s *NotificationService) CreateAndEnqueueSMSBatch(ctx context.Context, tenantID string, reqs []domain.SendSMSRequest, messageIDs []string, now time.Time) ([]domain.BatchItemResult, error) {
+	results := make([]domain.BatchItemResult, len(reqs))
+	pending := make([]int, 0, len(reqs))
+	seen := make(map[string]bool, len(reqs))
+	for i := range reqs {
+		results[i] = domain.BatchItemResult{Index: i, IdempotencyKey: reqs[i].IdempotencyKey}
+		if err := reqs[i].Validate(); err != nil {
+			results[i].Fail(err)
+			continue
+		}
+		num, err := phone.Parse(reqs[i].To)
+		if err != nil {
+			results[i].Fail(domain.ErrInvalidPhone)
+			continue
+		}
+		reqs[i].To = num.E164
+		if err := checkTiming(reqs[i], now); err != nil {
+			results[i].Fail(err)
+			continue
+		}
+		if seen[reqs[i].IdempotencyKey] {
+			results[i].Fail(domain.ErrDuplicateIdempotency)
+			continue
+		}
+		seen[reqs[i].IdempotencyKey] = true
+		pending = append(pending, i)
+	}
+
+	// 1) idempotency
+	keys := make([]string, 0, len(pending))
+	for _, i := range pending {
+		keys = append(keys, reqs[i].IdempotencyKey)
+	}
+	existing, err := s.Store.FindMessagesByIdempotency(ctx, tenantID, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2-4) country policy, suppression, consent, caps
+	pol, _, err := s.Store.GetCountryPolicy(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	windows := map[string]*store.DeliveryWindow{}
+	toInsert := make([]int, 0, len(pending))
+	inserts := make([]store.MessageInsert, 0, len(pending))
+	// A whole-batch failure gives back the cap slots already taken for earlier items.
+	abort := func(err error) ([]domain.BatchItemResult, error) {
+		for _, i := range toInsert {
+			if domain.MessageState(results[i].State).Pending() {
+				s.releaseCap(ctx, reqs[i], pol, now)
+			}
+		}
+		return nil, err
+	}
+	for _, i := range pending {
+		if res, ok := existing[reqs[i].IdempotencyKey]; ok {
+			results[i].MessageID, results[i].State = res.MessageID, res.State
+			continue
+		}
+		tpl, err := s.checkTemplate(ctx, reqs[i])
+		if err != nil {
+			if !IsRejection(err) {
+				return abort(err)
+			}
+			results[i].Fail(err)
+			continue
+		}
+		reqs[i].Priority = priority(reqs[i], tpl)
+		// admit only fails on lookups, which would fail the same way for the rest of the batch.
+		state, reason, err := s.admit(ctx, reqs[i], pol, now)
+		if err != nil {
+			return abort(err)
+		}
+		var sendAt *time.Time
+		if state == domain.StateQueued {
+			if sendAt, err = s.releaseAt(ctx, reqs[i], tpl, now, windows); err != nil {
+				s.releaseCap(ctx, reqs[i], pol, now)
+				if !IsRejection(err) {
+					return abort(err)
+				}
+				results[i].Fail(err)
+				continue
+			}
+			if sendAt != nil {
+				state = domain.StateScheduled
+			}
+		}
+		results[i].State = string(state)
+		toInsert = append(toInsert, i)
+		inserts = append(inserts, messageInsert(reqs[i], messageIDs[i], state, reason, sendAt, now))
+	}
+
+	// 5) create message rows (+ outbox rows) atomically
+	inserted, err := s.Store.InsertMessages(ctx, inserts)
+	if err != nil {
+		return abort(err)
+	}
+
+	// Rows skipped by ON CONFLICT were created concurrently by another request; report those.
+	var raced []string
+	for _, i := range toInsert {
+		if inserted[messageIDs[i]] {
+			results[i].MessageID = messageIDs[i]
+			continue
+		}
+		if domain.MessageState(results[i].State).Pending() {
+			s.releaseCap(ctx, reqs[i], pol, now)
 		}
-		return domain.CreateResponse{MessageID: messageID, State: string(domain.StateSuppressed)}, nil
+		raced = append(raced, reqs[i].IdempotencyKey)
 	}
+	if len(raced) > 0 {
+		existing, err = s.Store.FindMessagesByIdempotency(ctx, tenantID, raced)
+		if err != nil {
+			return nil, err
+		}
+		for _, i := range toInsert {
+			if res, ok := existing[reqs[i].IdempotencyKey]; ok && !inserted[messageIDs[i]] {
+				results[i].MessageID, results[i].State = res.MessageID, res.State
+			}
+		}
+	}
+
+	return results, nil
+}
 
-	// 5) caps
+// admit applies the tenant's country policy, suppression, consent and daily caps. It returns the
+// state the message should be created in and, when suppressed, the reason stored in last_error.
+// A queued result has consumed a daily cap slot (and a country cap slot if the country has one).
+func (s *NotificationService) admit(ctx context.Context, req domain.SendSMSRequest, pol store.CountryPolicy, now time.Time) (domain.MessageState, string, error) {
+	// country policy: cheapest check and the one that stops SMS-pumping traffic
+	country := phone.Region(req.To)
+	if reason := countryBlockReason(pol, req.To, country); reason != "" {
+		return domain.StateSuppressed, reason, nil
+	}
+
+	// suppression
+	if isSup, err := s.Store.IsSuppressed(ctx, req.TenantID, req.To, now); err != nil {
+		return "", "", err
+	} else if isSup {
+		return domain.StateSuppressed, "suppressed", nil
+	}
+
+	// consent
+	if ok, err := s.Store.IsOptedIn(ctx, req.TenantID, req.To); err != nil {
+		return "", "", err
+	} else if !ok {
+		return domain.StateSuppressed, "not_opted_in", nil
+	}
+
+	// caps
 	allowed, _, err := s.Store.IncrementDailyCap(ctx, req.TenantID, req.To, now, s.MaxPerDay)
 	if err != nil {
-		return domain.CreateResponse{}, err
+		return "", "", err
 	}
 	if !allowed {
-		if err := s.Store.MarkMessageState(ctx, store.MessageStateUpdate{
-			ID:        messageID,
-			State:     string(domain.StateSuppressed),
-			LastError: "cap_exceeded",
-			Now:       now,
-		}); err != nil {
-		}
-		return domain.CreateResponse{MessageID: messageID, State: string(domain.StateSuppressed)}, nil
-	}
-
-	// 6) enqueue
-	if err := s.Queue.EnqueueSMS(ctx, req.TenantID, messageID, req.IdempotencyKey, req.To, req.TemplateID, req.Vars, req.CampaignID); err != nil {
-		observability.Enqueues.WithLabelValues("error").Inc()
-		if err := s.Store.MarkMessageState(ctx, store.MessageStateUpdate{
-			ID:        messageID,
-			State:     string(domain.StateFailed),
-			LastError: "enqueue_failed",
-			Now:       now,
-		}); err != nil {
+		return domain.StateSuppressed, "cap_exceeded", nil
+	}
+	if maxPerDay, ok := pol.CountryDailyCaps[country]; ok {
+		allowed, err := s.Store.IncrementCountryCap(ctx, req.TenantID, country, now, maxPerDay)
+		if err != nil || !allowed {
+			_ = s.Store.ReleaseDailyCap(ctx, req.TenantID, req.To, now)
+		}
+		if err != nil {
+			return "", "", err
+		}
+		if !allowed {
+			return domain.StateSuppressed, domain.ReasonCountryCapExceeded, nil
 		}
+	}
+
+	return domain.StateQueued, "", nil
+}
+
+// checkTemplate rejects sends whose template has no published version, or whose vars do not
+// render (missing required or malformed values) or render past the template's segment limit.
+// Without a resolver every send is accepted and the worker reports template problems.
+func (s *NotificationService) checkTemplate(ctx context.Context, req domain.SendSMSRequest) (store.Template, error) {
+	if s.Templates == nil {
+		return store.Template{}, nil
+	}
+	tpl, found, err := s.Templates.Resolve(ctx, req.TenantID, req.TemplateID)
+	if err != nil {
+		return store.Template{}, err
+	}
+	if !found {
+		return store.Template{}, domain.ErrUnknownTemplate
+	}
+	_, _, err = templates.Prepare(tpl, req.Vars)
+	return tpl, err
+}
+
+// releaseAt returns when an admitted message may be sent, or nil to send now: the requested
+// sendAt, pushed into the recipient's delivery window unless the template is transactional.
+// windows caches the window per campaign across a batch (nil disables caching); a campaign
+// without any window maps to nil. A message that would expire before its release is rejected.
+func (s *NotificationService) releaseAt(ctx context.Context, req domain.SendSMSRequest, tpl store.Template, now time.Time, windows map[string]*store.DeliveryWindow) (*time.Time, error) {
+	at := now
+	if req.SendAt != nil && req.SendAt.After(now) {
+		at = req.SendAt.UTC()
+	}
+	if !tpl.Transactional {
+		w, err := s.deliveryWindow(ctx, req, windows)
+		if err != nil {
+			return nil, err
+		}
+		if w != nil {
+			at = NextDelivery(*w, req.To, at)
+		}
+	}
+	if !at.After(now) {
+		return nil, nil
+	}
+	if exp := req.Expiry(now); exp != nil && !exp.After(at) {
+		return nil, domain.ErrExpiresBeforeWindow
+	}
+	return &at, nil
+}
+
+func (s *NotificationService) deliveryWindow(ctx context.Context, req domain.SendSMSRequest, windows map[string]*store.DeliveryWindow) (*store.DeliveryWindow, error) {
+	w, cached := windows[req.CampaignID]
+	if !cached {
+		found, ok, err := s.Store.ResolveDeliveryWindow(ctx, req.TenantID, req.CampaignID)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			w = &found
+		}
+		if windows != nil {
+			windows[req.CampaignID] = w
+		}
+	}
+	return w, nil
+}
+
+// priority defaults transactional templates to the high lane.
+func priority(req domain.SendSMSRequest, tpl store.Template) string {
+	switch {
+	case req.Priority != "":
+		return req.Priority
+	case tpl.Transactional:
+		return domain.PriorityHigh
+	default:
+		return domain.PriorityNormal
+	}
+}
+
+// priorityRank orders the outbox so high priority rows are published first during a backlog.
+func priorityRank(p string) int {
+	switch p {
+	case domain.PriorityHigh:
+		return 2
+	case domain.PriorityLow:
+		return 0
+	default:
+		return 1
+	}
+}
+
+// checkTiming rejects a sendAt beyond MaxScheduleAhead and an expiry that has already passed or
+// comes before sendAt.
+func checkTiming(req domain.SendSMSRequest, now time.Time) error {
+	if req.SendAt != nil && req.SendAt.Sub(now) > domain.MaxScheduleAhead {
+		return domain.ErrSendAtTooFar
+	}
+	if exp := req.Expiry(now); exp != nil {
+		if !exp.After(now) || (req.SendAt != nil && !exp.After(*req.SendAt)) {
+			return domain.ErrInvalidExpiry
+		}
+	}
+	return nil
+}
+
+// IsRejection reports whether err from CreateAndEnqueueSMS is the caller's fault (bad number or
+// template problems) rather than a dependency failure.
+func IsRejection(err error) bool {
+	return errors.Is(err, domain.ErrInvalidPhone) ||
+		errors.Is(err, domain.ErrSendAtTooFar) ||
+		errors.Is(err, domain.ErrInvalidExpiry) ||
+		errors.Is(err, domain.ErrExpiresBeforeWindow) ||
+		errors.Is(err, domain.ErrInvalidPriority) ||
+		errors.Is(err, domain.ErrUnknownTemplate) ||
+		errors.Is(err, templates.ErrMissingVars) ||
+		errors.Is(err, templates.ErrInvalidValue) ||
+		errors.Is(err, templates.ErrTooManySegments) ||
+		errors.Is(err, templates.ErrSyntax)
+}
+
+// releaseCap is best effort: it only matters if the insert that consumed the slot failed.
+func (s *NotificationService) releaseCap(ctx context.Context, req domain.SendSMSRequest, pol store.CountryPolicy, now time.Time) {
+	_ = s.Store.ReleaseDailyCap(ctx, req.TenantID, req.To, now)
+	if country := phone.Region(req.To); pol.CountryDailyCaps[country] > 0 {
+		_ = s.Store.ReleaseCountryCap(ctx, req.TenantID, country, now)
+	}
+}
+
+func messageInsert(req domain.SendSMSRequest, messageID string, state domain.MessageState, reason string, sendAt *time.Time, now time.Time) store.MessageInsert {
+	in := store.MessageInsert{
+		ID:         messageID,
+		TenantID:   req.TenantID,
+		IdemKey:    req.IdempotencyKey,
+		To:         req.To,
+		Country:    phone.Region(req.To),
+		TemplateID: req.TemplateID,
+		Vars:       req.Vars,
+		CampaignID: req.CampaignID,
+		State:      string(state),
+		LastError:  reason,
+		Now:        now,
+		SendAt:     sendAt,
+		ExpiresAt:  req.Expiry(now),
+		Priority:   priorityRank(req.Priority),
+	}
+	if state.Pending() {
+		in.OutboxPayload = sqsqueue.SMSJob{
+			TenantID: req.TenantID, MessageID: messageID, IdempotencyKey: req.IdempotencyKey,
+			To: req.To, TemplateID: req.TemplateID, Vars: req.Vars, CampaignID: req.CampaignID,
+			SendAt: sendAt, Priority: req.Priority,
+		}
+	}
+	return in
+}
+
+// GetMessage fetches a message owned by tenantID. An empty tenantID (auth disabled) is unscoped.
+func (s *NotificationService) GetMessage(ctx context.Context, tenantID, msgID string) (store.Message, bool, error) {
+	return s.Store.GetMessage(ctx, tenantID, msgID)
+}
+
+// Cancel withdraws a scheduled or queued message owned by tenantID (unscoped when empty) and
+// gives back the cap slots it consumed. It returns ErrMessageNotFound or ErrNotCancelable.
+func (s *NotificationService) Cancel(ctx context.Context, tenantID, msgID string, now time.Time) (domain.CreateResponse, error) {
+	ok, err := s.Store.CancelMessage(ctx, tenantID, msgID, now)
+	if err != nil {
 		return domain.CreateResponse{}, err
 	}
-	observability.Enqueues.WithLabelValues("ok").Inc()
+	msg, found, err := s.Store.GetMessage(ctx, tenantID, msgID)
+	if err != nil {
+		return domain.CreateResponse{}, err
+	}
+	if !found {
+		return domain.CreateResponse{}, domain.ErrMessageNotFound
+	}
+	if !ok {
+		return domain.CreateResponse{}, domain.ErrNotCancelable
+	}
+
+	// Caps are counted on the day the message was accepted.
+	_ = s.Store.ReleaseDailyCap(ctx, msg.TenantID, msg.ToPhone, msg.CreatedAt)
+	if msg.Country != "" {
+		pol, _, err := s.Store.GetCountryPolicy(ctx, msg.TenantID)
+		if err == nil && pol.CountryDailyCaps[msg.Country] > 0 {
+			_ = s.Store.ReleaseCountryCap(ctx, msg.TenantID, msg.Country, msg.CreatedAt)
+		}
+	}
+	return domain.CreateResponse{MessageID: msg.ID, State: msg.State, ScheduledAt: msg.ScheduledAt}, nil
+}
+
+const (
+	DefaultListLimit = 50
+	MaxListLimit     = 200
+)
+
+// MessagePage is one page of a message listing. NextCursor is empty on the last page.
+type MessagePage struct {
+	Messages   []store.Message `json:"messages"`
+	NextCursor string          `json:"nextCursor,omitempty"`
+}
 
-	return domain.CreateResponse{MessageID: messageID, State: string(domain.StateQueued)}, nil
+// ListMessages returns messages matching f, newest first. f.BeforeID is the cursor from a
+// previous page's NextCursor.
+func (s *NotificationService) ListMessages(ctx context.Context, f store.MessageFilter) (MessagePage, error) {
+	if f.Limit <= 0 {
+		f.Limit = DefaultListLimit
+	}
+	if f.Limit > MaxListLimit {
+		f.Limit = MaxListLimit
+	}
+	want := f.Limit
+	// Fetch one extra row to learn whether another page exists.
+	f.Limit++
+	msgs, err := s.Store.ListMessages(ctx, f)
+	if err != nil {
+		return MessagePage{}, err
+	}
+	page := MessagePage{Messages: msgs}
+	if len(msgs) > want {
+		page.Messages = msgs[:want]
+		page.NextCursor = page.Messages[want-1].ID
+	}
+	return page, nil
 }
 
-func (s *NotificationService) GetMessage(ctx context.Context, msgID string) (store.Message, bool, error) {
-	return s.Store.GetMessage(ctx, msgID)
+// Timeline reconstructs what happened to a message: acceptance (and the suppression decision,
+// which is made before insert), worker claims, provider attempts and delivery receipts.
+func (s *NotificationService) Timeline(ctx context.Context, tenantID, msgID string) ([]domain.TimelineEvent, bool, error) {
+	msg, found, err := s.Store.GetMessage(ctx, tenantID, msgID)
+	if err != nil || !found {
+		return nil, found, err
+	}
+	history, err := s.Store.ListMessageHistory(ctx, msgID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	events := make([]domain.TimelineEvent, 0, len(history)+3)
+	events = append(events, domain.TimelineEvent{Type: domain.EventAccepted, At: msg.CreatedAt})
+	if msg.ScheduledAt != nil {
+		events = append(events, domain.TimelineEvent{
+			Type:        domain.EventScheduled,
+			At:          msg.CreatedAt,
+			State:       string(domain.StateScheduled),
+			ScheduledAt: msg.ScheduledAt,
+		})
+	}
+	if msg.State == string(domain.StateSuppressed) {
+		events = append(events, domain.TimelineEvent{
+			Type:   domain.EventSuppressed,
+			At:     msg.CreatedAt,
+			State:  msg.State,
+			Reason: msg.LastError,
+		})
+	}
+	for _, h := range history {
+		ev := domain.TimelineEvent{
+			At:            h.At,
+			Provider:      h.Provider,
+			ProviderMsgID: h.ProviderMsgID,
+			HTTPStatus:    h.HTTPStatus,
+			ErrorCode:     h.ErrorCode,
+			FailureReason: h.FailureReason,
+			Error:         h.ErrorMsg,
+			LatencyMs:     h.LatencyMs,
+			VendorStatus:  h.VendorStatus,
+		}
+		switch h.Kind {
+		case "claimed":
+			ev.Type = domain.EventClaimed
+			ev.State = h.Detail
+		case "canceled":
+			ev.Type = domain.EventCanceled
+			ev.State = string(domain.StateCanceled)
+		case "expired":
+			ev.Type = domain.EventExpired
+			ev.State = string(domain.StateExpired)
+		case "deferred":
+			// Held again by the worker because the delivery window had closed; detail is the release time.
+			ev.Type = domain.EventScheduled
+			ev.State = string(domain.StateScheduled)
+			if at, err := time.Parse(time.RFC3339, h.Detail); err == nil {
+				ev.ScheduledAt = &at
+			}
+		case "attempt":
+			ev.Type = domain.EventProviderAttempt
+		case "delivery":
+			ev.Type = domain.EventDeliveryStatus
+		default:
+			continue
+		}
+		events = append(events, ev)
+	}
+	return events, true, nil
 }
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"notif/internal/domain"
+	"notif/internal/store"
+)
+
+type RateLimitStore interface {
+	UpsertTenantRateLimit(ctx context.Context, in store.TenantRateLimit) (bool, error)
+	GetTenantRateLimit(ctx context.Context, tenantID string) (store.TenantRateLimit, bool, error)
+	DeleteTenantRateLimit(ctx context.Context, tenantID string) (bool, error)
+}
+
+// RateLimitService manages per-tenant send rates. Workers cache limits briefly, so changes take
+// effect within their cache TTL.
+type RateLimitService struct {
+	Store RateLimitStore
+}
+
+func (s *RateLimitService) Put(ctx context.Context, tenantID string, req domain.PutTenantRateLimitRequest, now time.Time) (domain.TenantRateLimit, error) {
+	l := store.TenantRateLimit{TenantID: tenantID, PerSecond: req.PerSecond, Burst: req.Burst, UpdatedAt: now}
+	ok, err := s.Store.UpsertTenantRateLimit(ctx, l)
+	if err != nil {
+		return domain.TenantRateLimit{}, err
+	}
+	if !ok {
+		return domain.TenantRateLimit{}, domain.ErrTenantNotFound
+	}
+	return toDomainRateLimit(l), nil
+}
+
+func (s *RateLimitService) Get(ctx context.Context, tenantID string) (domain.TenantRateLimit, error) {
+	l, found, err := s.Store.GetTenantRateLimit(ctx, tenantID)
+	if err != nil {
+		return domain.TenantRateLimit{}, err
+	}
+	if !found {
+		return domain.TenantRateLimit{}, domain.ErrRateLimitNotFound
+	}
+	return toDomainRateLimit(l), nil
+}
+
+// Delete removes the override; the tenant falls back to the workers' default limit.
+func (s *RateLimitService) Delete(ctx context.Context, tenantID string) error {
+	ok, err := s.Store.DeleteTenantRateLimit(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return domain.ErrRateLimitNotFound
+	}
+	return nil
+}
+
+func toDomainRateLimit(l store.TenantRateLimit) domain.TenantRateLimit {
+	return domain.TenantRateLimit{TenantID: l.TenantID, PerSecond: l.PerSecond, Burst: l.Burst, UpdatedAt: l.UpdatedAt}
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"notif/internal/domain"
+	"notif/internal/phone"
+	"notif/internal/store"
+)
+
+type SuppressionStore interface {
+	UpsertSuppressions(ctx context.Context, in []store.Suppression) (int, error)
+	DeleteSuppression(ctx context.Context, tenantID, phone string) (bool, error)
+	GetSuppression(ctx context.Context, tenantID, phone string) (store.Suppression, bool, error)
+	ListSuppressions(ctx context.Context, f store.SuppressionFilter) ([]store.Suppression, error)
+}
+
+// SuppressionService manages a tenant's suppression list. Suppressed numbers are never sent to,
+// until the suppression is removed or its expiry passes.
+type SuppressionService struct {
+	Store SuppressionStore
+}
+
+func (s *SuppressionService) Put(ctx context.Context, tenantID, phoneNum string, req domain.PutSuppressionRequest, now time.Time) (domain.Suppression, error) {
+	sp, err := suppressionRow(tenantID, phoneNum, req, now)
+	if err != nil {
+		return domain.Suppression{}, err
+	}
+	if _, err := s.Store.UpsertSuppressions(ctx, []store.Suppression{sp}); err != nil {
+		return domain.Suppression{}, err
+	}
+	return toDomainSuppression(sp), nil
+}
+
+func (s *SuppressionService) Import(ctx context.Context, req domain.ImportSuppressionsRequest, now time.Time) (int, error) {
+	rows := make([]store.Suppression, len(req.Items))
+	for i, it := range req.Items {
+		row, err := suppressionRow(req.TenantID, it.Phone, it.PutSuppressionRequest, now)
+		if err != nil {
+			return 0, fmt.Errorf("item %d: %w", i, err)
+		}
+		rows[i] = row
+	}
+	return s.Store.UpsertSuppressions(ctx, rows)
+}
+
+func (s *SuppressionService) Delete(ctx context.Context, tenantID, phoneNum string) error {
+	ok, err := s.Store.DeleteSuppression(ctx, tenantID, phone.Normalize(phoneNum))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return domain.ErrSuppressionNotFound
+	}
+	return nil
+}
+
+// Get returns the suppression for phone; an expired one is still returned so callers can see it lapsed.
+func (s *SuppressionService) Get(ctx context.Context, tenantID, phoneNum string) (domain.Suppression, error) {
+	sp, found, err := s.Store.GetSuppression(ctx, tenantID, phone.Normalize(phoneNum))
+	if err != nil {
+		return domain.Suppression{}, err
+	}
+	if !found {
+		return domain.Suppression{}, domain.ErrSuppressionNotFound
+	}
+	return toDomainSuppression(sp), nil
+}
+
+// List pages through suppressions in phone order. f.AfterPhone is the previous page's NextCursor.
+func (s *SuppressionService) List(ctx context.Context, f store.SuppressionFilter) (domain.SuppressionPage, error) {
+	if f.Limit <= 0 {
+		f.Limit = DefaultListLimit
+	}
+	if f.Limit > MaxListLimit {
+		f.Limit = MaxListLimit
+	}
+	want := f.Limit
+	f.Limit++
+	rows, err := s.Store.ListSuppressions(ctx, f)
+	if err != nil {
+		return domain.SuppressionPage{}, err
+	}
+	page := domain.SuppressionPage{Suppressions: make([]domain.Suppression, 0, len(rows))}
+	for i, sp := range rows {
+		if i == want {
+			page.NextCursor = rows[want-1].Phone
+			break
+		}
+		page.Suppressions = append(page.Suppressions, toDomainSuppression(sp))
+	}
+	return page, nil
+}
+
+func suppressionRow(tenantID, phoneNum string, req domain.PutSuppressionRequest, now time.Time) (store.Suppression, error) {
+	num, err := phone.Parse(phoneNum)
+	if err != nil {
+		return store.Suppression{}, domain.ErrInvalidPhone
+	}
+	if err := req.Validate(now); err != nil {
+		return store.Suppression{}, err
+	}
+	reason := req.Reason
+	if reason == "" {
+		reason = domain.DefaultSuppressionReason
+	}
+	return store.Suppression{
+		TenantID:  tenantID,
+		Phone:     num.E164,
+		Reason:    reason,
+		CreatedAt: now,
+		ExpiresAt: req.Expiry(now),
+	}, nil
+}
+
+func toDomainSuppression(sp store.Suppression) domain.Suppression {
+	return domain.Suppression{
+		TenantID:  sp.TenantID,
+		Phone:     sp.Phone,
+		Reason:    sp.Reason,
+		CreatedAt: sp.CreatedAt,
+		ExpiresAt: sp.ExpiresAt,
+	}
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"notif/internal/domain"
+	"notif/internal/store"
+	"notif/internal/templates"
+)
+
+type TemplateStore interface {
+	InsertTemplateVersion(ctx context.Context, in store.Template, now time.Time) (store.Template, bool, error)
+	GetTemplateVersion(ctx context.Context, tenantID, templateID string, version int) (store.Template, bool, error)
+	ListTemplateVersions(ctx context.Context, tenantID, templateID string) ([]store.Template, error)
+	UpdateDraftTemplate(ctx context.Context, in store.Template, now time.Time) (bool, error)
+	SetTemplateStatus(ctx context.Context, tenantID, templateID string, version int, status string, now time.Time) (bool, error)
+	DeleteDraftTemplate(ctx context.Context, tenantID, templateID string, version int) (bool, error)
+}
+
+// TemplateService manages versioned templates. Versions start as drafts, are immutable once
+// published, and the most recently published version is the one used for sends.
+type TemplateService struct {
+	Store TemplateStore
+	// Invalidate, when set, drops a template from the local resolver cache after it changes.
+	Invalidate func(tenantID, templateID string)
+}
+
+func (s *TemplateService) Create(ctx context.Context, tenantID string, req domain.CreateTemplateRequest, now time.Time) (domain.Template, error) {
+	if err := validateTemplateBody(req.Body); err != nil {
+		return domain.Template{}, err
+	}
+	t, ok, err := s.Store.InsertTemplateVersion(ctx, store.Template{
+		TenantID:      tenantID,
+		TemplateID:    req.TemplateID,
+		Body:          req.Body,
+		MaxSegments:   req.MaxSegments,
+		Transliterate: req.Transliterate,
+		Transactional: req.Transactional,
+	}, now)
+	if err != nil {
+		return domain.Template{}, err
+	}
+	if !ok {
+		return domain.Template{}, domain.ErrTenantNotFound
+	}
+	return toDomainTemplate(t), nil
+}
+
+// List returns the latest version of each template, or every version of templateID when set.
+func (s *TemplateService) List(ctx context.Context, tenantID, templateID string) ([]domain.Template, error) {
+	rows, err := s.Store.ListTemplateVersions(ctx, tenantID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	if templateID != "" && len(rows) == 0 {
+		return nil, domain.ErrTemplateNotFound
+	}
+	out := make([]domain.Template, 0, len(rows))
+	for _, t := range rows {
+		out = append(out, toDomainTemplate(t))
+	}
+	return out, nil
+}
+
+func (s *TemplateService) Get(ctx context.Context, tenantID, templateID string, version int) (domain.Template, error) {
+	t, found, err := s.Store.GetTemplateVersion(ctx, tenantID, templateID, version)
+	if err != nil {
+		return domain.Template{}, err
+	}
+	if !found {
+		return domain.Template{}, domain.ErrTemplateNotFound
+	}
+	return toDomainTemplate(t), nil
+}
+
+func (s *TemplateService) UpdateDraft(ctx context.Context, tenantID, templateID string, version int, req domain.UpdateTemplateRequest, now time.Time) (domain.Template, error) {
+	if err := validateTemplateBody(req.Body); err != nil {
+		return domain.Template{}, err
+	}
+	ok, err := s.Store.UpdateDraftTemplate(ctx, store.Template{
+		TenantID:      tenantID,
+		TemplateID:    templateID,
+		Version:       version,
+		Body:          req.Body,
+		MaxSegments:   req.MaxSegments,
+		Transliterate: req.Transliterate,
+		Transactional: req.Transactional,
+	}, now)
+	if err != nil {
+		return domain.Template{}, err
+	}
+	if !ok {
+		// Distinguish a missing version from a published/archived one.
+		if _, err := s.Get(ctx, tenantID, templateID, version); err != nil {
+			return domain.Template{}, err
+		}
+		return domain.Template{}, domain.ErrTemplateNotDraft
+	}
+	return s.Get(ctx, tenantID, templateID, version)
+}
+
+// Publish makes version the one used for new sends. Publishing an older version rolls back to it.
+func (s *TemplateService) Publish(ctx context.Context, tenantID, templateID string, version int, now time.Time) (domain.Template, error) {
+	return s.setStatus(ctx, tenantID, templateID, version, domain.TemplatePublished, now)
+}
+
+// Delete removes a draft version; a published version is archived so message history keeps
+// pointing at a real body.
+func (s *TemplateService) Delete(ctx context.Context, tenantID, templateID string, version int, now time.Time) error {
+	ok, err := s.Store.DeleteDraftTemplate(ctx, tenantID, templateID, version)
+	if err != nil || ok {
+		return err
+	}
+	_, err = s.setStatus(ctx, tenantID, templateID, version, domain.TemplateArchived, now)
+	return err
+}
+
+func (s *TemplateService) setStatus(ctx context.Context, tenantID, templateID string, version int, status domain.TemplateStatus, now time.Time) (domain.Template, error) {
+	ok, err := s.Store.SetTemplateStatus(ctx, tenantID, templateID, version, string(status), now)
+	if err != nil {
+		return domain.Template{}, err
+	}
+	if !ok {
+		return domain.Template{}, domain.ErrTemplateNotFound
+	}
+	if s.Invalidate != nil {
+		s.Invalidate(tenantID, templateID)
+	}
+	return s.Get(ctx, tenantID, templateID, version)
+}
+
+func validateTemplateBody(body string) error {
+	if _, err := templates.Parse(body); err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrInvalidTemplate, err)
+	}
+	return nil
+}
+
+func toDomainTemplate(t store.Template) domain.Template {
+	var required []string
+	if parsed, err := templates.Parse(t.Body); err == nil {
+		required = parsed.Required()
+	}
+	return domain.Template{
+		RequiredVars:  required,
+		MaxSegments:   t.MaxSegments,
+		Transliterate: t.Transliterate,
+		Transactional: t.Transactional,
+		TenantID:      t.TenantID,
+		TemplateID:    t.TemplateID,
+		Version:       t.Version,
+		Body:          t.Body,
+		Status:        domain.TemplateStatus(t.Status),
+		CreatedAt:     t.CreatedAt,
+		UpdatedAt:     t.UpdatedAt,
+		PublishedAt:   t.PublishedAt,
+	}
+}
@@ -0,0 +1,109 @@
+// Package sms analyzes message bodies the way carriers bill them: by encoding and segment.
+package sms
+
+import (
+	"strings"
+	"unicode/utf16"
+)
+
+type Encoding string
+
+const (
+	GSM7 Encoding = "GSM-7"
+	UCS2 Encoding = "UCS-2"
+)
+
+// Segment capacities. A concatenated message spends 6 bytes of each segment on the UDH, leaving
+// 153 septets (GSM-7) or 67 UTF-16 code units (UCS-2).
+const (
+	gsmSingle  = 160
+	gsmMulti   = 153
+	ucs2Single = 70
+	ucs2Multi  = 67
+)
+
+// gsmBasic is the GSM 03.38 default alphabet; each character costs one septet.
+const gsmBasic = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?" +
+	"¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+
+// gsmExtension characters are sent as ESC + char and cost two septets.
+const gsmExtension = "\f^{}\\[~]|€"
+
+var gsmCost = func() map[rune]int {
+	m := make(map[rune]int, len(gsmBasic)+len(gsmExtension))
+	for _, r := range gsmBasic {
+		m[r] = 1
+	}
+	for _, r := range gsmExtension {
+		m[r] = 2
+	}
+	return m
+}()
+
+// Info describes how a body will be sent.
+type Info struct {
+	Encoding Encoding
+	// Units is the body length in septets (GSM-7) or UTF-16 code units (UCS-2).
+	Units    int
+	Segments int
+}
+
+// Analyze picks GSM-7 when every character is in the GSM alphabet (including the extension
+// table), otherwise UCS-2, and counts segments. Multi-byte units (an escaped GSM character or a
+// UTF-16 surrogate pair) are never split across segments, as handsets would garble them.
+func Analyze(body string) Info {
+	if body == "" {
+		return Info{Encoding: GSM7}
+	}
+	costs := make([]int, 0, len(body))
+	enc := GSM7
+	for _, r := range body {
+		c, ok := gsmCost[r]
+		if !ok {
+			enc = UCS2
+			break
+		}
+		costs = append(costs, c)
+	}
+	single, multi := gsmSingle, gsmMulti
+	if enc == UCS2 {
+		costs = costs[:0]
+		for _, r := range body {
+			costs = append(costs, utf16.RuneLen(r))
+		}
+		single, multi = ucs2Single, ucs2Multi
+	}
+
+	units := 0
+	for _, c := range costs {
+		units += c
+	}
+	if units <= single {
+		return Info{Encoding: enc, Units: units, Segments: 1}
+	}
+	segments, used := 1, 0
+	for _, c := range costs {
+		if used+c > multi {
+			segments++
+			used = 0
+		}
+		used += c
+	}
+	return Info{Encoding: enc, Units: units, Segments: segments}
+}
+
+var transliterations = strings.NewReplacer(
+	"‘", "'", "’", "'", "‚", "'", "‛", "'", "′", "'", "´", "'", "`", "'",
+	"“", "\"", "”", "\"", "„", "\"", "‟", "\"", "″", "\"", "«", "\"", "»", "\"",
+	"‐", "-", "‑", "-", "‒", "-", "–", "-", "—", "-", "―", "-", "−", "-",
+	"…", "...", "•", "-",
+	" ", " ", " ", " ", " ", " ", " ", " ", "​", "",
+	"á", "a", "í", "i", "ó", "o", "ú", "u", "ç", "c", "ê", "e", "â", "a",
+)
+
+// Transliterate replaces common typographic characters (smart quotes, dashes, ellipses, odd
+// spaces, a few accented letters) with GSM-7 equivalents so a body does not fall back to UCS-2.
+// Other characters are left alone.
+func Transliterate(body string) string {
+	return transliterations.Replace(body)
+}
@@ -0,0 +1,52 @@
+package sms
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	cases := []struct {
+		name     string
+		body     string
+		enc      Encoding
+		units    int
+		segments int
+	}{
+		{"empty", "", GSM7, 0, 0},
+		{"single gsm", strings.Repeat("a", 160), GSM7, 160, 1},
+		{"two gsm", strings.Repeat("a", 161), GSM7, 161, 2},
+		{"three gsm", strings.Repeat("a", 307), GSM7, 307, 3},
+		{"extension chars cost two", strings.Repeat("€", 80), GSM7, 160, 1},
+		{"extension not split", strings.Repeat("a", 152) + "€" + strings.Repeat("a", 152), GSM7, 306, 3},
+		{"ucs2 single", strings.Repeat("é", 10) + "ł", UCS2, 11, 1},
+		{"ucs2 boundary", strings.Repeat("ł", 70), UCS2, 70, 1},
+		{"ucs2 two", strings.Repeat("ł", 71), UCS2, 71, 2},
+		{"emoji surrogate pair", "hi 😀", UCS2, 5, 1},
+		{"surrogate not split", strings.Repeat("ł", 66) + "😀" + strings.Repeat("ł", 4), UCS2, 72, 2},
+		{"smart quote forces ucs2", "it’s", UCS2, 4, 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Analyze(tc.body)
+			want := Info{Encoding: tc.enc, Units: tc.units, Segments: tc.segments}
+			if tc.body == "" {
+				want = Info{Encoding: GSM7}
+			}
+			if got != want {
+				t.Fatalf("expected %+v, got %+v", want, got)
+			}
+		})
+	}
+}
+
+func TestTransliterateKeepsGSM(t *testing.T) {
+	in := "“Hello” – it’s… done"
+	out := Transliterate(in)
+	if out != "\"Hello\" - it's... done" {
+		t.Fatalf("unexpected transliteration %q", out)
+	}
+	if Analyze(out).Encoding != GSM7 {
+		t.Fatalf("expected GSM-7 after transliteration")
+	}
+}
@@ -0,0 +1,38 @@
+package sms
+
+import "strings"
+
+// Keyword is a carrier-standard command a recipient can text to any sender.
+type Keyword string
+
+const (
+	KeywordStop  Keyword = "stop"
+	KeywordStart Keyword = "start"
+	KeywordHelp  Keyword = "help"
+)
+
+// keywords follows the CTIA list (and Twilio's default opt-out handling), so a number opted out
+// with the provider is opted out with us too.
+var keywords = map[string]Keyword{
+	"STOP":        KeywordStop,
+	"STOPALL":     KeywordStop,
+	"UNSUBSCRIBE": KeywordStop,
+	"CANCEL":      KeywordStop,
+	"END":         KeywordStop,
+	"QUIT":        KeywordStop,
+	"OPTOUT":      KeywordStop,
+	"REVOKE":      KeywordStop,
+	"START":       KeywordStart,
+	"UNSTOP":      KeywordStart,
+	"YES":         KeywordStart,
+	"HELP":        KeywordHelp,
+	"INFO":        KeywordHelp,
+}
+
+// ParseKeyword recognizes a body consisting of a single keyword, ignoring case, surrounding
+// whitespace and trailing punctuation ("Stop." counts, "please stop" does not). It returns "" for
+// anything else.
+func ParseKeyword(body string) Keyword {
+	word := strings.TrimRight(strings.TrimSpace(body), ".!?")
+	return keywords[strings.ToUpper(strings.TrimSpace(word))]
+}
@@ -0,0 +1,24 @@
+package sms
+
+import "testing"
+
+func TestParseKeyword(t *testing.T) {
+	cases := map[string]Keyword{
+		"STOP":         KeywordStop,
+		" stop\n":      KeywordStop,
+		"Unsubscribe.": KeywordStop,
+		"StopAll":      KeywordStop,
+		"start":        KeywordStart,
+		"UNSTOP!":      KeywordStart,
+		"help?":        KeywordHelp,
+		"info":         KeywordHelp,
+		"please stop":  "",
+		"stopped":      "",
+		"":             "",
+	}
+	for body, want := range cases {
+		if got := ParseKeyword(body); got != want {
+			t.Fatalf("ParseKeyword(%q): expected %q, got %q", body, want, got)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package pg
+
+import (
+	"context"
+	"time"
+
+	"notif/internal/store"
+)
+
+// InsertAPIKey returns false if the tenant does not exist.
+func (s *Store) InsertAPIKey(ctx context.Context, in store.APIKeyInsert) (bool, error) {
+	ct, err := s.DB.Exec(ctx, `
+		INSERT INTO api_keys (id, tenant_id, name, key_prefix, key_hash, created_at)
+		SELECT $1,$2,$3,$4,$5,$6 WHERE EXISTS (SELECT 1 FROM tenants WHERE id=$2)
+	`, in.ID, in.TenantID, in.Name, in.Prefix, in.Hash, in.Now)
+	if err != nil {
+		return false, err
+	}
+	return ct.RowsAffected() > 0, nil
+}
+
+// FindActiveAPIKeyByHash returns the key if it exists and is not revoked as of now.
+func (s *Store) FindActiveAPIKeyByHash(ctx context.Context, hash string, now time.Time) (store.APIKey, bool, error) {
+	row := s.DB.QueryRow(ctx, `
+		SELECT id, tenant_id, name, key_prefix, created_at, revoked_at
+		FROM api_keys WHERE key_hash=$1 AND (revoked_at IS NULL OR revoked_at > $2)
+	`, hash, now)
+	var k store.APIKey
+	err := row.Scan(&k.ID, &k.TenantID, &k.Name, &k.Prefix, &k.CreatedAt, &k.RevokedAt)
+	if err != nil {
+		if err.Error() == "no rows in result set" {
+			return store.APIKey{}, false, nil
+		}
+		return store.APIKey{}, false, err
+	}
+	return k, true, nil
+}
+
+func (s *Store) ListAPIKeys(ctx context.Context, tenantID string) ([]store.APIKey, error) {
+	rows, err := s.DB.Query(ctx, `
+		SELECT id, tenant_id, name, key_prefix, created_at, revoked_at
+		FROM api_keys WHERE tenant_id=$1 ORDER BY created_at
+	`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []store.APIKey
+	for rows.Next() {
+		var k store.APIKey
+		if err := rows.Scan(&k.ID, &k.TenantID, &k.Name, &k.Prefix, &k.CreatedAt, &k.RevokedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, k)
+	}
+	return out, rows.Err()
+}
+
+// RevokeAPIKey makes the key stop working at revokeAt. An earlier existing revocation is kept.
+func (s *Store) RevokeAPIKey(ctx context.Context, tenantID, keyID string, revokeAt time.Time) (bool, error) {
+	ct, err := s.DB.Exec(ctx, `
+		UPDATE api_keys SET revoked_at = LEAST(COALESCE(revoked_at, $3), $3)
+		WHERE tenant_id=$1 AND id=$2
+	`, tenantID, keyID, revokeAt)
+	if err != nil {
+		return false, err
+	}
+	return ct.RowsAffected() > 0, nil
+}
+
+// RotateAPIKey inserts the replacement key and schedules the old key's revocation in one transaction.
+// The new key inherits the old key's name, which is returned. ok is false if the old key does not
+// exist (or is already revoked) for the tenant.
+func (s *Store) RotateAPIKey(ctx context.Context, oldKeyID string, in store.APIKeyInsert, revokeOldAt time.Time) (name string, ok bool, err error) {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	ct, err := tx.Exec(ctx, `
+		UPDATE api_keys SET revoked_at=$3
+		WHERE tenant_id=$1 AND id=$2 AND (revoked_at IS NULL OR revoked_at > $3)
+	`, in.TenantID, oldKeyID, revokeOldAt)
+	if err != nil {
+		return "", false, err
+	}
+	if ct.RowsAffected() == 0 {
+		return "", false, nil
+	}
+	if err := tx.QueryRow(ctx, `
+		INSERT INTO api_keys (id, tenant_id, name, key_prefix, key_hash, created_at)
+		SELECT $1,$2,name,$4,$5,$6 FROM api_keys WHERE id=$3
+		RETURNING name
+	`, in.ID, in.TenantID, oldKeyID, in.Prefix, in.Hash, in.Now).Scan(&name); err != nil {
+		return "", false, err
+	}
+	return name, true, tx.Commit(ctx)
+}
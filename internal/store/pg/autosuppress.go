@@ -0,0 +1,71 @@
+package pg
+
+import (
+	"context"
+	"time"
+
+	"notif/internal/store"
+)
+
+// RecordDeliveryFailure extends the failure streak of the number the provider message was sent
+// to, or starts a new one when the reason differs. A repeated callback for the same provider
+// message doesn't count again. found is false when no message has that provider message ID.
+func (s *Store) RecordDeliveryFailure(ctx context.Context, provider, providerMsgID, reason string, now time.Time) (store.FailureStreak, bool, error) {
+	var st store.FailureStreak
+	err := s.DB.QueryRow(ctx, `
+		INSERT INTO delivery_failure_streaks (tenant_id, phone, failure_reason, failures, last_provider_msg_id, updated_at)
+		SELECT tenant_id, to_phone, $3, 1, $2, $4
+		FROM messages WHERE provider=$1 AND provider_msg_id=$2
+		LIMIT 1
+		ON CONFLICT (tenant_id, phone) DO UPDATE SET
+			failures = CASE
+				WHEN delivery_failure_streaks.failure_reason <> EXCLUDED.failure_reason THEN 1
+				WHEN delivery_failure_streaks.last_provider_msg_id = EXCLUDED.last_provider_msg_id THEN delivery_failure_streaks.failures
+				ELSE delivery_failure_streaks.failures + 1
+			END,
+			failure_reason = EXCLUDED.failure_reason,
+			last_provider_msg_id = EXCLUDED.last_provider_msg_id,
+			updated_at = EXCLUDED.updated_at
+		RETURNING tenant_id, phone, failure_reason, failures
+	`, provider, providerMsgID, reason, now).Scan(&st.TenantID, &st.Phone, &st.FailureReason, &st.Failures)
+	if err != nil {
+		if err.Error() == "no rows in result set" {
+			return store.FailureStreak{}, false, nil
+		}
+		return store.FailureStreak{}, false, err
+	}
+	return st, true, nil
+}
+
+// ResetDeliveryFailures ends the failure streak of the number the provider message was sent to.
+func (s *Store) ResetDeliveryFailures(ctx context.Context, provider, providerMsgID string) error {
+	_, err := s.DB.Exec(ctx, `
+		DELETE FROM delivery_failure_streaks
+		WHERE (tenant_id, phone) IN (
+			SELECT tenant_id, to_phone FROM messages WHERE provider=$1 AND provider_msg_id=$2
+		)
+	`, provider, providerMsgID)
+	return err
+}
+
+// AutoSuppress adds a suppression unless the number already has one at least as strong: an
+// existing permanent suppression, or a temporary one lasting longer, is left alone. Either way
+// the number's failure streak ends in the same statement, so failures after a temporary
+// suppression lapses count from one again.
+func (s *Store) AutoSuppress(ctx context.Context, sp store.Suppression) (bool, error) {
+	ct, err := s.DB.Exec(ctx, `
+		WITH reset AS (
+			DELETE FROM delivery_failure_streaks WHERE tenant_id=$1 AND phone=$2
+		)
+		INSERT INTO suppression_list (tenant_id, phone, reason, created_at, expires_at)
+		VALUES ($1,$2,$3,$4,$5)
+		ON CONFLICT (tenant_id, phone) DO UPDATE
+		SET reason=EXCLUDED.reason, created_at=EXCLUDED.created_at, expires_at=EXCLUDED.expires_at
+		WHERE suppression_list.expires_at IS NOT NULL
+		  AND (EXCLUDED.expires_at IS NULL OR EXCLUDED.expires_at > suppression_list.expires_at)
+	`, sp.TenantID, sp.Phone, sp.Reason, sp.CreatedAt, sp.ExpiresAt)
+	if err != nil {
+		return false, err
+	}
+	return ct.RowsAffected() > 0, nil
+}
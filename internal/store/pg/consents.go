@@ -0,0 +1,100 @@
+package pg
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+
+	"notif/internal/store"
+)
+
+// ApplyConsentChanges upserts (or deletes) consents and records each change in consent_events,
+// all in one transaction and one round trip. It returns how many changes touched a consent
+// record; deleting a consent that does not exist is neither counted nor recorded.
+func (s *Store) ApplyConsentChanges(ctx context.Context, changes []store.ConsentChange) (int, error) {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	batch := &pgx.Batch{}
+	for _, c := range changes {
+		// Each statement changes the consent and appends the history row only if it did.
+		change := `
+			WITH changed AS (
+				INSERT INTO consents (tenant_id, phone, channel, status, updated_at)
+				VALUES ($1,$2,$3,$4,$7)
+				ON CONFLICT (tenant_id, phone, channel)
+				DO UPDATE SET status=EXCLUDED.status, updated_at=EXCLUDED.updated_at
+				RETURNING 1
+			)`
+		if c.Status == "deleted" {
+			change = `
+			WITH changed AS (
+				DELETE FROM consents WHERE tenant_id=$1 AND phone=$2 AND channel=$3
+				RETURNING 1
+			)`
+		}
+		batch.Queue(change+`
+			INSERT INTO consent_events (tenant_id, phone, channel, status, source, actor, created_at)
+			SELECT $1::text, $2::text, $3::text, $4::text, $5::text, $6::text, $7::timestamptz FROM changed
+		`, c.TenantID, c.Phone, c.Channel, c.Status, c.Source, nullIfEmpty(c.Actor), c.Now)
+	}
+
+	br := tx.SendBatch(ctx, batch)
+	applied := 0
+	for range changes {
+		ct, err := br.Exec()
+		if err != nil {
+			_ = br.Close()
+			return 0, err
+		}
+		applied += int(ct.RowsAffected())
+	}
+	if err := br.Close(); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return applied, nil
+}
+
+func (s *Store) GetConsent(ctx context.Context, tenantID, phone, channel string) (store.Consent, bool, error) {
+	row := s.DB.QueryRow(ctx, `
+		SELECT tenant_id, phone, channel, status, updated_at
+		FROM consents WHERE tenant_id=$1 AND phone=$2 AND channel=$3
+	`, tenantID, phone, channel)
+	var c store.Consent
+	err := row.Scan(&c.TenantID, &c.Phone, &c.Channel, &c.Status, &c.UpdatedAt)
+	if err != nil {
+		if err.Error() == "no rows in result set" {
+			return store.Consent{}, false, nil
+		}
+		return store.Consent{}, false, err
+	}
+	return c, true, nil
+}
+
+// ListConsentEvents returns the consent history for a phone, oldest first.
+func (s *Store) ListConsentEvents(ctx context.Context, tenantID, phone, channel string) ([]store.ConsentEvent, error) {
+	rows, err := s.DB.Query(ctx, `
+		SELECT status, source, COALESCE(actor,''), created_at
+		FROM consent_events WHERE tenant_id=$1 AND phone=$2 AND channel=$3
+		ORDER BY created_at, id
+	`, tenantID, phone, channel)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []store.ConsentEvent
+	for rows.Next() {
+		var e store.ConsentEvent
+		if err := rows.Scan(&e.Status, &e.Source, &e.Actor, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
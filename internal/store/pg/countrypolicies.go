@@ -0,0 +1,92 @@
+package pg
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"notif/internal/store"
+)
+
+// UpsertCountryPolicy replaces the tenant's policy. It returns false if the tenant does not exist.
+func (s *Store) UpsertCountryPolicy(ctx context.Context, in store.CountryPolicy) (bool, error) {
+	caps, _ := json.Marshal(in.CountryDailyCaps)
+	ct, err := s.DB.Exec(ctx, `
+		INSERT INTO country_policies (tenant_id, allowed_countries, blocked_prefixes, country_daily_caps, updated_at)
+		SELECT $1,$2,$3,$4,$5 WHERE EXISTS (SELECT 1 FROM tenants WHERE id=$1)
+		ON CONFLICT (tenant_id) DO UPDATE SET
+		  allowed_countries=EXCLUDED.allowed_countries,
+		  blocked_prefixes=EXCLUDED.blocked_prefixes,
+		  country_daily_caps=EXCLUDED.country_daily_caps,
+		  updated_at=EXCLUDED.updated_at
+	`, in.TenantID, nonNilStrings(in.AllowedCountries), nonNilStrings(in.BlockedPrefixes), caps, in.UpdatedAt)
+	if err != nil {
+		return false, err
+	}
+	return ct.RowsAffected() > 0, nil
+}
+
+func (s *Store) GetCountryPolicy(ctx context.Context, tenantID string) (store.CountryPolicy, bool, error) {
+	var p store.CountryPolicy
+	var caps []byte
+	err := s.DB.QueryRow(ctx, `
+		SELECT tenant_id, allowed_countries, blocked_prefixes, country_daily_caps, updated_at
+		FROM country_policies WHERE tenant_id=$1
+	`, tenantID).Scan(&p.TenantID, &p.AllowedCountries, &p.BlockedPrefixes, &caps, &p.UpdatedAt)
+	if err != nil {
+		if err.Error() == "no rows in result set" {
+			return store.CountryPolicy{}, false, nil
+		}
+		return store.CountryPolicy{}, false, err
+	}
+	_ = json.Unmarshal(caps, &p.CountryDailyCaps)
+	return p, true, nil
+}
+
+func (s *Store) DeleteCountryPolicy(ctx context.Context, tenantID string) (bool, error) {
+	ct, err := s.DB.Exec(ctx, `DELETE FROM country_policies WHERE tenant_id=$1`, tenantID)
+	if err != nil {
+		return false, err
+	}
+	return ct.RowsAffected() > 0, nil
+}
+
+// IncrementCountryCap takes one of the tenant's daily slots for country. The row is only bumped
+// while under maxPerDay, so a refused send leaves the counter untouched.
+func (s *Store) IncrementCountryCap(ctx context.Context, tenantID, country string, day time.Time, maxPerDay int) (bool, error) {
+	d := day.UTC().Truncate(24 * time.Hour)
+	var n int
+	err := s.DB.QueryRow(ctx, `
+		INSERT INTO send_caps_country_daily (tenant_id, country, day, count, updated_at)
+		SELECT $1,$2,$3,1,now() WHERE $4 > 0
+		ON CONFLICT (tenant_id, country, day)
+		DO UPDATE SET count = send_caps_country_daily.count + 1, updated_at=now()
+		WHERE send_caps_country_daily.count < $4
+		RETURNING count
+	`, tenantID, country, d, maxPerDay).Scan(&n)
+	if err != nil {
+		if err.Error() == "no rows in result set" {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// ReleaseCountryCap gives back a slot taken by IncrementCountryCap.
+func (s *Store) ReleaseCountryCap(ctx context.Context, tenantID, country string, day time.Time) error {
+	d := day.UTC().Truncate(24 * time.Hour)
+	_, err := s.DB.Exec(ctx, `
+		UPDATE send_caps_country_daily SET count = GREATEST(count - 1, 0), updated_at=now()
+		WHERE tenant_id=$1 AND country=$2 AND day=$3
+	`, tenantID, country, d)
+	return err
+}
+
+// nonNilStrings keeps NOT NULL array columns from receiving a SQL NULL.
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
@@ -0,0 +1,71 @@
+package pg
+
+import (
+	"context"
+
+	"notif/internal/store"
+)
+
+const deliveryWindowColumns = `tenant_id, campaign_id, start_minute, end_minute, days, timezone, updated_at`
+
+func scanDeliveryWindow(row interface{ Scan(...any) error }) (store.DeliveryWindow, error) {
+	var w store.DeliveryWindow
+	err := row.Scan(&w.TenantID, &w.CampaignID, &w.StartMinute, &w.EndMinute, &w.Days, &w.Timezone, &w.UpdatedAt)
+	return w, err
+}
+
+// UpsertDeliveryWindow returns false if the tenant does not exist.
+func (s *Store) UpsertDeliveryWindow(ctx context.Context, in store.DeliveryWindow) (bool, error) {
+	ct, err := s.DB.Exec(ctx, `
+		INSERT INTO delivery_windows (tenant_id, campaign_id, start_minute, end_minute, days, timezone, updated_at)
+		SELECT $1,$2,$3,$4,$5,$6,$7 WHERE EXISTS (SELECT 1 FROM tenants WHERE id=$1)
+		ON CONFLICT (tenant_id, campaign_id) DO UPDATE SET
+		  start_minute=EXCLUDED.start_minute,
+		  end_minute=EXCLUDED.end_minute,
+		  days=EXCLUDED.days,
+		  timezone=EXCLUDED.timezone,
+		  updated_at=EXCLUDED.updated_at
+	`, in.TenantID, in.CampaignID, in.StartMinute, in.EndMinute, nonNilStrings(in.Days), in.Timezone, in.UpdatedAt)
+	if err != nil {
+		return false, err
+	}
+	return ct.RowsAffected() > 0, nil
+}
+
+// GetDeliveryWindow returns the window stored for exactly (tenantID, campaignID).
+func (s *Store) GetDeliveryWindow(ctx context.Context, tenantID, campaignID string) (store.DeliveryWindow, bool, error) {
+	w, err := scanDeliveryWindow(s.DB.QueryRow(ctx, `
+		SELECT `+deliveryWindowColumns+` FROM delivery_windows WHERE tenant_id=$1 AND campaign_id=$2
+	`, tenantID, campaignID))
+	if err != nil {
+		if err.Error() == "no rows in result set" {
+			return store.DeliveryWindow{}, false, nil
+		}
+		return store.DeliveryWindow{}, false, err
+	}
+	return w, true, nil
+}
+
+// ResolveDeliveryWindow returns the campaign's window, falling back to the tenant default.
+func (s *Store) ResolveDeliveryWindow(ctx context.Context, tenantID, campaignID string) (store.DeliveryWindow, bool, error) {
+	w, err := scanDeliveryWindow(s.DB.QueryRow(ctx, `
+		SELECT `+deliveryWindowColumns+` FROM delivery_windows
+		WHERE tenant_id=$1 AND campaign_id IN ($2, '')
+		ORDER BY campaign_id DESC LIMIT 1
+	`, tenantID, campaignID))
+	if err != nil {
+		if err.Error() == "no rows in result set" {
+			return store.DeliveryWindow{}, false, nil
+		}
+		return store.DeliveryWindow{}, false, err
+	}
+	return w, true, nil
+}
+
+func (s *Store) DeleteDeliveryWindow(ctx context.Context, tenantID, campaignID string) (bool, error) {
+	ct, err := s.DB.Exec(ctx, `DELETE FROM delivery_windows WHERE tenant_id=$1 AND campaign_id=$2`, tenantID, campaignID)
+	if err != nil {
+		return false, err
+	}
+	return ct.RowsAffected() > 0, nil
+}
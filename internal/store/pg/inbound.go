@@ -0,0 +1,105 @@
+package pg
+
+import (
+	"context"
+
+	"notif/internal/store"
+)
+
+// InsertInboundMessage stores an inbound SMS. A provider redelivering the same message is a no-op.
+func (s *Store) InsertInboundMessage(ctx context.Context, in store.InboundMessage) error {
+	_, err := s.DB.Exec(ctx, `
+		INSERT INTO inbound_messages (provider, provider_msg_id, from_phone, to_phone, body, keyword, received_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7)
+		ON CONFLICT (provider, provider_msg_id) DO NOTHING
+	`, in.Provider, in.ProviderMsgID, in.From, in.To, in.Body, nullIfEmpty(in.Keyword), in.ReceivedAt)
+	return err
+}
+
+// ListPhoneConsents returns every tenant's consent for phone with the source of its latest change.
+func (s *Store) ListPhoneConsents(ctx context.Context, phone, channel string) ([]store.Consent, error) {
+	rows, err := s.DB.Query(ctx, `
+		SELECT c.tenant_id, c.phone, c.channel, c.status, c.updated_at, COALESCE(e.source, '')
+		FROM consents c
+		LEFT JOIN LATERAL (
+			SELECT source FROM consent_events ce
+			WHERE ce.tenant_id=c.tenant_id AND ce.phone=c.phone AND ce.channel=c.channel
+			ORDER BY ce.created_at DESC, ce.id DESC
+			LIMIT 1
+		) e ON true
+		WHERE c.phone=$1 AND c.channel=$2
+		ORDER BY c.tenant_id
+	`, phone, channel)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []store.Consent
+	for rows.Next() {
+		var c store.Consent
+		if err := rows.Scan(&c.TenantID, &c.Phone, &c.Channel, &c.Status, &c.UpdatedAt, &c.Source); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// LastSenderTenant returns the tenant that last had a message accepted for phone, whatever its consent.
+func (s *Store) LastSenderTenant(ctx context.Context, phone string) (store.SenderTenant, bool, error) {
+	var t store.SenderTenant
+	err := s.DB.QueryRow(ctx, `
+		SELECT t.id, t.name
+		FROM messages m JOIN tenants t ON t.id=m.tenant_id
+		WHERE m.to_phone=$1 AND m.provider_msg_id IS NOT NULL
+		ORDER BY m.created_at DESC
+		LIMIT 1
+	`, phone).Scan(&t.ID, &t.Name)
+	if err != nil {
+		if err.Error() == "no rows in result set" {
+			return store.SenderTenant{}, false, nil
+		}
+		return store.SenderTenant{}, false, err
+	}
+	return t, true, nil
+}
+
+// UpsertKeywordReplies returns false if the tenant does not exist.
+func (s *Store) UpsertKeywordReplies(ctx context.Context, in store.KeywordReplies) (bool, error) {
+	ct, err := s.DB.Exec(ctx, `
+		INSERT INTO keyword_replies (tenant_id, help_text, stop_text, start_text, updated_at)
+		SELECT $1,$2,$3,$4,$5 WHERE EXISTS (SELECT 1 FROM tenants WHERE id=$1)
+		ON CONFLICT (tenant_id) DO UPDATE SET
+		  help_text=EXCLUDED.help_text,
+		  stop_text=EXCLUDED.stop_text,
+		  start_text=EXCLUDED.start_text,
+		  updated_at=EXCLUDED.updated_at
+	`, in.TenantID, in.Help, in.Stop, in.Start, in.UpdatedAt)
+	if err != nil {
+		return false, err
+	}
+	return ct.RowsAffected() > 0, nil
+}
+
+func (s *Store) GetKeywordReplies(ctx context.Context, tenantID string) (store.KeywordReplies, bool, error) {
+	var r store.KeywordReplies
+	err := s.DB.QueryRow(ctx, `
+		SELECT tenant_id, help_text, stop_text, start_text, updated_at FROM keyword_replies WHERE tenant_id=$1
+	`, tenantID).Scan(&r.TenantID, &r.Help, &r.Stop, &r.Start, &r.UpdatedAt)
+	if err != nil {
+		if err.Error() == "no rows in result set" {
+			return store.KeywordReplies{}, false, nil
+		}
+		return store.KeywordReplies{}, false, err
+	}
+	return r, true, nil
+}
+
+func (s *Store) DeleteKeywordReplies(ctx context.Context, tenantID string) (bool, error) {
+	ct, err := s.DB.Exec(ctx, `DELETE FROM keyword_replies WHERE tenant_id=$1`, tenantID)
+	if err != nil {
+		return false, err
+	}
+	return ct.RowsAffected() > 0, nil
+}
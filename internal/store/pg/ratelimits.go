@@ -0,0 +1,81 @@
+package pg
+
+import (
+	"context"
+	"time"
+
+	"notif/internal/store"
+)
+
+// ReserveToken takes a token from the bucket key, refilled at perSecond up to burst, and returns
+// how long the caller must wait for it. The bucket may go into debt by up to maxWait worth of
+// tokens; a reservation beyond that is refused (false) and nothing is taken. The database clock
+// is used so that skew between worker pods doesn't matter.
+func (s *Store) ReserveToken(ctx context.Context, key string, perSecond float64, burst int, maxWait time.Duration) (time.Duration, bool, error) {
+	var tokens float64
+	err := s.DB.QueryRow(ctx, `
+		INSERT INTO rate_buckets AS b (key, tokens, updated_at)
+		VALUES ($1, $3::float8 - 1, clock_timestamp())
+		ON CONFLICT (key) DO UPDATE SET
+		  tokens = LEAST($3::float8, b.tokens + GREATEST(0, EXTRACT(EPOCH FROM (EXCLUDED.updated_at - b.updated_at))::float8) * $2::float8) - 1,
+		  updated_at = GREATEST(b.updated_at, EXCLUDED.updated_at)
+		WHERE LEAST($3::float8, b.tokens + GREATEST(0, EXTRACT(EPOCH FROM (EXCLUDED.updated_at - b.updated_at))::float8) * $2::float8) - 1 >= -$2::float8 * $4::float8
+		RETURNING tokens
+	`, key, perSecond, float64(burst), maxWait.Seconds()).Scan(&tokens)
+	if err != nil {
+		if err.Error() == "no rows in result set" {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	if tokens >= 0 {
+		return 0, true, nil
+	}
+	return time.Duration(-tokens / perSecond * float64(time.Second)), true, nil
+}
+
+// RefundToken gives back a token reserved with ReserveToken, up to burst.
+func (s *Store) RefundToken(ctx context.Context, key string, burst int) error {
+	_, err := s.DB.Exec(ctx, `
+		UPDATE rate_buckets SET tokens = LEAST($2::float8, tokens + 1) WHERE key=$1
+	`, key, float64(burst))
+	return err
+}
+
+// UpsertTenantRateLimit returns false if the tenant does not exist.
+func (s *Store) UpsertTenantRateLimit(ctx context.Context, in store.TenantRateLimit) (bool, error) {
+	ct, err := s.DB.Exec(ctx, `
+		INSERT INTO tenant_rate_limits (tenant_id, per_second, burst, updated_at)
+		SELECT $1,$2,$3,$4 WHERE EXISTS (SELECT 1 FROM tenants WHERE id=$1)
+		ON CONFLICT (tenant_id) DO UPDATE SET
+		  per_second=EXCLUDED.per_second,
+		  burst=EXCLUDED.burst,
+		  updated_at=EXCLUDED.updated_at
+	`, in.TenantID, in.PerSecond, in.Burst, in.UpdatedAt)
+	if err != nil {
+		return false, err
+	}
+	return ct.RowsAffected() > 0, nil
+}
+
+func (s *Store) GetTenantRateLimit(ctx context.Context, tenantID string) (store.TenantRateLimit, bool, error) {
+	var l store.TenantRateLimit
+	err := s.DB.QueryRow(ctx, `
+		SELECT tenant_id, per_second, burst, updated_at FROM tenant_rate_limits WHERE tenant_id=$1
+	`, tenantID).Scan(&l.TenantID, &l.PerSecond, &l.Burst, &l.UpdatedAt)
+	if err != nil {
+		if err.Error() == "no rows in result set" {
+			return store.TenantRateLimit{}, false, nil
+		}
+		return store.TenantRateLimit{}, false, err
+	}
+	return l, true, nil
+}
+
+func (s *Store) DeleteTenantRateLimit(ctx context.Context, tenantID string) (bool, error) {
+	ct, err := s.DB.Exec(ctx, `DELETE FROM tenant_rate_limits WHERE tenant_id=$1`, tenantID)
+	if err != nil {
+		return false, err
+	}
+	return ct.RowsAffected() > 0, nil
+}
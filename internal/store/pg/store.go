@@ -3,6 +3,8 @@ package pg
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -32,36 +34,177 @@ func (s *Store) FindMessageByIdempotency(ctx context.Context, tenantID, idemKey
 }
 
 func (s *Store) InsertMessage(ctx context.Context, in store.MessageInsert) error {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
 	b, _ := json.Marshal(in.Vars)
-	_, err := s.DB.Exec(ctx, `
-		INSERT INTO messages (id, tenant_id, idempotency_key, to_phone, template_id, vars_json, campaign_id, state, created_at, updated_at)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$9)
-	`, in.ID, in.TenantID, in.IdemKey, in.To, in.TemplateID, b, nullIfEmpty(in.CampaignID), in.State, in.Now)
-	return err
+	_, err = tx.Exec(ctx, `
+		INSERT INTO messages (id, tenant_id, idempotency_key, to_phone, template_id, vars_json, campaign_id, state, last_error, created_at, updated_at, country, scheduled_at, expires_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$10,$11,$12,$13)
+	`, in.ID, in.TenantID, in.IdemKey, in.To, in.TemplateID, b, nullIfEmpty(in.CampaignID), in.State, nullIfEmpty(in.LastError), in.Now, nullIfEmpty(in.Country), in.SendAt, in.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	if in.OutboxPayload != nil {
+		pb, err := json.Marshal(in.OutboxPayload)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO outbox (message_id, payload_json, next_attempt_at, priority, created_at) VALUES ($1,$2,$3,$4,$5)
+		`, in.ID, pb, outboxDueAt(in), in.Priority, in.Now); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// outboxDueAt is when the relay may publish the message: now, or its scheduled time.
+func outboxDueAt(in store.MessageInsert) time.Time {
+	if in.SendAt != nil {
+		return *in.SendAt
+	}
+	return in.Now
+}
+
+// FindMessagesByIdempotency looks up many idempotency keys at once, keyed by idempotency key.
+func (s *Store) FindMessagesByIdempotency(ctx context.Context, tenantID string, idemKeys []string) (map[string]store.IdempotencyResult, error) {
+	out := make(map[string]store.IdempotencyResult, len(idemKeys))
+	if len(idemKeys) == 0 {
+		return out, nil
+	}
+	rows, err := s.DB.Query(ctx, `
+		SELECT idempotency_key, id, state FROM messages WHERE tenant_id=$1 AND idempotency_key = ANY($2)
+	`, tenantID, idemKeys)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key string
+		var res store.IdempotencyResult
+		if err := rows.Scan(&key, &res.MessageID, &res.State); err != nil {
+			return nil, err
+		}
+		res.Found = true
+		out[key] = res
+	}
+	return out, rows.Err()
+}
+
+// InsertMessages inserts many messages (and their outbox rows) in one transaction. Rows that lose
+// an idempotency race to a concurrent request are skipped; the returned set holds the IDs inserted.
+func (s *Store) InsertMessages(ctx context.Context, in []store.MessageInsert) (map[string]bool, error) {
+	inserted := make(map[string]bool, len(in))
+	if len(in) == 0 {
+		return inserted, nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`
+		INSERT INTO messages (id, tenant_id, idempotency_key, to_phone, template_id, vars_json, campaign_id, state, last_error, created_at, updated_at, country, scheduled_at, expires_at)
+		VALUES `)
+	const cols = 13
+	args := make([]any, 0, len(in)*cols)
+	for i, m := range in {
+		b, _ := json.Marshal(m.Vars)
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		n := i * cols
+		fmt.Fprintf(&sb, "($%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d)", n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8, n+9, n+10, n+10, n+11, n+12, n+13)
+		args = append(args, m.ID, m.TenantID, m.IdemKey, m.To, m.TemplateID, b, nullIfEmpty(m.CampaignID), m.State, nullIfEmpty(m.LastError), m.Now, nullIfEmpty(m.Country), m.SendAt, m.ExpiresAt)
+	}
+	sb.WriteString(`
+		ON CONFLICT (tenant_id, idempotency_key) DO NOTHING
+		RETURNING id`)
+
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	rows, err := tx.Query(ctx, sb.String(), args...)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		inserted[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var outboxIDs, outboxPayloads []string
+	var outboxDue []time.Time
+	var outboxPriority []int32
+	var now time.Time
+	for _, m := range in {
+		if m.OutboxPayload == nil || !inserted[m.ID] {
+			continue
+		}
+		pb, err := json.Marshal(m.OutboxPayload)
+		if err != nil {
+			return nil, err
+		}
+		outboxIDs = append(outboxIDs, m.ID)
+		outboxPayloads = append(outboxPayloads, string(pb))
+		outboxDue = append(outboxDue, outboxDueAt(m))
+		outboxPriority = append(outboxPriority, int32(m.Priority))
+		now = m.Now
+	}
+	if len(outboxIDs) > 0 {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO outbox (message_id, payload_json, next_attempt_at, priority, created_at)
+			SELECT m, p::jsonb, d, r, $5 FROM unnest($1::text[], $2::text[], $3::timestamptz[], $4::smallint[]) AS t(m, p, d, r)
+		`, outboxIDs, outboxPayloads, outboxDue, outboxPriority, now); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return inserted, nil
 }
 
 func (s *Store) MarkMessageState(ctx context.Context, in store.MessageStateUpdate) error {
 	_, err := s.DB.Exec(ctx, `
-		UPDATE messages SET state=$2, last_error=$3, updated_at=$4 WHERE id=$1
-	`, in.ID, in.State, nullIfEmpty(in.LastError), in.Now)
+		UPDATE messages SET state=$2, last_error=$3, failure_reason=$5, updated_at=$4,
+		  retries = retries + CASE WHEN $6 THEN 1 ELSE 0 END
+		WHERE id=$1
+	`, in.ID, in.State, nullIfEmpty(in.LastError), in.Now, nullIfEmpty(in.FailureReason), in.CountRetry)
 	return err
 }
 
 func (s *Store) SetProviderDetails(ctx context.Context, in store.ProviderDetailsUpdate) error {
 	_, err := s.DB.Exec(ctx, `
-		UPDATE messages SET provider=$2, provider_msg_id=$3, state=$4, updated_at=$5 WHERE id=$1
-	`, in.ID, in.Provider, in.ProviderMsgID, in.State, in.Now)
+		UPDATE messages
+		SET provider=$2, provider_msg_id=$3, state=$4, updated_at=$5, failure_reason=NULL,
+		    encoding=COALESCE($6, encoding), segments=COALESCE($7, segments)
+		WHERE id=$1
+	`, in.ID, in.Provider, in.ProviderMsgID, in.State, in.Now, nullIfEmpty(in.Encoding), nullIfZero(in.Segments))
 	return err
 }
 
 func (s *Store) GetMessageForWorker(ctx context.Context, msgID string) (store.MessageForWorker, error) {
 	var varsJSON []byte
 	row := s.DB.QueryRow(ctx, `
-		SELECT tenant_id, to_phone, template_id, COALESCE(campaign_id,''), state, COALESCE(provider_msg_id,''), vars_json, created_at
+		SELECT tenant_id, to_phone, template_id, COALESCE(campaign_id,''), state, COALESCE(provider_msg_id,''), vars_json, expires_at, created_at, retries
 		FROM messages WHERE id=$1
 	`, msgID)
 	var out store.MessageForWorker
-	err := row.Scan(&out.TenantID, &out.To, &out.TemplateID, &out.CampaignID, &out.State, &out.ProviderMsgID, &varsJSON, &out.CreatedAt)
+	err := row.Scan(&out.TenantID, &out.To, &out.TemplateID, &out.CampaignID, &out.State, &out.ProviderMsgID, &varsJSON, &out.ExpiresAt, &out.CreatedAt, &out.Retries)
 	if err != nil {
 		return store.MessageForWorker{}, err
 	}
@@ -73,14 +216,18 @@ func (s *Store) InsertAttempt(ctx context.Context, in store.ProviderAttempt) err
 	reqB, _ := json.Marshal(in.RequestJSON)
 	respB, _ := json.Marshal(in.ResponseJSON)
 	_, err := s.DB.Exec(ctx, `
-		INSERT INTO provider_attempts (message_id, provider, provider_msg_id, http_status, error_code, error_msg, request_json, response_json)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
-	`, in.MessageID, in.Provider, nullIfEmpty(in.ProviderMsgID), in.HTTPStatus, nullIfEmpty(in.ErrorCode), nullIfEmpty(in.ErrorMsg), reqB, respB)
+		INSERT INTO provider_attempts (message_id, provider, provider_msg_id, http_status, error_code, error_msg, latency_ms, request_json, response_json, failure_reason)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)
+	`, in.MessageID, in.Provider, nullIfEmpty(in.ProviderMsgID), in.HTTPStatus, nullIfEmpty(in.ErrorCode), nullIfEmpty(in.ErrorMsg), nullIfZero(in.LatencyMs), reqB, respB, nullIfEmpty(in.FailureReason))
 	return err
 }
 
-func (s *Store) IsSuppressed(ctx context.Context, tenantID, phone string) (bool, error) {
-	row := s.DB.QueryRow(ctx, `SELECT 1 FROM suppression_list WHERE tenant_id=$1 AND phone=$2`, tenantID, phone)
+// IsSuppressed reports whether phone has a suppression that has not expired as of now.
+func (s *Store) IsSuppressed(ctx context.Context, tenantID, phone string, now time.Time) (bool, error) {
+	row := s.DB.QueryRow(ctx, `
+		SELECT 1 FROM suppression_list
+		WHERE tenant_id=$1 AND phone=$2 AND (expires_at IS NULL OR expires_at > $3)
+	`, tenantID, phone, now)
 	var one int
 	err := row.Scan(&one)
 	if err != nil {
@@ -144,38 +291,49 @@ func (s *Store) IncrementDailyCap(ctx context.Context, tenantID, phone string, d
 	return true, newCount, nil
 }
 
+// ReleaseDailyCap gives back a slot taken by IncrementDailyCap when the send was not created after all.
+func (s *Store) ReleaseDailyCap(ctx context.Context, tenantID, phone string, day time.Time) error {
+	d := day.UTC().Truncate(24 * time.Hour)
+	_, err := s.DB.Exec(ctx, `
+		UPDATE send_caps_daily SET count = GREATEST(count - 1, 0), updated_at=now()
+		WHERE tenant_id=$1 AND phone=$2 AND day=$3
+	`, tenantID, phone, d)
+	return err
+}
+
 func (s *Store) InsertDeliveryEvent(ctx context.Context, in store.DeliveryEvent) error {
 	b, _ := json.Marshal(in.Payload)
 	_, err := s.DB.Exec(ctx, `
-		INSERT INTO delivery_events (provider, provider_msg_id, vendor_status, error_code, payload_json, occurred_at)
-		VALUES ($1,$2,$3,$4,$5,$6)
-	`, in.Provider, in.ProviderMsgID, in.VendorStatus, nullIfEmpty(in.ErrorCode), b, in.OccurredAt)
+		INSERT INTO delivery_events (provider, provider_msg_id, vendor_status, error_code, payload_json, occurred_at, failure_reason)
+		VALUES ($1,$2,$3,$4,$5,$6,$7)
+	`, in.Provider, in.ProviderMsgID, in.VendorStatus, nullIfEmpty(in.ErrorCode), b, in.OccurredAt, nullIfEmpty(in.FailureReason))
 	return err
 }
 
 func (s *Store) UpdateMessageByProviderMsgID(ctx context.Context, in store.ProviderMsgUpdate) (bool, error) {
 	ct, err := s.DB.Exec(ctx, `
 		UPDATE messages
-		SET state=$3, last_error=$4, updated_at=$5
+		SET state=$3, last_error=$4, failure_reason=$6, updated_at=$5
 		WHERE provider=$1 AND provider_msg_id=$2
-	`, in.Provider, in.ProviderMsgID, in.NewState, nullIfEmpty(in.LastError), in.Now)
+	`, in.Provider, in.ProviderMsgID, in.NewState, nullIfEmpty(in.LastError), in.Now, nullIfEmpty(in.FailureReason))
 	if err != nil {
 		return false, err
 	}
 	return ct.RowsAffected() > 0, nil
 }
 
-func (s *Store) GetMessage(ctx context.Context, msgID string) (store.Message, bool, error) {
+// GetMessage fetches a message by ID, restricted to tenantID unless it is empty.
+func (s *Store) GetMessage(ctx context.Context, tenantID, msgID string) (store.Message, bool, error) {
 	var m store.Message
 	row := s.DB.QueryRow(ctx, `
 		SELECT id, tenant_id, to_phone, template_id, COALESCE(campaign_id,''), state,
-		       COALESCE(provider,''), COALESCE(provider_msg_id,''), COALESCE(last_error,''),
-		       created_at, updated_at
-		FROM messages WHERE id=$1
-	`, msgID)
+		       COALESCE(provider,''), COALESCE(provider_msg_id,''), COALESCE(last_error,''), COALESCE(failure_reason,''),
+		       COALESCE(encoding,''), COALESCE(segments,0), COALESCE(country,''), scheduled_at, expires_at, created_at, updated_at
+		FROM messages WHERE id=$1 AND ($2 = '' OR tenant_id=$2)
+	`, msgID, tenantID)
 
 	err := row.Scan(&m.ID, &m.TenantID, &m.ToPhone, &m.TemplateID, &m.CampaignID, &m.State,
-		&m.Provider, &m.ProviderMsgID, &m.LastError, &m.CreatedAt, &m.UpdatedAt)
+		&m.Provider, &m.ProviderMsgID, &m.LastError, &m.FailureReason, &m.Encoding, &m.Segments, &m.Country, &m.ScheduledAt, &m.ExpiresAt, &m.CreatedAt, &m.UpdatedAt)
 
 	if err != nil {
 		if err.Error() == "no rows in result set" {
@@ -186,14 +344,124 @@ func (s *Store) GetMessage(ctx context.Context, msgID string) (store.Message, bo
 	return m, true, nil
 }
 
+// ListMessages returns up to f.Limit messages matching f, newest first.
+func (s *Store) ListMessages(ctx context.Context, f store.MessageFilter) ([]store.Message, error) {
+	var sb strings.Builder
+	sb.WriteString(`
+		SELECT id, tenant_id, to_phone, template_id, COALESCE(campaign_id,''), state,
+		       COALESCE(provider,''), COALESCE(provider_msg_id,''), COALESCE(last_error,''), COALESCE(failure_reason,''),
+		       COALESCE(encoding,''), COALESCE(segments,0), COALESCE(country,''), scheduled_at, expires_at, created_at, updated_at
+		FROM messages WHERE tenant_id=$1`)
+	args := []any{f.TenantID}
+	add := func(cond string, v any) {
+		args = append(args, v)
+		fmt.Fprintf(&sb, " AND "+cond, len(args))
+	}
+	if f.CampaignID != "" {
+		add("campaign_id=$%d", f.CampaignID)
+	}
+	if f.ToPhone != "" {
+		add("to_phone=$%d", f.ToPhone)
+	}
+	if f.State != "" {
+		add("state=$%d", f.State)
+	}
+	if f.TemplateID != "" {
+		add("template_id=$%d", f.TemplateID)
+	}
+	if f.Country != "" {
+		add("country=$%d", f.Country)
+	}
+	if f.FailureReason != "" {
+		add("failure_reason=$%d", f.FailureReason)
+	}
+	if f.CreatedFrom != nil {
+		add("created_at >= $%d", *f.CreatedFrom)
+	}
+	if f.CreatedTo != nil {
+		add("created_at < $%d", *f.CreatedTo)
+	}
+	if f.BeforeID != "" {
+		add("id < $%d", f.BeforeID)
+	}
+	args = append(args, f.Limit)
+	fmt.Fprintf(&sb, " ORDER BY id DESC LIMIT $%d", len(args))
+
+	rows, err := s.DB.Query(ctx, sb.String(), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]store.Message, 0, f.Limit)
+	for rows.Next() {
+		var m store.Message
+		if err := rows.Scan(&m.ID, &m.TenantID, &m.ToPhone, &m.TemplateID, &m.CampaignID, &m.State,
+			&m.Provider, &m.ProviderMsgID, &m.LastError, &m.FailureReason, &m.Encoding, &m.Segments, &m.Country, &m.ScheduledAt, &m.ExpiresAt, &m.CreatedAt, &m.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// ListMessageHistory returns message events (claims, cancellation), provider attempts and
+// delivery receipts for a message, oldest first. Receipts are matched on the provider message
+// IDs of the message's attempts, so they are found even when the webhook arrived before
+// SetProviderDetails.
+func (s *Store) ListMessageHistory(ctx context.Context, msgID string) ([]store.HistoryEvent, error) {
+	rows, err := s.DB.Query(ctx, `
+		SELECT event, created_at, COALESCE(detail,''), '', '', 0, '', '', NULL::int, '', id, ''
+		FROM message_events WHERE message_id=$1
+		UNION ALL
+		SELECT 'attempt', created_at, '', provider, COALESCE(provider_msg_id,''), COALESCE(http_status,0),
+		       COALESCE(error_code,''), COALESCE(error_msg,''), latency_ms, '', id, COALESCE(failure_reason,'')
+		FROM provider_attempts WHERE message_id=$1
+		UNION ALL
+		SELECT 'delivery', received_at, '', provider, provider_msg_id, 0,
+		       COALESCE(error_code,''), '', NULL::int, vendor_status, id, COALESCE(failure_reason,'')
+		FROM delivery_events
+		WHERE (provider, provider_msg_id) IN (
+			SELECT provider, provider_msg_id FROM provider_attempts
+			WHERE message_id=$1 AND provider_msg_id IS NOT NULL
+			UNION
+			SELECT provider, provider_msg_id FROM messages
+			WHERE id=$1 AND provider_msg_id IS NOT NULL
+		)
+		ORDER BY 2, 11
+	`, msgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []store.HistoryEvent
+	for rows.Next() {
+		var e store.HistoryEvent
+		var id int64
+		if err := rows.Scan(&e.Kind, &e.At, &e.Detail, &e.Provider, &e.ProviderMsgID, &e.HTTPStatus,
+			&e.ErrorCode, &e.ErrorMsg, &e.LatencyMs, &e.VendorStatus, &id, &e.FailureReason); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
 // ClaimMessage attempts to move a message into processing state.
 // It allows reclaiming if the message is still "processing" but stale.
 func (s *Store) ClaimMessage(ctx context.Context, msgID string, now time.Time, staleAfter time.Duration) (bool, error) {
 	staleBefore := now.Add(-staleAfter)
+	// The claim is recorded in message_events with the state it was taken from, so
+	// reclaims of stale processing rows show up in the timeline.
 	ct, err := s.DB.Exec(ctx, `
-		UPDATE messages
-		SET state=$2, updated_at=$3
-		WHERE id=$1 AND (state='queued' OR (state='processing' AND updated_at < $4))
+		WITH claimed AS (
+			UPDATE messages m
+			SET state=$2, updated_at=$3
+			FROM (SELECT state AS prev_state FROM messages WHERE id=$1) prev
+			WHERE m.id=$1 AND (m.state IN ('queued','scheduled') OR (m.state='processing' AND m.updated_at < $4))
+			RETURNING m.id, prev.prev_state
+		)
+		INSERT INTO message_events (message_id, event, detail, created_at)
+		SELECT id, 'claimed', prev_state, $3 FROM claimed
 	`, msgID, "processing", now, staleBefore)
 	if err != nil {
 		return false, err
@@ -201,6 +469,144 @@ func (s *Store) ClaimMessage(ctx context.Context, msgID string, now time.Time, s
 	return ct.RowsAffected() > 0, nil
 }
 
+// ClaimOutbox leases up to limit due outbox rows, highest priority first. The lease is
+// next_attempt_at itself, so rows held by a relay that died become due again after lease
+// without any cleanup.
+func (s *Store) ClaimOutbox(ctx context.Context, limit int, now time.Time, lease time.Duration) ([]store.OutboxRow, error) {
+	rows, err := s.DB.Query(ctx, `
+		UPDATE outbox SET attempts = attempts + 1, next_attempt_at = $3
+		WHERE id IN (
+			SELECT id FROM outbox
+			WHERE state='pending' AND next_attempt_at <= $2
+			ORDER BY priority DESC, next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, message_id, payload_json, attempts
+	`, limit, now, now.Add(lease))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []store.OutboxRow
+	for rows.Next() {
+		var r store.OutboxRow
+		if err := rows.Scan(&r.ID, &r.MessageID, &r.Payload, &r.Attempts); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// MarkOutboxSent also moves scheduled messages to queued: publishing is what releases them.
+func (s *Store) MarkOutboxSent(ctx context.Context, ids []int64, now time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := s.DB.Exec(ctx, `
+		WITH sent AS (
+			UPDATE outbox SET state='sent', sent_at=$2, last_error=NULL WHERE id = ANY($1)
+			RETURNING message_id
+		)
+		UPDATE messages SET state='queued', updated_at=$2
+		WHERE id IN (SELECT message_id FROM sent) AND state='scheduled'
+	`, ids, now)
+	return err
+}
+
+// CancelMessage moves a scheduled or queued message to canceled and withdraws its unpublished
+// outbox row. It returns false if no such message is pending. Jobs already on SQS are dropped by
+// the worker, whose claim only accepts pending messages.
+func (s *Store) CancelMessage(ctx context.Context, tenantID, msgID string, now time.Time) (bool, error) {
+	ct, err := s.DB.Exec(ctx, `
+		WITH canceled AS (
+			UPDATE messages SET state='canceled', last_error='canceled', updated_at=$3
+			WHERE id=$1 AND ($2 = '' OR tenant_id=$2) AND state IN ('scheduled','queued')
+			RETURNING id
+		), withdrawn AS (
+			UPDATE outbox SET state='canceled'
+			WHERE message_id IN (SELECT id FROM canceled) AND state='pending'
+		)
+		INSERT INTO message_events (message_id, event, created_at)
+		SELECT id, 'canceled', $3 FROM canceled
+	`, msgID, tenantID, now)
+	if err != nil {
+		return false, err
+	}
+	return ct.RowsAffected() > 0, nil
+}
+
+// ExpireMessage moves a pending message whose expires_at has passed to expired and, like a
+// cancel, gives back the daily and country cap slots it took on the day it was accepted.
+// Processing rows count as pending once they are stale the way ClaimMessage sees them, so a
+// message whose worker died is not reclaimed and sent late. It returns false if the message is
+// not pending or not yet expired.
+func (s *Store) ExpireMessage(ctx context.Context, msgID string, now time.Time, staleAfter time.Duration) (bool, error) {
+	ct, err := s.DB.Exec(ctx, `
+		WITH expired AS (
+			UPDATE messages SET state='expired', last_error='expired', updated_at=$2
+			WHERE id=$1 AND expires_at <= $2
+			  AND (state IN ('scheduled','queued') OR (state='processing' AND updated_at < $3))
+			RETURNING id, tenant_id, to_phone, country, (created_at AT TIME ZONE 'UTC')::date AS day
+		), daily AS (
+			UPDATE send_caps_daily c SET count = GREATEST(c.count - 1, 0), updated_at=now()
+			FROM expired e WHERE c.tenant_id=e.tenant_id AND c.phone=e.to_phone AND c.day=e.day
+		), by_country AS (
+			-- Rows only exist for countries with a cap.
+			UPDATE send_caps_country_daily c SET count = GREATEST(c.count - 1, 0), updated_at=now()
+			FROM expired e WHERE c.tenant_id=e.tenant_id AND c.country=e.country AND c.day=e.day
+		)
+		INSERT INTO message_events (message_id, event, created_at)
+		SELECT id, 'expired', $2 FROM expired
+	`, msgID, now, now.Add(-staleAfter))
+	if err != nil {
+		return false, err
+	}
+	return ct.RowsAffected() > 0, nil
+}
+
+// DeferMessage puts a claimed message back on hold until at with a new outbox row carrying
+// payload, for a delivery window that closed after it was released or a retry too long to wait
+// out on a FIFO queue. It returns false if the message is no longer processing.
+func (s *Store) DeferMessage(ctx context.Context, msgID string, payload any, at, now time.Time) (bool, error) {
+	pb, err := json.Marshal(payload)
+	if err != nil {
+		return false, err
+	}
+	ct, err := s.DB.Exec(ctx, `
+		WITH deferred AS (
+			UPDATE messages SET state='scheduled', scheduled_at=$3, updated_at=$4
+			WHERE id=$1 AND state='processing'
+			RETURNING id
+		), requeued AS (
+			INSERT INTO outbox (message_id, payload_json, next_attempt_at, priority, created_at)
+			SELECT id, $2::jsonb, $3, COALESCE((SELECT priority FROM outbox WHERE message_id=$1 ORDER BY id DESC LIMIT 1), 1), $4
+			FROM deferred
+		)
+		INSERT INTO message_events (message_id, event, detail, created_at)
+		SELECT id, 'deferred', $5, $4 FROM deferred
+	`, msgID, string(pb), at, now, at.UTC().Format(time.RFC3339))
+	if err != nil {
+		return false, err
+	}
+	return ct.RowsAffected() > 0, nil
+}
+
+func (s *Store) MarkOutboxRetry(ctx context.Context, id int64, lastError string, nextAttemptAt time.Time) error {
+	_, err := s.DB.Exec(ctx, `
+		UPDATE outbox SET last_error=$2, next_attempt_at=$3 WHERE id=$1 AND state='pending'
+	`, id, nullIfEmpty(lastError), nextAttemptAt)
+	return err
+}
+
+func nullIfZero(n int) any {
+	if n == 0 {
+		return nil
+	}
+	return n
+}
+
 func nullIfEmpty(s string) any {
 	if s == "" {
 		return nil
@@ -0,0 +1,84 @@
+package pg
+
+import (
+	"context"
+	"time"
+
+	"notif/internal/store"
+)
+
+// UpsertSuppressions adds or replaces suppressions in one statement. Re-adding a phone
+// replaces its reason and expiry and restarts it from in.CreatedAt.
+func (s *Store) UpsertSuppressions(ctx context.Context, in []store.Suppression) (int, error) {
+	tenants := make([]string, len(in))
+	phones := make([]string, len(in))
+	reasons := make([]string, len(in))
+	created := make([]time.Time, len(in))
+	expires := make([]*time.Time, len(in))
+	for i, sp := range in {
+		tenants[i], phones[i], reasons[i], created[i], expires[i] = sp.TenantID, sp.Phone, sp.Reason, sp.CreatedAt, sp.ExpiresAt
+	}
+	// DISTINCT ON keeps the last entry per phone; ON CONFLICT cannot touch a row twice.
+	ct, err := s.DB.Exec(ctx, `
+		INSERT INTO suppression_list (tenant_id, phone, reason, created_at, expires_at)
+		SELECT DISTINCT ON (t, p) t, p, r, c, e
+		FROM unnest($1::text[], $2::text[], $3::text[], $4::timestamptz[], $5::timestamptz[])
+		     WITH ORDINALITY AS x(t, p, r, c, e, n)
+		ORDER BY t, p, n DESC
+		ON CONFLICT (tenant_id, phone)
+		DO UPDATE SET reason=EXCLUDED.reason, created_at=EXCLUDED.created_at, expires_at=EXCLUDED.expires_at
+	`, tenants, phones, reasons, created, expires)
+	if err != nil {
+		return 0, err
+	}
+	return int(ct.RowsAffected()), nil
+}
+
+func (s *Store) DeleteSuppression(ctx context.Context, tenantID, phone string) (bool, error) {
+	ct, err := s.DB.Exec(ctx, `DELETE FROM suppression_list WHERE tenant_id=$1 AND phone=$2`, tenantID, phone)
+	if err != nil {
+		return false, err
+	}
+	return ct.RowsAffected() > 0, nil
+}
+
+// GetSuppression returns the suppression for phone, including an expired one.
+func (s *Store) GetSuppression(ctx context.Context, tenantID, phone string) (store.Suppression, bool, error) {
+	row := s.DB.QueryRow(ctx, `
+		SELECT tenant_id, phone, reason, created_at, expires_at
+		FROM suppression_list WHERE tenant_id=$1 AND phone=$2
+	`, tenantID, phone)
+	var sp store.Suppression
+	err := row.Scan(&sp.TenantID, &sp.Phone, &sp.Reason, &sp.CreatedAt, &sp.ExpiresAt)
+	if err != nil {
+		if err.Error() == "no rows in result set" {
+			return store.Suppression{}, false, nil
+		}
+		return store.Suppression{}, false, err
+	}
+	return sp, true, nil
+}
+
+func (s *Store) ListSuppressions(ctx context.Context, f store.SuppressionFilter) ([]store.Suppression, error) {
+	rows, err := s.DB.Query(ctx, `
+		SELECT tenant_id, phone, reason, created_at, expires_at
+		FROM suppression_list
+		WHERE tenant_id=$1 AND phone > $2
+		  AND ($3 OR expires_at IS NULL OR expires_at > $4)
+		ORDER BY phone
+		LIMIT $5
+	`, f.TenantID, f.AfterPhone, f.IncludeExpired, f.Now, f.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]store.Suppression, 0, f.Limit)
+	for rows.Next() {
+		var sp store.Suppression
+		if err := rows.Scan(&sp.TenantID, &sp.Phone, &sp.Reason, &sp.CreatedAt, &sp.ExpiresAt); err != nil {
+			return nil, err
+		}
+		out = append(out, sp)
+	}
+	return out, rows.Err()
+}
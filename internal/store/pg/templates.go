@@ -0,0 +1,149 @@
+package pg
+
+import (
+	"context"
+	"time"
+
+	"notif/internal/store"
+)
+
+const templateColumns = `tenant_id, template_id, version, body, COALESCE(max_segments,0), transliterate, transactional,
+	status, created_at, updated_at, published_at`
+
+func scanTemplate(row interface{ Scan(...any) error }) (store.Template, error) {
+	var t store.Template
+	err := row.Scan(&t.TenantID, &t.TemplateID, &t.Version, &t.Body, &t.MaxSegments, &t.Transliterate, &t.Transactional,
+		&t.Status, &t.CreatedAt, &t.UpdatedAt, &t.PublishedAt)
+	return t, err
+}
+
+// InsertTemplateVersion creates the next draft version of in.TemplateID. It returns false if the
+// tenant does not exist. Concurrent creates of the same template are serialized on a transaction
+// advisory lock, since there is no row to lock before its first version.
+func (s *Store) InsertTemplateVersion(ctx context.Context, in store.Template, now time.Time) (store.Template, bool, error) {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return store.Template{}, false, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('templates/' || $1 || '/' || $2))`, in.TenantID, in.TemplateID); err != nil {
+		return store.Template{}, false, err
+	}
+	row := tx.QueryRow(ctx, `
+		INSERT INTO templates (tenant_id, template_id, version, body, max_segments, transliterate, transactional, status, created_at, updated_at)
+		SELECT $1::text, $2::text,
+		       COALESCE((SELECT MAX(version) FROM templates WHERE tenant_id=$1 AND template_id=$2), 0) + 1,
+		       $3::text, $4::int, $5::bool, $7::bool, 'draft', $6::timestamptz, $6::timestamptz
+		WHERE EXISTS (SELECT 1 FROM tenants WHERE id=$1)
+		RETURNING `+templateColumns, in.TenantID, in.TemplateID, in.Body, nullIfZero(in.MaxSegments), in.Transliterate, now, in.Transactional)
+	t, err := scanTemplate(row)
+	if err != nil {
+		if err.Error() == "no rows in result set" {
+			return store.Template{}, false, nil
+		}
+		return store.Template{}, false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return store.Template{}, false, err
+	}
+	return t, true, nil
+}
+
+func (s *Store) GetTemplateVersion(ctx context.Context, tenantID, templateID string, version int) (store.Template, bool, error) {
+	row := s.DB.QueryRow(ctx, `
+		SELECT `+templateColumns+` FROM templates
+		WHERE tenant_id=$1 AND template_id=$2 AND version=$3
+	`, tenantID, templateID, version)
+	t, err := scanTemplate(row)
+	if err != nil {
+		if err.Error() == "no rows in result set" {
+			return store.Template{}, false, nil
+		}
+		return store.Template{}, false, err
+	}
+	return t, true, nil
+}
+
+// GetPublishedTemplate returns the most recently published version, so republishing an older
+// version rolls back to it.
+func (s *Store) GetPublishedTemplate(ctx context.Context, tenantID, templateID string) (store.Template, bool, error) {
+	row := s.DB.QueryRow(ctx, `
+		SELECT `+templateColumns+` FROM templates
+		WHERE tenant_id=$1 AND template_id=$2 AND status='published'
+		ORDER BY published_at DESC
+		LIMIT 1
+	`, tenantID, templateID)
+	t, err := scanTemplate(row)
+	if err != nil {
+		if err.Error() == "no rows in result set" {
+			return store.Template{}, false, nil
+		}
+		return store.Template{}, false, err
+	}
+	return t, true, nil
+}
+
+// ListTemplateVersions returns every version of a template, newest first. An empty templateID
+// returns the latest version of each of the tenant's templates instead.
+func (s *Store) ListTemplateVersions(ctx context.Context, tenantID, templateID string) ([]store.Template, error) {
+	q := `SELECT ` + templateColumns + ` FROM templates WHERE tenant_id=$1 AND template_id=$2 ORDER BY version DESC`
+	args := []any{tenantID, templateID}
+	if templateID == "" {
+		q = `SELECT DISTINCT ON (template_id) ` + templateColumns + ` FROM templates
+			WHERE tenant_id=$1 ORDER BY template_id, version DESC`
+		args = args[:1]
+	}
+	rows, err := s.DB.Query(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []store.Template
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+// UpdateDraftTemplate replaces the body and send limits of a draft version. It returns false if
+// the version does not exist or is not a draft.
+func (s *Store) UpdateDraftTemplate(ctx context.Context, in store.Template, now time.Time) (bool, error) {
+	ct, err := s.DB.Exec(ctx, `
+		UPDATE templates SET body=$4, max_segments=$5, transliterate=$6, transactional=$8, updated_at=$7
+		WHERE tenant_id=$1 AND template_id=$2 AND version=$3 AND status='draft'
+	`, in.TenantID, in.TemplateID, in.Version, in.Body, nullIfZero(in.MaxSegments), in.Transliterate, now, in.Transactional)
+	if err != nil {
+		return false, err
+	}
+	return ct.RowsAffected() > 0, nil
+}
+
+// SetTemplateStatus moves a version to status; publishing stamps published_at.
+func (s *Store) SetTemplateStatus(ctx context.Context, tenantID, templateID string, version int, status string, now time.Time) (bool, error) {
+	ct, err := s.DB.Exec(ctx, `
+		UPDATE templates
+		SET status=$4, updated_at=$5,
+		    published_at = CASE WHEN $4='published' THEN $5 ELSE published_at END
+		WHERE tenant_id=$1 AND template_id=$2 AND version=$3
+	`, tenantID, templateID, version, status, now)
+	if err != nil {
+		return false, err
+	}
+	return ct.RowsAffected() > 0, nil
+}
+
+// DeleteDraftTemplate removes a draft version; published history is archived instead.
+func (s *Store) DeleteDraftTemplate(ctx context.Context, tenantID, templateID string, version int) (bool, error) {
+	ct, err := s.DB.Exec(ctx, `
+		DELETE FROM templates WHERE tenant_id=$1 AND template_id=$2 AND version=$3 AND status='draft'
+	`, tenantID, templateID, version)
+	if err != nil {
+		return false, err
+	}
+	return ct.RowsAffected() > 0, nil
+}
@@ -12,6 +12,12 @@ type Message struct {
 	Provider      string
 	ProviderMsgID string
 	LastError     string
+	FailureReason string
+	Encoding      string
+	Segments      int
+	Country       string
+	ScheduledAt   *time.Time
+	ExpiresAt     *time.Time
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
 }
@@ -27,18 +33,34 @@ type MessageInsert struct {
 	TenantID   string
 	IdemKey    string
 	To         string
+	Country    string // ISO region of To
 	TemplateID string
 	Vars       map[string]string
 	CampaignID string
 	State      string
+	LastError  string
 	Now        time.Time
+	// SendAt holds a scheduled message back: the outbox row only becomes due at this time.
+	SendAt *time.Time
+	// ExpiresAt is when the worker drops the message instead of sending it.
+	ExpiresAt *time.Time
+	// Priority orders the outbox: higher ranks are published first.
+	Priority int
+
+	// OutboxPayload, when set, is written to the outbox in the same transaction as the message
+	// so the relay publishes it even if the caller dies right after the insert.
+	OutboxPayload any
 }
 
 type MessageStateUpdate struct {
 	ID        string
 	State     string
 	LastError string
-	Now       time.Time
+	// FailureReason is the normalized cause of a failure; empty clears it.
+	FailureReason string
+	// CountRetry adds one to the message's retries.
+	CountRetry bool
+	Now        time.Time
 }
 
 type ProviderDetailsUpdate struct {
@@ -46,7 +68,10 @@ type ProviderDetailsUpdate struct {
 	Provider      string
 	ProviderMsgID string
 	State         string
-	Now           time.Time
+	// Encoding and Segments describe the body as sent (left unchanged when empty/zero).
+	Encoding string
+	Segments int
+	Now      time.Time
 }
 
 type MessageForWorker struct {
@@ -57,7 +82,10 @@ type MessageForWorker struct {
 	State         string
 	ProviderMsgID string
 	Vars          map[string]string
+	ExpiresAt     *time.Time
 	CreatedAt     time.Time
+	// Retries counts earlier receives that reached a provider.
+	Retries int
 }
 
 type ProviderAttempt struct {
@@ -67,8 +95,11 @@ type ProviderAttempt struct {
 	HTTPStatus    int
 	ErrorCode     string
 	ErrorMsg      string
-	RequestJSON   any
-	ResponseJSON  any
+	FailureReason string
+	// LatencyMs is the provider call duration; 0 means the provider was not called.
+	LatencyMs    int
+	RequestJSON  any
+	ResponseJSON any
 }
 
 type DeliveryEvent struct {
@@ -76,6 +107,7 @@ type DeliveryEvent struct {
 	ProviderMsgID string
 	VendorStatus  string
 	ErrorCode     string
+	FailureReason string
 	Payload       any
 	OccurredAt    *time.Time
 }
@@ -85,5 +117,183 @@ type ProviderMsgUpdate struct {
 	ProviderMsgID string
 	NewState      string
 	LastError     string
+	FailureReason string
 	Now           time.Time
 }
+
+type OutboxRow struct {
+	ID        int64
+	MessageID string
+	Payload   []byte
+	Attempts  int
+}
+
+type APIKey struct {
+	ID        string
+	TenantID  string
+	Name      string
+	Prefix    string
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+type APIKeyInsert struct {
+	ID       string
+	TenantID string
+	Name     string
+	Prefix   string
+	Hash     string
+	Now      time.Time
+}
+
+// MessageFilter selects messages for listing. Zero values mean "no filter".
+// Results are ordered by ID descending (IDs are ULIDs, so newest first); BeforeID is the cursor.
+type MessageFilter struct {
+	TenantID      string
+	CampaignID    string
+	ToPhone       string
+	State         string
+	TemplateID    string
+	Country       string
+	FailureReason string
+	CreatedFrom   *time.Time
+	CreatedTo     *time.Time
+	BeforeID      string
+	Limit         int
+}
+
+// HistoryEvent is a recorded lifecycle event of a message: a worker claim, a provider attempt
+// or a delivery receipt for one of its provider message IDs.
+type HistoryEvent struct {
+	Kind          string // claimed | canceled | expired | attempt | delivery
+	At            time.Time
+	Detail        string // claimed: previous state
+	Provider      string
+	ProviderMsgID string
+	HTTPStatus    int
+	ErrorCode     string
+	ErrorMsg      string
+	FailureReason string
+	LatencyMs     *int
+	VendorStatus  string
+}
+
+// ConsentChange sets (or with Status "deleted", removes) a consent and appends it to consent_events.
+type ConsentChange struct {
+	TenantID string
+	Phone    string
+	Channel  string
+	Status   string
+	Source   string
+	Actor    string
+	Now      time.Time
+}
+
+type Consent struct {
+	TenantID  string
+	Phone     string
+	Channel   string
+	Status    string
+	UpdatedAt time.Time
+	// Source is how the current status was captured; only ListPhoneConsents sets it.
+	Source string
+}
+
+type ConsentEvent struct {
+	Status    string
+	Source    string
+	Actor     string
+	CreatedAt time.Time
+}
+
+type Suppression struct {
+	TenantID  string
+	Phone     string
+	Reason    string
+	CreatedAt time.Time
+	ExpiresAt *time.Time
+}
+
+// FailureStreak counts a number's consecutive failed deliveries with the same failure reason.
+type FailureStreak struct {
+	TenantID      string
+	Phone         string
+	FailureReason string
+	Failures      int
+}
+
+// SuppressionFilter pages through a tenant's suppressions in phone order; AfterPhone is the cursor.
+type SuppressionFilter struct {
+	TenantID       string
+	AfterPhone     string
+	IncludeExpired bool
+	Now            time.Time
+	Limit          int
+}
+
+type Template struct {
+	TenantID   string
+	TemplateID string
+	Version    int
+	Body       string
+	// MaxSegments rejects rendered bodies longer than this many segments (0 = no limit).
+	MaxSegments int
+	// Transliterate replaces typographic characters so bodies stay in GSM-7.
+	Transliterate bool
+	// Transactional templates bypass delivery windows (quiet hours).
+	Transactional bool
+	Status        string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	PublishedAt   *time.Time
+}
+
+type CountryPolicy struct {
+	TenantID         string
+	AllowedCountries []string
+	BlockedPrefixes  []string
+	CountryDailyCaps map[string]int
+	UpdatedAt        time.Time
+}
+
+type DeliveryWindow struct {
+	TenantID    string
+	CampaignID  string
+	StartMinute int
+	EndMinute   int
+	Days        []string
+	Timezone    string
+	UpdatedAt   time.Time
+}
+
+type TenantRateLimit struct {
+	TenantID  string
+	PerSecond float64
+	Burst     int
+	UpdatedAt time.Time
+}
+
+// InboundMessage is an SMS received on one of our sender numbers.
+type InboundMessage struct {
+	Provider      string
+	ProviderMsgID string
+	From          string
+	To            string
+	Body          string
+	Keyword       string
+	ReceivedAt    time.Time
+}
+
+// SenderTenant is the tenant whose message a number received last.
+type SenderTenant struct {
+	ID   string
+	Name string
+}
+
+type KeywordReplies struct {
+	TenantID  string
+	Help      string
+	Stop      string
+	Start     string
+	UpdatedAt time.Time
+}
@@ -0,0 +1,80 @@
+// Package templates resolves the template version a send should use.
+package templates
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"notif/internal/store"
+)
+
+// Resolver returns the published template for a tenant, or false if there is none.
+type Resolver interface {
+	Resolve(ctx context.Context, tenantID, templateID string) (store.Template, bool, error)
+}
+
+type Loader interface {
+	GetPublishedTemplate(ctx context.Context, tenantID, templateID string) (store.Template, bool, error)
+}
+
+// Cache is a TTL cache in front of the templates table. Only hits are cached, so a newly
+// published template is usable immediately; a republished one is picked up after TTL
+// (or right away in a process that calls Invalidate).
+type Cache struct {
+	Loader Loader
+	TTL    time.Duration
+
+	mu      sync.Mutex
+	entries map[cacheKey]cacheEntry
+}
+
+type cacheKey struct{ tenantID, templateID string }
+
+type cacheEntry struct {
+	tpl     store.Template
+	expires time.Time
+}
+
+func NewCache(loader Loader, ttl time.Duration) *Cache {
+	return &Cache{Loader: loader, TTL: ttl, entries: make(map[cacheKey]cacheEntry)}
+}
+
+func (c *Cache) Resolve(ctx context.Context, tenantID, templateID string) (store.Template, bool, error) {
+	k := cacheKey{tenantID, templateID}
+	now := time.Now()
+
+	c.mu.Lock()
+	e, ok := c.entries[k]
+	c.mu.Unlock()
+	if ok && now.Before(e.expires) {
+		return e.tpl, true, nil
+	}
+
+	tpl, found, err := c.Loader.GetPublishedTemplate(ctx, tenantID, templateID)
+	if err != nil || !found {
+		c.Invalidate(tenantID, templateID)
+		return store.Template{}, false, err
+	}
+	c.mu.Lock()
+	c.entries[k] = cacheEntry{tpl: tpl, expires: now.Add(c.TTL)}
+	c.mu.Unlock()
+	return tpl, true, nil
+}
+
+func (c *Cache) Invalidate(tenantID, templateID string) {
+	c.mu.Lock()
+	delete(c.entries, cacheKey{tenantID, templateID})
+	c.mu.Unlock()
+}
+
+// Static serves fixed template bodies keyed by template ID for every tenant (tests, local tools).
+type Static map[string]string
+
+func (s Static) Resolve(_ context.Context, tenantID, templateID string) (store.Template, bool, error) {
+	body, ok := s[templateID]
+	if !ok || body == "" {
+		return store.Template{}, false, nil
+	}
+	return store.Template{TenantID: tenantID, TemplateID: templateID, Version: 1, Body: body, Status: "published"}, true, nil
+}
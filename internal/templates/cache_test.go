@@ -0,0 +1,62 @@
+package templates
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"notif/internal/store"
+)
+
+type countingLoader struct {
+	calls int
+	body  string
+}
+
+func (l *countingLoader) GetPublishedTemplate(ctx context.Context, tenantID, templateID string) (store.Template, bool, error) {
+	l.calls++
+	if l.body == "" {
+		return store.Template{}, false, nil
+	}
+	return store.Template{TenantID: tenantID, TemplateID: templateID, Body: l.body}, true, nil
+}
+
+func TestCacheCachesHitsOnly(t *testing.T) {
+	ctx := context.Background()
+	l := &countingLoader{}
+	c := NewCache(l, time.Minute)
+
+	if _, ok, _ := c.Resolve(ctx, "t1", "otp"); ok {
+		t.Fatalf("expected miss")
+	}
+	l.body = "code {code}"
+	tpl, ok, err := c.Resolve(ctx, "t1", "otp")
+	if err != nil || !ok || tpl.Body != "code {code}" {
+		t.Fatalf("expected hit after publish, got %+v ok=%v err=%v", tpl, ok, err)
+	}
+	_, _, _ = c.Resolve(ctx, "t1", "otp")
+	if l.calls != 2 {
+		t.Fatalf("expected the hit to be served from cache, loader calls=%d", l.calls)
+	}
+
+	l.body = "new {code}"
+	c.Invalidate("t1", "otp")
+	if tpl, _, _ := c.Resolve(ctx, "t1", "otp"); tpl.Body != "new {code}" {
+		t.Fatalf("expected reload after invalidate, got %q", tpl.Body)
+	}
+	if _, ok, _ := c.Resolve(ctx, "t2", "otp"); !ok || l.calls != 4 {
+		t.Fatalf("expected per-tenant entries, calls=%d", l.calls)
+	}
+}
+
+func TestCacheExpires(t *testing.T) {
+	ctx := context.Background()
+	l := &countingLoader{body: "hi"}
+	c := NewCache(l, time.Nanosecond)
+	_, _, _ = c.Resolve(ctx, "t1", "a")
+	time.Sleep(time.Millisecond)
+	_, _, _ = c.Resolve(ctx, "t1", "a")
+	if l.calls != 2 {
+		t.Fatalf("expected reload after TTL, loader calls=%d", l.calls)
+	}
+}
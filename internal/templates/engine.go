@@ -0,0 +1,356 @@
+package templates
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Template syntax (a superset of the original "{var}" placeholders):
+//
+//	{name}                      variable; rendering fails if it is missing or empty
+//	{name|default:"there"}      fallback when missing or empty (the variable is then optional)
+//	{name|upper} {name|lower}   case formatters
+//	{due|date:"Jan 2"}          RFC 3339 or YYYY-MM-DD input, Go time layout (default "Jan 2, 2006")
+//	{total|currency:"USD"}      decimal input, grouped with 2 decimals and a currency symbol
+//	{#if name}...{else}...{/if} conditional on the variable being present and non-empty
+//	{#if !name}...{/if}         negated conditional
+//	{{ and }}                   literal braces
+//
+// Filters chain left to right. Variables used outside any conditional and without a default are
+// required; sends that do not supply them are rejected. Inside a conditional they are optional and
+// render empty when missing.
+
+var (
+	ErrSyntax       = errors.New("template syntax error")
+	ErrMissingVars  = errors.New("missing template variables")
+	ErrInvalidValue = errors.New("invalid template variable")
+)
+
+type Parsed struct {
+	nodes    []node
+	required []string
+}
+
+type node interface{}
+
+type textNode string
+
+type varNode struct {
+	name    string
+	filters []filter
+}
+
+type ifNode struct {
+	name   string
+	negate bool
+	then   []node
+	els    []node
+}
+
+type filter struct {
+	name string
+	arg  string
+}
+
+var (
+	identRe  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+	filterRe = regexp.MustCompile(`^([a-z]+)(?::(?:"([^"]*)"|([^"|]+)))?$`)
+)
+
+var knownFilters = map[string]bool{"default": true, "upper": true, "lower": true, "date": true, "currency": true}
+
+// Parse compiles a template body.
+func Parse(body string) (*Parsed, error) {
+	p := &parser{src: body}
+	nodes, end, err := p.parse(0)
+	if err != nil {
+		return nil, err
+	}
+	if end != "" {
+		return nil, fmt.Errorf("%w: unexpected {%s}", ErrSyntax, end)
+	}
+	t := &Parsed{nodes: nodes}
+	seen := map[string]bool{}
+	collectRequired(nodes, seen)
+	for name := range seen {
+		t.required = append(t.required, name)
+	}
+	sort.Strings(t.required)
+	return t, nil
+}
+
+// Required lists the variables a send must supply, sorted.
+func (t *Parsed) Required() []string { return t.required }
+
+// Check reports the required variables missing (or empty) in vars.
+func (t *Parsed) Check(vars map[string]string) error {
+	var missing []string
+	for _, name := range t.required {
+		if vars[name] == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrMissingVars, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Execute renders the template. It never emits an unresolved placeholder: a required variable
+// without a value is an error.
+func (t *Parsed) Execute(vars map[string]string) (string, error) {
+	if err := t.Check(vars); err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	if err := render(&sb, t.nodes, vars, false); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// Render parses and executes body in one step.
+func Render(body string, vars map[string]string) (string, error) {
+	t, err := Parse(body)
+	if err != nil {
+		return "", err
+	}
+	return t.Execute(vars)
+}
+
+type parser struct {
+	src string
+	pos int
+}
+
+// parse reads nodes until EOF or a block tag ({else}/{/if}), which it returns as end.
+func (p *parser) parse(depth int) ([]node, string, error) {
+	var nodes []node
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			nodes = append(nodes, textNode(text.String()))
+			text.Reset()
+		}
+	}
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == '{' && strings.HasPrefix(p.src[p.pos:], "{{"):
+			text.WriteByte('{')
+			p.pos += 2
+		case c == '}' && strings.HasPrefix(p.src[p.pos:], "}}"):
+			text.WriteByte('}')
+			p.pos += 2
+		case c == '}':
+			return nil, "", fmt.Errorf("%w: unmatched } at offset %d", ErrSyntax, p.pos)
+		case c == '{':
+			closeIdx := strings.IndexByte(p.src[p.pos:], '}')
+			if closeIdx < 0 {
+				return nil, "", fmt.Errorf("%w: unclosed { at offset %d", ErrSyntax, p.pos)
+			}
+			tag := strings.TrimSpace(p.src[p.pos+1 : p.pos+closeIdx])
+			p.pos += closeIdx + 1
+			flush()
+
+			switch {
+			case tag == "else" || tag == "/if":
+				if depth == 0 {
+					return nil, "", fmt.Errorf("%w: {%s} outside {#if}", ErrSyntax, tag)
+				}
+				return nodes, tag, nil
+			case strings.HasPrefix(tag, "#if "):
+				n, err := p.parseIf(strings.TrimSpace(tag[len("#if "):]), depth)
+				if err != nil {
+					return nil, "", err
+				}
+				nodes = append(nodes, n)
+			default:
+				n, err := parseVar(tag)
+				if err != nil {
+					return nil, "", err
+				}
+				nodes = append(nodes, n)
+			}
+		default:
+			text.WriteByte(c)
+			p.pos++
+		}
+	}
+	flush()
+	if depth > 0 {
+		return nil, "", fmt.Errorf("%w: missing {/if}", ErrSyntax)
+	}
+	return nodes, "", nil
+}
+
+func (p *parser) parseIf(cond string, depth int) (node, error) {
+	n := ifNode{name: cond}
+	if strings.HasPrefix(cond, "!") {
+		n.negate, n.name = true, strings.TrimSpace(cond[1:])
+	}
+	if !identRe.MatchString(n.name) {
+		return nil, fmt.Errorf("%w: invalid condition %q", ErrSyntax, cond)
+	}
+	then, end, err := p.parse(depth + 1)
+	if err != nil {
+		return nil, err
+	}
+	n.then = then
+	if end == "else" {
+		els, end2, err := p.parse(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if end2 != "/if" {
+			return nil, fmt.Errorf("%w: expected {/if} after {else}", ErrSyntax)
+		}
+		n.els = els
+	}
+	return n, nil
+}
+
+func parseVar(tag string) (node, error) {
+	parts := strings.Split(tag, "|")
+	n := varNode{name: strings.TrimSpace(parts[0])}
+	if !identRe.MatchString(n.name) {
+		return nil, fmt.Errorf("%w: invalid placeholder {%s}", ErrSyntax, tag)
+	}
+	for _, raw := range parts[1:] {
+		m := filterRe.FindStringSubmatch(strings.TrimSpace(raw))
+		if m == nil || !knownFilters[m[1]] {
+			return nil, fmt.Errorf("%w: unknown filter %q in {%s}", ErrSyntax, strings.TrimSpace(raw), tag)
+		}
+		n.filters = append(n.filters, filter{name: m[1], arg: m[2] + m[3]})
+	}
+	return n, nil
+}
+
+func collectRequired(nodes []node, out map[string]bool) {
+	for _, n := range nodes {
+		v, ok := n.(varNode)
+		if !ok {
+			continue // variables inside conditionals are optional
+		}
+		if !v.hasDefault() {
+			out[v.name] = true
+		}
+	}
+}
+
+func (v varNode) hasDefault() bool {
+	for _, f := range v.filters {
+		if f.name == "default" {
+			return true
+		}
+	}
+	return false
+}
+
+// render writes nodes to sb. Within a conditional (optional) a missing variable renders empty, as
+// collectRequired assumes.
+func render(sb *strings.Builder, nodes []node, vars map[string]string, optional bool) error {
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case textNode:
+			sb.WriteString(string(n))
+		case ifNode:
+			branch := n.els
+			if (vars[n.name] != "") != n.negate {
+				branch = n.then
+			}
+			if err := render(sb, branch, vars, true); err != nil {
+				return err
+			}
+		case varNode:
+			s, err := n.eval(vars)
+			if err != nil && !(optional && errors.Is(err, ErrMissingVars)) {
+				return err
+			}
+			sb.WriteString(s)
+		}
+	}
+	return nil
+}
+
+func (v varNode) eval(vars map[string]string) (string, error) {
+	s := vars[v.name]
+	for _, f := range v.filters {
+		var err error
+		switch f.name {
+		case "default":
+			if s == "" {
+				s = f.arg
+			}
+		case "upper":
+			s = strings.ToUpper(s)
+		case "lower":
+			s = strings.ToLower(s)
+		case "date":
+			s, err = formatDate(s, f.arg)
+		case "currency":
+			s, err = formatCurrency(s, f.arg)
+		}
+		if err != nil {
+			return "", fmt.Errorf("{%s}: %w", v.name, err)
+		}
+	}
+	if s == "" {
+		return "", fmt.Errorf("%w: %s", ErrMissingVars, v.name)
+	}
+	return s, nil
+}
+
+func formatDate(s, layout string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	if layout == "" {
+		layout = "Jan 2, 2006"
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		if t, err = time.Parse(time.DateOnly, s); err != nil {
+			return "", fmt.Errorf("%w: date %q", ErrInvalidValue, s)
+		}
+	}
+	return t.Format(layout), nil
+}
+
+var currencySymbols = map[string]string{"USD": "$", "EUR": "€", "GBP": "£", "INR": "₹", "JPY": "¥"}
+
+func formatCurrency(s, code string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return "", fmt.Errorf("%w: amount %q", ErrInvalidValue, s)
+	}
+	neg := r.Sign() < 0
+	digits := new(big.Rat).Abs(r).FloatString(2)
+	intPart, frac, _ := strings.Cut(digits, ".")
+	var grouped strings.Builder
+	for i, c := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(c)
+	}
+	amount := grouped.String() + "." + frac
+	code = strings.ToUpper(code)
+	if sym, ok := currencySymbols[code]; ok {
+		amount = sym + amount
+	} else if code != "" {
+		amount += " " + code
+	}
+	if neg {
+		amount = "-" + amount
+	}
+	return amount, nil
+}
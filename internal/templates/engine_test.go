@@ -0,0 +1,113 @@
+package templates
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"notif/internal/sms"
+	"notif/internal/store"
+)
+
+func TestRender(t *testing.T) {
+	cases := []struct {
+		name string
+		body string
+		vars map[string]string
+		want string
+	}{
+		{"plain vars", "Hi {name}, ref {ref}.", map[string]string{"name": "Ann", "ref": "R1"}, "Hi Ann, ref R1."},
+		{"overlapping names", "{a}{ab}", map[string]string{"a": "x", "ab": "y"}, "xy"},
+		{"value not re-expanded", "{a}", map[string]string{"a": "{b}", "b": "no"}, "{b}"},
+		{"default", "Hi {name|default:\"there\"}", nil, "Hi there"},
+		{"default unused", "Hi {name|default:\"there\"}", map[string]string{"name": "Bo"}, "Hi Bo"},
+		{"filters chain", "{code|default:\"abc\"|upper}", nil, "ABC"},
+		{"if", "{#if name}Hi {name}{else}Hello{/if}!", map[string]string{"name": "Cy"}, "Hi Cy!"},
+		{"else", "{#if name}Hi {name}{else}Hello{/if}!", nil, "Hello!"},
+		{"negated if", "{#if !vip}Standard{/if}", nil, "Standard"},
+		{"escaped braces", "{{literal}} {x}", map[string]string{"x": "1"}, "{literal} 1"},
+		{"date", "Due {d|date:\"2 Jan\"}", map[string]string{"d": "2024-03-05"}, "Due 5 Mar"},
+		{"date rfc3339", "{d|date}", map[string]string{"d": "2024-03-05T10:00:00Z"}, "Mar 5, 2024"},
+		{"currency", "{t|currency:\"USD\"}", map[string]string{"t": "1234567.5"}, "$1,234,567.50"},
+		{"currency code", "{t|currency:\"CHF\"}", map[string]string{"t": "-3"}, "-3.00 CHF"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Render(tc.body, tc.vars)
+			if err != nil {
+				t.Fatalf("render: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestRequiredAndMissing(t *testing.T) {
+	p, err := Parse("Hi {name}, {#if coupon}use {coupon}{/if} {total|currency:\"USD\"} {sig|default:\"-\"}")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if want := []string{"name", "total"}; !reflect.DeepEqual(p.Required(), want) {
+		t.Fatalf("expected required %v, got %v", want, p.Required())
+	}
+	_, err = p.Execute(map[string]string{"name": "A"})
+	if !errors.Is(err, ErrMissingVars) || err.Error() != "missing template variables: total" {
+		t.Fatalf("expected missing total, got %v", err)
+	}
+}
+
+func TestConditionalVarsAreOptional(t *testing.T) {
+	p, err := Parse("Hi {name}{#if vip}, code {code|upper}{/if}.")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if want := []string{"name"}; !reflect.DeepEqual(p.Required(), want) {
+		t.Fatalf("expected required %v, got %v", want, p.Required())
+	}
+	// Whatever Check accepts renders: a variable missing from a taken branch is left empty.
+	vars := map[string]string{"name": "A", "vip": "yes"}
+	if err := p.Check(vars); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	got, err := p.Execute(vars)
+	if err != nil || got != "Hi A, code ." {
+		t.Fatalf("expected the branch with an empty code, got %q %v", got, err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, body := range []string{
+		"Hi {name",
+		"Hi name}",
+		"{#if a}x",
+		"{else}",
+		"{#if a}x{else}y{else}z{/if}",
+		"{name|bogus}",
+		"{bad name}",
+		"{}",
+	} {
+		if _, err := Parse(body); !errors.Is(err, ErrSyntax) {
+			t.Fatalf("expected syntax error for %q, got %v", body, err)
+		}
+	}
+}
+
+func TestPrepareSegmentLimit(t *testing.T) {
+	tpl := store.Template{Body: "Hi {name} — welcome", MaxSegments: 1}
+	_, info, err := Prepare(tpl, map[string]string{"name": strings.Repeat("x", 60)})
+	if !errors.Is(err, ErrTooManySegments) || info.Encoding != sms.UCS2 {
+		t.Fatalf("expected UCS-2 body over the limit, got %+v err=%v", info, err)
+	}
+
+	tpl.Transliterate = true
+	body, info, err := Prepare(tpl, map[string]string{"name": strings.Repeat("x", 60)})
+	if err != nil || info.Encoding != sms.GSM7 || info.Segments != 1 {
+		t.Fatalf("expected transliterated GSM-7 single segment, got %+v err=%v", info, err)
+	}
+	if strings.Contains(body, "—") {
+		t.Fatalf("expected dash to be transliterated: %q", body)
+	}
+}
@@ -0,0 +1,28 @@
+package templates
+
+import (
+	"errors"
+	"fmt"
+
+	"notif/internal/sms"
+	"notif/internal/store"
+)
+
+var ErrTooManySegments = errors.New("message exceeds the template's segment limit")
+
+// Prepare renders tpl with vars the way it will be sent: transliterated when the template asks
+// for it, analyzed for encoding and segments, and checked against the template's segment limit.
+func Prepare(tpl store.Template, vars map[string]string) (string, sms.Info, error) {
+	body, err := Render(tpl.Body, vars)
+	if err != nil {
+		return "", sms.Info{}, err
+	}
+	if tpl.Transliterate {
+		body = sms.Transliterate(body)
+	}
+	info := sms.Analyze(body)
+	if tpl.MaxSegments > 0 && info.Segments > tpl.MaxSegments {
+		return "", info, fmt.Errorf("%w: %d segments (%s), limit %d", ErrTooManySegments, info.Segments, info.Encoding, tpl.MaxSegments)
+	}
+	return body, info, nil
+}
@@ -3,16 +3,20 @@ package worker
 import (
 	"context"
 	"errors"
+	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/sony/gobreaker"
-	"golang.org/x/time/rate"
 
 	"notif/internal/observability"
-	"notif/internal/providers/twilio"
+	"notif/internal/providers"
 	sqsqueue "notif/internal/queue/sqs"
+	"notif/internal/ratelimit"
+	"notif/internal/retry"
+	"notif/internal/service"
 	"notif/internal/store"
+	"notif/internal/templates"
 	"notif/internal/util"
 )
 
@@ -22,19 +26,52 @@ type Store interface {
 	SetProviderDetails(ctx context.Context, in store.ProviderDetailsUpdate) error
 	MarkMessageState(ctx context.Context, in store.MessageStateUpdate) error
 	ClaimMessage(ctx context.Context, msgID string, now time.Time, staleAfter time.Duration) (bool, error)
+	ExpireMessage(ctx context.Context, msgID string, now time.Time, staleAfter time.Duration) (bool, error)
+	ResolveDeliveryWindow(ctx context.Context, tenantID, campaignID string) (store.DeliveryWindow, bool, error)
+	DeferMessage(ctx context.Context, msgID string, payload any, at, now time.Time) (bool, error)
 }
 
-type TwilioSender interface {
-	SendSMS(ctx context.Context, req twilio.SendRequest) (twilio.SendResponse, int, []byte, error)
+var ErrProviderNotConfigured = errors.New("provider not configured")
+
+// Limiter paces provider calls within this pod (*rate.Limiter, *ratelimit.Adaptive).
+type Limiter interface {
+	Wait(ctx context.Context) error
+}
+
+// RateFeedback is implemented by limiters that adapt to provider responses.
+type RateFeedback interface {
+	Throttled(retryAfter time.Duration)
+	Succeeded()
 }
 
 type Processor struct {
-	Store           Store
-	Sender          TwilioSender
-	Templates       map[string]string
-	Limiter         *rate.Limiter
-	Breaker         *gobreaker.CircuitBreaker
+	Store     Store
+	Providers *providers.Registry
+	// Router picks the providers to try per message. Nil sends via DefaultProvider and
+	// fails over to the rest of the registry.
+	Router          *providers.Router
+	DefaultProvider string
+	// Templates resolves the published template body for a message's tenant.
+	Templates templates.Resolver
+	// Limiters pace calls within this pod, keyed by provider name so that one provider's 429s
+	// don't slow the others. A provider without a limiter is not paced.
+	Limiters map[string]Limiter
+	// RateLimits, when set, enforces the provider and tenant rates shared by all pods.
+	RateLimits *ratelimit.Limiter
+	// Breakers are keyed by provider name. A provider without a breaker is called directly.
+	Breakers        map[string]*gobreaker.CircuitBreaker
 	ClaimStaleAfter time.Duration
+	// Retry picks the retry policy per tenant and template. Nil uses retry.Defaults.
+	// Attempts are the receives that reached a provider (the message's retries plus this one).
+	Retry *retry.Policies
+	// MaxReceives is the queue's redrive maxReceiveCount. On that receive a message that would go
+	// back to SQS is marked failed instead, so it never reaches the DLQ while still queued.
+	// 0 means no limit.
+	MaxReceives int
+	// MaxVisibilityDelay caps how long a retry hides its SQS message (0 = no cap). Set it for FIFO
+	// queues, where a hidden message holds up its whole message group: longer retries are put
+	// back in the outbox instead, as a new SQS message published when due.
+	MaxVisibilityDelay time.Duration
 }
 
 func (p *Processor) Process(ctx context.Context, job sqsqueue.SMSJob) error {
@@ -55,13 +92,59 @@ func (p *Processor) Process(ctx context.Context, job sqsqueue.SMSJob) error {
 	}
 
 	// Idempotent consumer: skip final or already submitted with SID
-	if msg.State == "suppressed" || msg.State == "delivered" || msg.State == "failed" {
+	if msg.State == "suppressed" || msg.State == "delivered" || msg.State == "failed" || msg.State == "canceled" || msg.State == "expired" {
 		return nil
 	}
 	if msg.ProviderMsgID != "" && msg.State == "submitted" {
 		return nil
 	}
 
+	// Expired before anyone claimed it (backlog, long quiet hours) or while a worker that died
+	// held it: drop rather than send late.
+	if now := util.NowUTC(); msg.ExpiresAt != nil && !now.Before(*msg.ExpiresAt) {
+		expired, err := p.Store.ExpireMessage(ctx, job.MessageID, now, p.claimStaleAfter())
+		if err != nil {
+			return err
+		}
+		if expired {
+			observability.MessagesExpired.Inc()
+			return nil
+		}
+	}
+
+	plan, err := p.plan(msg.TenantID, msg.To)
+	if err != nil {
+		return err
+	}
+	policy := p.Retry.For(msg.TenantID, msg.TemplateID)
+
+	// Tenant rate: wait before claiming so a refused job goes back to SQS untouched. That still
+	// uses up one of the queue's receives, so on the last one the message is claimed and failed
+	// instead; a delay past MaxVisibilityDelay also claims it, to hold it in the outbox. The token
+	// pays for a provider call; it is refunded if none is made (claimed elsewhere, breakers open,
+	// ...).
+	called := false
+	var tenantLimited error
+	if p.RateLimits != nil {
+		tok, err := p.RateLimits.WaitTenant(ctx, msg.TenantID)
+		limitedDelay := retryDelay(policy, receives(job), 0)
+		switch {
+		case errors.Is(err, ratelimit.ErrLimited) && !p.lastReceive(job) && !p.viaOutbox(limitedDelay):
+			return &sqsqueue.RetryLater{Delay: limitedDelay, Err: err}
+		case errors.Is(err, ratelimit.ErrLimited):
+			tenantLimited = err
+		case err != nil:
+			return err
+		default:
+			defer func() {
+				if !called {
+					// Best effort: a lost refund only costs the tenant one slot.
+					_ = p.RateLimits.Refund(context.WithoutCancel(ctx), tok)
+				}
+			}()
+		}
+	}
+
 	// Claim before sending to avoid duplicate processing.
 	claimed, err := p.Store.ClaimMessage(ctx, job.MessageID, util.NowUTC(), p.claimStaleAfter())
 	if err != nil {
@@ -72,62 +155,131 @@ func (p *Processor) Process(ctx context.Context, job sqsqueue.SMSJob) error {
 	}
 	processed = true
 
-	bodyTmpl, ok := p.Templates[msg.TemplateID]
-	if !ok || bodyTmpl == "" {
+	if tenantLimited != nil {
+		result = "failure_throttled_rate"
+		return p.retryLater(ctx, job, "", "", providers.ReasonRateLimited, retryDelay(policy, receives(job), 0), tenantLimited)
+	}
+
+	tpl, ok, err := p.resolveTemplate(ctx, msg.TenantID, msg.TemplateID)
+	if err != nil {
+		result = "failure_template_lookup"
+		return err
+	}
+	if !ok {
 		result = "failure_invalid_template"
-		if err := p.Store.MarkMessageState(ctx, store.MessageStateUpdate{
-			ID:        job.MessageID,
-			State:     "failed",
-			LastError: "template_not_found",
-			Now:       util.NowUTC(),
-		}); err != nil {
+		if err := p.markFailed(ctx, job.MessageID, "", "template_not_found", providers.ReasonInvalidTemplate); err != nil {
 			return err
 		}
 		return errors.New("template_not_found: " + msg.TemplateID)
 	}
-	body := util.RenderTemplate(bodyTmpl, msg.Vars)
+	body, info, err := templates.Prepare(tpl, msg.Vars)
+	if err != nil {
+		// Vars are checked at accept time, but the published version may have changed since.
+		result = "failure_invalid_template"
+		lastError, reason := "template_render_failed", providers.ReasonInvalidTemplate
+		if errors.Is(err, templates.ErrTooManySegments) {
+			lastError, reason = "segment_limit_exceeded", providers.ReasonContentRejected
+		}
+		if err := p.markFailed(ctx, job.MessageID, "", lastError, reason); err != nil {
+			return err
+		}
+		return err
+	}
 
-	// Send with small retries on transient issues
+	// The delivery window was checked at accept time, but a backlog or retries can bring the
+	// message here after it closed. Hold it in the outbox until the next opening instead of
+	// spending SQS receives on it.
+	if !tpl.Transactional {
+		at, err := p.nextDelivery(ctx, msg)
+		if err != nil {
+			return err
+		}
+		if now := util.NowUTC(); at.After(now) {
+			result = "deferred_window"
+			job.SendAt, job.ReceiveCount = &at, 0
+			_, err := p.Store.DeferMessage(ctx, job.MessageID, job, at, now)
+			return err
+		}
+	}
+
+	// One pass over the routing plan per receive: an open breaker, a rate limit or a retryable
+	// error the provider answered with fails over to the next provider. A timeout does not, since
+	// the provider may have taken the message anyway. If none takes it the message goes back to
+	// SQS with a backoff delay instead of holding this worker.
 	var lastErr error
-	start := util.NowUTC()
+	var lastProv providers.Provider
+	var lastReason providers.FailureReason
+	var retryAfter time.Duration
 	endToEndRecorded := false
+	requestJSON := map[string]any{
+		"to": msg.To, "templateId": msg.TemplateID, "templateVersion": tpl.Version, "campaignId": msg.CampaignID, "tenantId": msg.TenantID,
+		"encoding": info.Encoding, "segments": info.Segments,
+	}
 
-	for attempt := 0; attempt < 3; attempt++ {
-		// 1) Rate limit before calling Twilio (per pod)
-		if p.Limiter != nil {
+	breakerOpen, throttled := 0, 0
+
+	for _, prov := range plan {
+		// 1) Rate limit before calling the provider (per pod)
+		if lim := p.Limiters[prov.Name()]; lim != nil {
 			waitCtx, cancelWait := context.WithTimeout(ctx, 2*time.Second)
-			err := p.Limiter.Wait(waitCtx)
+			err := lim.Wait(waitCtx)
 			cancelWait()
 			if err != nil {
 				// If we can't even acquire a token, treat as transient (don't mark failed)
-				observability.TwilioSend.WithLabelValues("rate_limited_local", "0").Inc()
-				lastErr = err
-				time.Sleep(200 * time.Millisecond)
+				observability.ProviderSend.WithLabelValues(prov.Name(), "rate_limited_local", "0").Inc()
+				throttled++
+				lastErr, lastReason = err, providers.ReasonRateLimited
 				continue
 			}
 		}
+		var provTok ratelimit.Token
+		if p.RateLimits != nil {
+			tok, err := p.RateLimits.WaitProvider(ctx, prov.Name())
+			if err != nil {
+				// Account is saturated: try the next provider rather than queueing up here.
+				observability.ProviderSend.WithLabelValues(prov.Name(), "rate_limited_shared", "0").Inc()
+				throttled++
+				lastErr, lastReason = err, providers.ReasonRateLimited
+				continue
+			}
+			provTok = tok
+		}
 
-		// 2) Circuit breaker wraps the Twilio call
-		resAny, err := p.executeWithBreaker(ctx, msg.To, body)
+		// 2) Circuit breaker wraps the provider call
+		callStart := time.Now()
+		res, err := p.executeWithBreaker(ctx, prov, msg.To, body)
+		latencyMs := int(time.Since(callStart).Milliseconds())
 
-		// 3) Handle breaker open (fail fast; let SQS redrive later)
+		// 3) Breaker open: record the hop and fail over to the next provider
 		if errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests) {
-			observability.TwilioSend.WithLabelValues("failed_cb_open", "0").Inc()
-			result = "failure_throttled_cb"
-			// IMPORTANT: do NOT mark message failed; this is transient provider protection.
-			return err
+			observability.ProviderSend.WithLabelValues(prov.Name(), "failed_cb_open", "0").Inc()
+			breakerOpen++
+			if p.RateLimits != nil {
+				_ = p.RateLimits.Refund(ctx, provTok)
+			}
+			lastErr, lastReason = err, providers.ReasonProviderError
+			if err := p.Store.InsertAttempt(ctx, store.ProviderAttempt{
+				MessageID:     job.MessageID,
+				Provider:      prov.Name(),
+				ErrorMsg:      err.Error(),
+				FailureReason: string(providers.ReasonProviderError),
+				RequestJSON:   requestJSON,
+			}); err != nil {
+				return err
+			}
+			continue
 		}
 
-		var resp twilio.SendResponse
-		var httpStatus int
-		var raw []byte
+		called = true
+		httpStatus, raw := res.HTTPStatus, res.Raw
+		lastProv = prov
+		retryAfter = max(retryAfter, res.RetryAfter)
 
-		if err == nil {
-			r := resAny.(sendResult)
-			resp, httpStatus, raw = r.resp, r.httpStatus, r.raw
+		p.rateFeedback(prov.Name(), err, res)
 
-			observability.TwilioSend.WithLabelValues("ok", strconv.Itoa(httpStatus)).Inc()
-			observability.TwilioLatency.Observe(time.Since(start).Seconds())
+		if err == nil {
+			observability.ProviderSend.WithLabelValues(prov.Name(), "ok", strconv.Itoa(httpStatus)).Inc()
+			observability.ProviderLatency.WithLabelValues(prov.Name()).Observe(time.Since(callStart).Seconds())
 			if !endToEndRecorded {
 				observability.EndToEndLatency.Observe(time.Since(msg.CreatedAt).Seconds())
 				endToEndRecorded = true
@@ -135,53 +287,49 @@ func (p *Processor) Process(ctx context.Context, job sqsqueue.SMSJob) error {
 
 			if err := p.Store.InsertAttempt(ctx, store.ProviderAttempt{
 				MessageID:     job.MessageID,
-				Provider:      "twilio",
-				ProviderMsgID: resp.Sid,
+				Provider:      prov.Name(),
+				ProviderMsgID: res.ProviderMsgID,
 				HTTPStatus:    httpStatus,
-				RequestJSON: map[string]any{
-					"to": msg.To, "templateId": msg.TemplateID, "campaignId": msg.CampaignID, "tenantId": msg.TenantID,
-				},
-				ResponseJSON: jsonRaw(raw),
+				LatencyMs:     latencyMs,
+				RequestJSON:   requestJSON,
+				ResponseJSON:  jsonRaw(raw),
 			}); err != nil {
 				return err
 			}
 
 			if err := p.Store.SetProviderDetails(ctx, store.ProviderDetailsUpdate{
 				ID:            job.MessageID,
-				Provider:      "twilio",
-				ProviderMsgID: resp.Sid,
+				Provider:      prov.Name(),
+				ProviderMsgID: res.ProviderMsgID,
 				State:         "submitted",
+				Encoding:      string(info.Encoding),
+				Segments:      info.Segments,
 				Now:           util.NowUTC(),
 			}); err != nil {
 				return err
 			}
+			observability.SMSSegments.WithLabelValues(prov.Name(), string(info.Encoding)).Add(float64(info.Segments))
 			return nil
 		}
 
 		// err != nil (non-breaker-open)
-		lastErr = err
+		lastErr, lastReason = err, res.Reason()
 
-		// Extract httpStatus/raw if this was a twilioCallError
-		var tce twilioCallError
-		if errors.As(err, &tce) {
-			httpStatus = tce.httpStatus
-			raw = tce.raw
-		}
-
-		observability.TwilioSend.WithLabelValues("error", strconv.Itoa(httpStatus)).Inc()
+		observability.ProviderSend.WithLabelValues(prov.Name(), "error", strconv.Itoa(httpStatus)).Inc()
 		if !endToEndRecorded {
 			observability.EndToEndLatency.Observe(time.Since(msg.CreatedAt).Seconds())
 			endToEndRecorded = true
 		}
 
 		if err := p.Store.InsertAttempt(ctx, store.ProviderAttempt{
-			MessageID:  job.MessageID,
-			Provider:   "twilio",
-			HTTPStatus: httpStatus,
-			ErrorMsg:   err.Error(),
-			RequestJSON: map[string]any{
-				"to": msg.To, "templateId": msg.TemplateID, "campaignId": msg.CampaignID, "tenantId": msg.TenantID,
-			},
+			MessageID:     job.MessageID,
+			Provider:      prov.Name(),
+			HTTPStatus:    httpStatus,
+			ErrorCode:     res.ErrorCode,
+			ErrorMsg:      err.Error(),
+			FailureReason: string(lastReason),
+			LatencyMs:     latencyMs,
+			RequestJSON:   requestJSON,
 			ResponseJSON: map[string]any{
 				"raw": string(raw),
 			},
@@ -189,53 +337,222 @@ func (p *Processor) Process(ctx context.Context, job sqsqueue.SMSJob) error {
 			return err
 		}
 
-		if !twilio.ShouldRetry(err, httpStatus) {
+		// Permanent errors (bad number, auth, ...) won't get better on another vendor.
+		if !policy.Retryable(prov.Classify(err, res), res) {
 			result = "failure_non_retryable"
-			if err := p.Store.MarkMessageState(ctx, store.MessageStateUpdate{
-				ID:        job.MessageID,
-				State:     "failed",
-				LastError: "twilio_non_retryable",
-				Now:       util.NowUTC(),
-			}); err != nil {
+			if err := p.markFailed(ctx, job.MessageID, prov.Name(), prov.Name()+"_non_retryable", lastReason); err != nil {
 				return err
 			}
 			return err
 		}
+		// Sending again elsewhere could deliver the message twice.
+		if !declined(res) {
+			break
+		}
+	}
+
+	expired := policy.Expired(util.NowUTC().Sub(msg.CreatedAt))
 
-		time.Sleep(twilio.Backoff(attempt))
+	// Every provider is behind an open breaker or at its rate limit: retry later without counting
+	// an attempt against the retry policy. The receive still counts towards the queue's redrive
+	// limit, so retryLater ends the message on the last one; before that only the policy's max
+	// age does. Backing off by receive keeps a long brownout from burning through them.
+	if breakerOpen+throttled == len(plan) && !expired {
+		result = "failure_throttled_cb"
+		if throttled > 0 {
+			result = "failure_throttled_rate"
+		}
+		return p.retryLater(ctx, job, "", "", lastReason, retryDelay(policy, receives(job), retryAfter), lastErr)
 	}
 
-	if err := p.Store.MarkMessageState(ctx, store.MessageStateUpdate{
-		ID:        job.MessageID,
-		State:     "failed",
-		LastError: "twilio_retry_exhausted",
-		Now:       util.NowUTC(),
-	}); err != nil {
+	provName, prefix := "", ""
+	if lastProv != nil {
+		provName, prefix = lastProv.Name(), lastProv.Name()+"_"
+	}
+	attempt := msg.Retries + 1
+	if !expired && !policy.LastAttempt(attempt) {
+		result = "retry_scheduled"
+		return p.retryLater(ctx, job, provName, prefix+"retry_scheduled", lastReason, retryDelay(policy, attempt, retryAfter), lastErr)
+	}
+	if err := p.markFailed(ctx, job.MessageID, provName, prefix+"retry_exhausted", lastReason); err != nil {
 		return err
 	}
 	result = "failure_retry_exhausted"
 	return lastErr
 }
 
-func (p *Processor) executeWithBreaker(ctx context.Context, to, body string) (any, error) {
+// declined reports whether a failed send is known not to have been accepted: the provider
+// answered, and not with a gateway timeout from somewhere in front of it.
+func declined(res providers.SendResult) bool {
+	return res.HTTPStatus != 0 && res.HTTPStatus != http.StatusGatewayTimeout
+}
+
+// nextDelivery is when msg may be sent under its campaign's delivery window (now without one).
+func (p *Processor) nextDelivery(ctx context.Context, msg store.MessageForWorker) (time.Time, error) {
+	now := util.NowUTC()
+	w, ok, err := p.Store.ResolveDeliveryWindow(ctx, msg.TenantID, msg.CampaignID)
+	if err != nil || !ok {
+		return now, err
+	}
+	return service.NextDelivery(w, msg.To, now), nil
+}
+
+// rateFeedback tells the provider's adaptive limiter how it responded: 429 slows it down,
+// success lets it speed back up. Other failures say nothing about the rate.
+func (p *Processor) rateFeedback(provider string, err error, res providers.SendResult) {
+	fb, ok := p.Limiters[provider].(RateFeedback)
+	if !ok {
+		return
+	}
+	switch {
+	case err == nil:
+		fb.Succeeded()
+	case res.HTTPStatus == http.StatusTooManyRequests:
+		fb.Throttled(res.RetryAfter)
+	}
+}
+
+func (p *Processor) executeWithBreaker(ctx context.Context, prov providers.Provider, to, body string) (providers.SendResult, error) {
 	call := func() (any, error) {
 		reqCtx, cancel := context.WithTimeout(ctx, 6*time.Second)
 		defer cancel()
 
-		resp, httpStatus, raw, callErr := p.Sender.SendSMS(reqCtx, twilio.SendRequest{
+		res, callErr := prov.Send(reqCtx, providers.SendRequest{
 			To:   to,
 			Body: body,
 		})
 		if callErr != nil {
-			return nil, twilioCallError{err: callErr, httpStatus: httpStatus, raw: raw}
+			// Carry the partial result through the breaker so attempts still record status/raw.
+			return nil, providerCallError{err: callErr, res: res}
+		}
+		return res, nil
+	}
+
+	var out any
+	var err error
+	if cb := p.Breakers[prov.Name()]; cb != nil {
+		out, err = cb.Execute(call)
+	} else {
+		out, err = call()
+	}
+	if err != nil {
+		var pce providerCallError
+		if errors.As(err, &pce) {
+			return pce.res, err
+		}
+		return providers.SendResult{}, err
+	}
+	return out.(providers.SendResult), nil
+}
+
+// plan resolves the ordered providers to try for a message.
+func (p *Processor) plan(tenantID, to string) ([]providers.Provider, error) {
+	if p.Providers == nil {
+		return nil, ErrProviderNotConfigured
+	}
+	var names []string
+	if p.Router != nil {
+		names = p.Router.Plan(tenantID, to)
+	} else {
+		names = providers.DefaultOrder(p.Providers, p.DefaultProvider)
+	}
+	out := make([]providers.Provider, 0, len(names))
+	for _, name := range names {
+		if prov, ok := p.Providers.Get(name); ok {
+			out = append(out, prov)
+		}
+	}
+	if len(out) == 0 {
+		return nil, ErrProviderNotConfigured
+	}
+	return out, nil
+}
+
+func (p *Processor) resolveTemplate(ctx context.Context, tenantID, templateID string) (store.Template, bool, error) {
+	if p.Templates == nil {
+		return store.Template{}, false, nil
+	}
+	tpl, ok, err := p.Templates.Resolve(ctx, tenantID, templateID)
+	if err != nil || !ok || tpl.Body == "" {
+		return store.Template{}, false, err
+	}
+	return tpl, true, nil
+}
+
+// retryLater releases the claim so the next receive can take the message again, and asks the
+// consumer to redeliver it after delay, or past MaxVisibilityDelay holds it in the outbox until
+// then. A provider name means one was called, which counts the receive as a retry. On the
+// queue's last receive the message is marked failed instead.
+func (p *Processor) retryLater(ctx context.Context, job sqsqueue.SMSJob, provider, lastError string, reason providers.FailureReason, delay time.Duration, err error) error {
+	if p.lastReceive(job) {
+		prefix := ""
+		if provider != "" {
+			prefix = provider + "_"
+		}
+		if err := p.markFailed(ctx, job.MessageID, provider, prefix+"receives_exhausted", reason); err != nil {
+			return err
 		}
-		return sendResult{resp: resp, httpStatus: httpStatus, raw: raw}, nil
+		return err
+	}
+	update := store.MessageStateUpdate{
+		ID:            job.MessageID,
+		State:         "queued",
+		LastError:     lastError,
+		FailureReason: string(reason),
+		CountRetry:    provider != "",
+		Now:           util.NowUTC(),
+	}
+	if p.viaOutbox(delay) {
+		// Keep the claim for DeferMessage, which releases it.
+		update.State = "processing"
+	}
+	if err := p.Store.MarkMessageState(ctx, update); err != nil {
+		return err
+	}
+	if !p.viaOutbox(delay) {
+		return &sqsqueue.RetryLater{Delay: delay, Err: err}
 	}
+	// The job comes back as a new SQS message, so this one's receives carry over for backoff.
+	now := util.NowUTC()
+	at := now.Add(delay)
+	job.SendAt, job.EarlierReceives, job.ReceiveCount = &at, receives(job), 0
+	_, err = p.Store.DeferMessage(ctx, job.MessageID, job, at, now)
+	return err
+}
+
+// viaOutbox reports whether a retry after delay waits in the outbox rather than on SQS.
+func (p *Processor) viaOutbox(delay time.Duration) bool {
+	return p.MaxVisibilityDelay > 0 && delay > p.MaxVisibilityDelay
+}
+
+// receives counts the job's receives across the SQS messages it has been published as.
+func receives(job sqsqueue.SMSJob) int {
+	return job.EarlierReceives + job.ReceiveCount
+}
+
+// lastReceive reports whether SQS dead-letters the job rather than deliver it again.
+func (p *Processor) lastReceive(job sqsqueue.SMSJob) bool {
+	return p.MaxReceives > 0 && job.ReceiveCount >= p.MaxReceives
+}
 
-	if p.Breaker == nil {
-		return call()
+// markFailed ends a message as failed and counts the failure by provider and reason.
+func (p *Processor) markFailed(ctx context.Context, msgID, provider, lastError string, reason providers.FailureReason) error {
+	if err := p.Store.MarkMessageState(ctx, store.MessageStateUpdate{
+		ID:            msgID,
+		State:         "failed",
+		LastError:     lastError,
+		FailureReason: string(reason),
+		Now:           util.NowUTC(),
+	}); err != nil {
+		return err
 	}
-	return p.Breaker.Execute(call)
+	observability.MessageFailures.WithLabelValues(provider, string(reason)).Inc()
+	return nil
+}
+
+// retryDelay is the policy's delay after attempt, within what SQS can defer.
+func retryDelay(policy retry.Policy, attempt int, retryAfter time.Duration) time.Duration {
+	return min(policy.Delay(attempt, retryAfter), sqsqueue.MaxVisibilityTimeout)
 }
 
 func (p *Processor) claimStaleAfter() time.Duration {
@@ -247,17 +564,10 @@ func (p *Processor) claimStaleAfter() time.Duration {
 
 func jsonRaw(b []byte) any { return map[string]any{"raw": string(b)} }
 
-type sendResult struct {
-	resp       twilio.SendResponse
-	httpStatus int
-	raw        []byte
-}
-
-type twilioCallError struct {
-	err        error
-	httpStatus int
-	raw        []byte
+type providerCallError struct {
+	err error
+	res providers.SendResult
 }
 
-func (e twilioCallError) Error() string { return e.err.Error() }
-func (e twilioCallError) Unwrap() error { return e.err }
+func (e providerCallError) Error() string { return e.err.Error() }
+func (e providerCallError) Unwrap() error { return e.err }
@@ -0,0 +1,275 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"notif/internal/providers"
+	sqsqueue "notif/internal/queue/sqs"
+	"notif/internal/retry"
+	"notif/internal/store"
+	"notif/internal/templates"
+)
+
+type fakeStore struct {
+	msg       store.MessageForWorker
+	lastError string
+	attempts  []store.ProviderAttempt
+	window    *store.DeliveryWindow
+	deferred  *sqsqueue.SMSJob
+	// claimedAt stands in for updated_at on processing rows.
+	claimedAt time.Time
+}
+
+func (f *fakeStore) pending(now time.Time, staleAfter time.Duration) bool {
+	switch f.msg.State {
+	case "queued", "scheduled":
+		return true
+	case "processing":
+		return f.claimedAt.Before(now.Add(-staleAfter))
+	}
+	return false
+}
+
+func (f *fakeStore) GetMessageForWorker(ctx context.Context, msgID string) (store.MessageForWorker, error) {
+	return f.msg, nil
+}
+
+func (f *fakeStore) InsertAttempt(ctx context.Context, in store.ProviderAttempt) error {
+	f.attempts = append(f.attempts, in)
+	return nil
+}
+
+func (f *fakeStore) SetProviderDetails(ctx context.Context, in store.ProviderDetailsUpdate) error {
+	f.msg.State, f.msg.ProviderMsgID = in.State, in.ProviderMsgID
+	return nil
+}
+
+func (f *fakeStore) MarkMessageState(ctx context.Context, in store.MessageStateUpdate) error {
+	f.msg.State, f.lastError = in.State, in.LastError
+	if in.CountRetry {
+		f.msg.Retries++
+	}
+	return nil
+}
+
+func (f *fakeStore) ClaimMessage(ctx context.Context, msgID string, now time.Time, staleAfter time.Duration) (bool, error) {
+	if !f.pending(now, staleAfter) {
+		return false, nil
+	}
+	f.msg.State, f.claimedAt = "processing", now
+	return true, nil
+}
+
+func (f *fakeStore) ExpireMessage(ctx context.Context, msgID string, now time.Time, staleAfter time.Duration) (bool, error) {
+	if f.msg.ExpiresAt == nil || now.Before(*f.msg.ExpiresAt) || !f.pending(now, staleAfter) {
+		return false, nil
+	}
+	f.msg.State = "expired"
+	return true, nil
+}
+
+func (f *fakeStore) ResolveDeliveryWindow(ctx context.Context, tenantID, campaignID string) (store.DeliveryWindow, bool, error) {
+	if f.window == nil {
+		return store.DeliveryWindow{}, false, nil
+	}
+	return *f.window, true, nil
+}
+
+func (f *fakeStore) DeferMessage(ctx context.Context, msgID string, payload any, at, now time.Time) (bool, error) {
+	job := payload.(sqsqueue.SMSJob)
+	f.msg.State, f.deferred = "scheduled", &job
+	return true, nil
+}
+
+// fakeProvider answers every send with res and err.
+type fakeProvider struct {
+	name  string
+	res   providers.SendResult
+	err   error
+	sends int
+}
+
+func (f *fakeProvider) Name() string { return f.name }
+
+func (f *fakeProvider) Send(ctx context.Context, req providers.SendRequest) (providers.SendResult, error) {
+	f.sends++
+	return f.res, f.err
+}
+
+func (f *fakeProvider) Classify(err error, res providers.SendResult) providers.ErrorClass {
+	if res.HTTPStatus >= 500 || errors.Is(err, context.DeadlineExceeded) {
+		return providers.ErrorRetryable
+	}
+	return providers.ErrorPermanent
+}
+
+func (f *fakeProvider) ParseStatusCallback(form url.Values) providers.StatusUpdate {
+	return providers.StatusUpdate{}
+}
+
+type refusingLimiter struct{}
+
+func (refusingLimiter) Wait(ctx context.Context) error { return errors.New("no tokens") }
+
+func newTestProcessor(t *testing.T, st *fakeStore, ps ...providers.Provider) *Processor {
+	t.Helper()
+	registry, err := providers.NewRegistry(ps...)
+	if err != nil {
+		t.Fatalf("registry: %v", err)
+	}
+	st.msg = store.MessageForWorker{TenantID: "t1", To: "+15550001111", TemplateID: "otp", State: "queued", CreatedAt: time.Now()}
+	return &Processor{
+		Store:           st,
+		Providers:       registry,
+		DefaultProvider: ps[0].Name(),
+		Templates:       templates.Static{"otp": "Your code"},
+		Retry:           &retry.Policies{Default: retry.Policy{MaxAttempts: 3, BaseDelaySeconds: 1}},
+		MaxReceives:     5,
+	}
+}
+
+func TestRetriesCountOnlyProviderAttempts(t *testing.T) {
+	st := &fakeStore{}
+	prov := &fakeProvider{name: "a", res: providers.SendResult{HTTPStatus: 503}, err: errors.New("status 503")}
+	p := newTestProcessor(t, st, prov)
+	ctx := context.Background()
+
+	// Throttled receives go back to SQS without using up the retry policy.
+	p.Limiters = map[string]Limiter{"a": refusingLimiter{}}
+	for receive := 1; receive <= 2; receive++ {
+		var rl *sqsqueue.RetryLater
+		if err := p.Process(ctx, sqsqueue.SMSJob{MessageID: "m1", ReceiveCount: receive}); !errors.As(err, &rl) {
+			t.Fatalf("receive %d: expected RetryLater, got %v", receive, err)
+		}
+	}
+	if st.msg.Retries != 0 || prov.sends != 0 || st.msg.State != "queued" {
+		t.Fatalf("expected no attempts counted, got %+v after %d sends", st.msg, prov.sends)
+	}
+
+	// Failed sends do, and the third one is the policy's last.
+	p.Limiters = nil
+	for receive := 3; receive <= 4; receive++ {
+		var rl *sqsqueue.RetryLater
+		if err := p.Process(ctx, sqsqueue.SMSJob{MessageID: "m1", ReceiveCount: receive}); !errors.As(err, &rl) {
+			t.Fatalf("receive %d: expected RetryLater, got %v", receive, err)
+		}
+	}
+	if st.msg.Retries != 2 {
+		t.Fatalf("expected 2 retries, got %d", st.msg.Retries)
+	}
+	var rl *sqsqueue.RetryLater
+	if err := p.Process(ctx, sqsqueue.SMSJob{MessageID: "m1", ReceiveCount: 5}); err == nil || errors.As(err, &rl) {
+		t.Fatalf("expected a final error, got %v", err)
+	}
+	if st.msg.State != "failed" || st.lastError != "a_retry_exhausted" {
+		t.Fatalf("expected a_retry_exhausted, got %s %q", st.msg.State, st.lastError)
+	}
+}
+
+func TestLastReceiveFailsInsteadOfDeadLettering(t *testing.T) {
+	st := &fakeStore{}
+	p := newTestProcessor(t, st, &fakeProvider{name: "a"})
+	p.Limiters = map[string]Limiter{"a": refusingLimiter{}}
+
+	var rl *sqsqueue.RetryLater
+	if err := p.Process(context.Background(), sqsqueue.SMSJob{MessageID: "m1", ReceiveCount: 5}); err == nil || errors.As(err, &rl) {
+		t.Fatalf("expected a final error on the last receive, got %v", err)
+	}
+	if st.msg.State != "failed" || st.lastError != "receives_exhausted" {
+		t.Fatalf("expected receives_exhausted, got %s %q", st.msg.State, st.lastError)
+	}
+}
+
+func TestClosedWindowDefersMessage(t *testing.T) {
+	st := &fakeStore{}
+	prov := &fakeProvider{name: "a"}
+	p := newTestProcessor(t, st, prov)
+	// Whole days only, three days out: closed now whatever the time. 555 numbers span several
+	// zones, so the window's own zone applies.
+	opens := time.Now().UTC().AddDate(0, 0, 3)
+	st.window = &store.DeliveryWindow{Days: []string{opens.Weekday().String()[:3]}, Timezone: "UTC"}
+
+	if err := p.Process(context.Background(), sqsqueue.SMSJob{MessageID: "m1", ReceiveCount: 1}); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	want := time.Date(opens.Year(), opens.Month(), opens.Day(), 0, 0, 0, 0, time.UTC)
+	if prov.sends != 0 || st.msg.State != "scheduled" || st.deferred == nil || !st.deferred.SendAt.Equal(want) {
+		t.Fatalf("expected a deferral to %s without a send, got %+v %+v after %d sends", want, st.msg, st.deferred, prov.sends)
+	}
+}
+
+func TestFailover(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		first      providers.SendResult
+		err        error
+		secondSent bool
+	}{
+		{"refused", providers.SendResult{HTTPStatus: 503}, errors.New("status 503"), true},
+		// No answer: the first provider may have the message, so it is retried rather than resent elsewhere.
+		{"timed out", providers.SendResult{}, context.DeadlineExceeded, false},
+		{"gateway timeout", providers.SendResult{HTTPStatus: 504}, errors.New("status 504"), false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			st := &fakeStore{}
+			first := &fakeProvider{name: "a", res: tc.first, err: tc.err}
+			second := &fakeProvider{name: "b", res: providers.SendResult{ProviderMsgID: "SM1", HTTPStatus: 201}}
+			p := newTestProcessor(t, st, first, second)
+
+			err := p.Process(context.Background(), sqsqueue.SMSJob{MessageID: "m1", ReceiveCount: 1})
+			if first.sends != 1 || (second.sends == 1) != tc.secondSent {
+				t.Fatalf("unexpected sends: a=%d b=%d", first.sends, second.sends)
+			}
+			if tc.secondSent {
+				if err != nil || st.msg.State != "submitted" || st.msg.ProviderMsgID != "SM1" {
+					t.Fatalf("expected submission via b, got %v %+v", err, st.msg)
+				}
+				return
+			}
+			var rl *sqsqueue.RetryLater
+			if !errors.As(err, &rl) || st.msg.Retries != 1 {
+				t.Fatalf("expected a counted retry, got %v %+v", err, st.msg)
+			}
+		})
+	}
+}
+
+func TestStaleClaimExpiresInsteadOfSending(t *testing.T) {
+	st := &fakeStore{}
+	prov := &fakeProvider{name: "a", res: providers.SendResult{ProviderMsgID: "SM1", HTTPStatus: 201}}
+	p := newTestProcessor(t, st, prov)
+	// A worker claimed the message and died; it expired while the claim went stale.
+	expiresAt := time.Now().Add(-time.Minute)
+	st.msg.State, st.msg.ExpiresAt, st.claimedAt = "processing", &expiresAt, time.Now().Add(-time.Hour)
+
+	if err := p.Process(context.Background(), sqsqueue.SMSJob{MessageID: "m1", ReceiveCount: 2}); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if prov.sends != 0 || st.msg.State != "expired" {
+		t.Fatalf("expected the message to expire unsent, got %s after %d sends", st.msg.State, prov.sends)
+	}
+}
+
+func TestLongFIFORetryGoesThroughOutbox(t *testing.T) {
+	st := &fakeStore{}
+	prov := &fakeProvider{name: "a", res: providers.SendResult{HTTPStatus: 503}, err: errors.New("status 503")}
+	p := newTestProcessor(t, st, prov)
+	p.Retry.Default.BaseDelaySeconds, p.Retry.Default.MaxDelaySeconds = 60, 600
+	p.MaxVisibilityDelay = 5 * time.Second
+
+	// Hiding the message for a minute would hold up its FIFO group, so it waits in the outbox.
+	before := time.Now()
+	if err := p.Process(context.Background(), sqsqueue.SMSJob{MessageID: "m1", ReceiveCount: 2}); err != nil {
+		t.Fatalf("expected the SQS message to be done with, got %v", err)
+	}
+	if st.msg.State != "scheduled" || st.msg.Retries != 1 || st.deferred == nil {
+		t.Fatalf("expected a counted retry held in the outbox, got %+v %+v", st.msg, st.deferred)
+	}
+	if st.deferred.EarlierReceives != 2 || st.deferred.SendAt.Before(before.Add(30*time.Second)) {
+		t.Fatalf("unexpected republished job %+v", st.deferred)
+	}
+}
@@ -5,6 +5,7 @@ package integration
 
 import (
 	"context"
+	"errors"
 	"crypto/hmac"
 	"crypto/sha1"
 	"encoding/base64"
@@ -21,23 +22,21 @@ import (
 
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"notif/internal/autosuppress"
 	"notif/internal/domain"
 	"notif/internal/httpserver"
+	"notif/internal/providers"
 	"notif/internal/providers/twilio"
 	sqsqueue "notif/internal/queue/sqs"
+	"notif/internal/retry"
 	"notif/internal/service"
 	"notif/internal/store"
 	"notif/internal/store/pg"
+	"notif/internal/templates"
 	"notif/internal/util"
 	workerproc "notif/internal/worker"
 )
 
-type noopQueue struct{}
-
-func (noopQueue) EnqueueSMS(ctx context.Context, tenantID, messageID, idempotencyKey, to, templateID string, vars map[string]string, campaignID string) error {
-	return nil
-}
-
 func TestConsentOptedOutSuppressed(t *testing.T) {
 	ctx := context.Background()
 	db, cleanup := setupTestDB(t)
@@ -61,7 +60,6 @@ func TestConsentOptedOutSuppressed(t *testing.T) {
 
 	svc := &service.NotificationService{
 		Store:     dbStore,
-		Queue:     noopQueue{},
 		MaxPerDay: 10,
 	}
 
@@ -106,7 +104,6 @@ func TestCapExceededSuppressed(t *testing.T) {
 
 	svc := &service.NotificationService{
 		Store:     dbStore,
-		Queue:     noopQueue{},
 		MaxPerDay: 1,
 	}
 
@@ -141,7 +138,6 @@ func TestHappyPathQueuedSubmittedDelivered(t *testing.T) {
 
 	svc := &service.NotificationService{
 		Store:     dbStore,
-		Queue:     noopQueue{},
 		MaxPerDay: 10,
 	}
 
@@ -161,6 +157,29 @@ func TestHappyPathQueuedSubmittedDelivered(t *testing.T) {
 
 	assertMessageStateDB(t, db, "msg-3", string(domain.StateQueued))
 
+	// The queued message must have its outbox row committed with it.
+	var outboxState string
+	if err := db.QueryRow(ctx, `SELECT state FROM outbox WHERE message_id=$1`, "msg-3").Scan(&outboxState); err != nil {
+		t.Fatalf("select outbox: %v", err)
+	}
+	if outboxState != "pending" {
+		t.Fatalf("expected pending outbox row, got %s", outboxState)
+	}
+
+	claimed, err := dbStore.ClaimMessage(ctx, "msg-3", util.NowUTC(), time.Minute)
+	if err != nil || !claimed {
+		t.Fatalf("claim: claimed=%v err=%v", claimed, err)
+	}
+	if err := dbStore.InsertAttempt(ctx, store.ProviderAttempt{
+		MessageID:     "msg-3",
+		Provider:      "twilio",
+		ProviderMsgID: "SM123",
+		HTTPStatus:    201,
+		LatencyMs:     42,
+	}); err != nil {
+		t.Fatalf("insert attempt: %v", err)
+	}
+
 	if err := dbStore.SetProviderDetails(ctx, store.ProviderDetailsUpdate{
 		ID:            "msg-3",
 		Provider:      "twilio",
@@ -192,6 +211,7 @@ func TestHappyPathQueuedSubmittedDelivered(t *testing.T) {
 	webhook := &httpserver.Webhook{
 		Store:           dbStore,
 		VerifySignature: twilio.VerifySignature,
+		ParseStatus:     twilio.ParseStatusCallback,
 		AuthToken:       authToken,
 		PublicURL:       publicURL,
 	}
@@ -203,6 +223,660 @@ func TestHappyPathQueuedSubmittedDelivered(t *testing.T) {
 	}
 
 	assertMessageStateDB(t, db, "msg-3", string(domain.StateDelivered))
+
+	events, found, err := svc.Timeline(ctx, tenantID, "msg-3")
+	if err != nil || !found {
+		t.Fatalf("timeline: found=%v err=%v", found, err)
+	}
+	wantTypes := []string{domain.EventAccepted, domain.EventClaimed, domain.EventProviderAttempt, domain.EventDeliveryStatus}
+	if len(events) != len(wantTypes) {
+		t.Fatalf("expected %d timeline events, got %+v", len(wantTypes), events)
+	}
+	for i, w := range wantTypes {
+		if events[i].Type != w {
+			t.Fatalf("event %d: expected %s, got %s", i, w, events[i].Type)
+		}
+	}
+	if events[1].State != string(domain.StateQueued) {
+		t.Fatalf("expected claim from queued, got %q", events[1].State)
+	}
+	if events[2].LatencyMs == nil || *events[2].LatencyMs != 42 {
+		t.Fatalf("expected attempt latency 42ms, got %v", events[2].LatencyMs)
+	}
+	if events[3].VendorStatus != "delivered" {
+		t.Fatalf("expected delivered receipt, got %q", events[3].VendorStatus)
+	}
+	if _, found, _ := svc.Timeline(ctx, "other", "msg-3"); found {
+		t.Fatalf("timeline must be tenant scoped")
+	}
+}
+
+func TestBatchPerItemResults(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	dbStore := pg.New(db)
+
+	tenantID := "t5"
+	optedIn := "+15550003333"
+	seedTenantOptedIn(t, db, tenantID, optedIn)
+
+	svc := &service.NotificationService{
+		Store:     dbStore,
+		MaxPerDay: 10,
+	}
+
+	batch := domain.SendSMSBatchRequest{
+		TenantID:   tenantID,
+		TemplateID: "tpl-5",
+		Recipients: []domain.BatchRecipient{
+			{IdempotencyKey: "b-1", To: optedIn},
+			{IdempotencyKey: "b-2", To: "+15550004444"}, // no consent
+			{IdempotencyKey: "b-1", To: optedIn},        // duplicate key
+			{IdempotencyKey: "", To: optedIn},           // invalid
+		},
+	}
+	results, err := svc.CreateAndEnqueueSMSBatch(ctx, tenantID, batch.Items(), []string{"msg-b1", "msg-b2", "msg-b3", "msg-b4"}, util.NowUTC())
+	if err != nil {
+		t.Fatalf("batch: %v", err)
+	}
+	want := []string{string(domain.StateQueued), string(domain.StateSuppressed), domain.BatchItemError, domain.BatchItemError}
+	for i, w := range want {
+		if results[i].State != w {
+			t.Fatalf("item %d: expected %s, got %s (%s)", i, w, results[i].State, results[i].Error)
+		}
+	}
+	assertMessageStateDB(t, db, "msg-b1", string(domain.StateQueued))
+	assertMessageStateDB(t, db, "msg-b2", string(domain.StateSuppressed))
+
+	// Retrying the batch returns the existing messages instead of creating new ones.
+	results, err = svc.CreateAndEnqueueSMSBatch(ctx, tenantID, batch.Items()[:1], []string{"msg-b5"}, util.NowUTC())
+	if err != nil {
+		t.Fatalf("batch retry: %v", err)
+	}
+	if results[0].MessageID != "msg-b1" {
+		t.Fatalf("expected idempotent hit msg-b1, got %s", results[0].MessageID)
+	}
+}
+
+func TestListMessagesPagination(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	dbStore := pg.New(db)
+
+	tenantID := "t6"
+	optedIn := "+15550005555"
+	seedTenantOptedIn(t, db, tenantID, optedIn)
+
+	svc := &service.NotificationService{
+		Store:     dbStore,
+		MaxPerDay: 10,
+	}
+	for i, id := range []string{"msg_A1", "msg_A2", "msg_A3"} {
+		to := optedIn
+		if i == 2 {
+			to = "+15550006666" // no consent -> suppressed
+		}
+		if _, err := svc.CreateAndEnqueueSMS(ctx, domain.SendSMSRequest{
+			TenantID:       tenantID,
+			IdempotencyKey: "list-" + id,
+			To:             to,
+			TemplateID:     "tpl-6",
+		}, id, util.NowUTC()); err != nil {
+			t.Fatalf("create %s: %v", id, err)
+		}
+	}
+
+	page, err := svc.ListMessages(ctx, store.MessageFilter{TenantID: tenantID, Limit: 2})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(page.Messages) != 2 || page.Messages[0].ID != "msg_A3" || page.NextCursor != "msg_A2" {
+		t.Fatalf("unexpected first page: %+v", page)
+	}
+	page, err = svc.ListMessages(ctx, store.MessageFilter{TenantID: tenantID, Limit: 2, BeforeID: page.NextCursor})
+	if err != nil {
+		t.Fatalf("list page 2: %v", err)
+	}
+	if len(page.Messages) != 1 || page.Messages[0].ID != "msg_A1" || page.NextCursor != "" {
+		t.Fatalf("unexpected second page: %+v", page)
+	}
+
+	page, err = svc.ListMessages(ctx, store.MessageFilter{TenantID: tenantID, State: string(domain.StateSuppressed)})
+	if err != nil {
+		t.Fatalf("list suppressed: %v", err)
+	}
+	if len(page.Messages) != 1 || page.Messages[0].ID != "msg_A3" {
+		t.Fatalf("expected only msg_A3 suppressed, got %+v", page.Messages)
+	}
+	page, err = svc.ListMessages(ctx, store.MessageFilter{TenantID: "other"})
+	if err != nil {
+		t.Fatalf("list other tenant: %v", err)
+	}
+	if len(page.Messages) != 0 {
+		t.Fatalf("expected no messages for other tenant, got %d", len(page.Messages))
+	}
+}
+
+func TestConsentLifecycleWithHistory(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	dbStore := pg.New(db)
+	tenantID := "t7"
+	insertTenant(t, db, tenantID)
+
+	consents := &service.ConsentService{Store: dbStore}
+	svc := &service.NotificationService{Store: dbStore, MaxPerDay: 10}
+	now := util.NowUTC()
+
+	if _, err := consents.Set(ctx, tenantID, "+1555 000 7777", "", domain.ConsentOptedIn, domain.ConsentSourceAPI, "agent-1", now); err != nil {
+		t.Fatalf("set consent: %v", err)
+	}
+	resp, err := svc.CreateAndEnqueueSMS(ctx, domain.SendSMSRequest{
+		TenantID: tenantID, IdempotencyKey: "c-1", To: "+15550007777", TemplateID: "tpl-7",
+	}, "msg-c1", now)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if resp.State != string(domain.StateQueued) {
+		t.Fatalf("expected queued after opt-in, got %s", resp.State)
+	}
+
+	if err := consents.Delete(ctx, tenantID, "+15550007777", "", domain.ConsentSourceAPI, "agent-2", now.Add(time.Second)); err != nil {
+		t.Fatalf("delete consent: %v", err)
+	}
+	if err := consents.Delete(ctx, tenantID, "+15550007777", "", domain.ConsentSourceAPI, "", now); err != domain.ErrConsentNotFound {
+		t.Fatalf("expected not found on second delete, got %v", err)
+	}
+	// A deleted consent still answers with its history.
+	if c, err := consents.Get(ctx, tenantID, "+15550007777", domain.ChannelSMS); err != nil || c.Status != domain.ConsentDeleted || len(c.History) != 2 {
+		t.Fatalf("expected deleted with history, got %+v err=%v", c, err)
+	}
+	if _, err := consents.Get(ctx, tenantID, "+15550009999", domain.ChannelSMS); err != domain.ErrConsentNotFound {
+		t.Fatalf("expected not found without history, got %v", err)
+	}
+
+	n, err := consents.Import(ctx, domain.ImportConsentsRequest{
+		TenantID: tenantID,
+		Actor:    "crm-sync",
+		Items: []domain.ImportConsentItem{
+			{Phone: "+15550007777", Status: domain.ConsentOptedOut},
+			{Phone: "+15550008888", Status: domain.ConsentOptedIn},
+		},
+	}, now.Add(2*time.Second))
+	if err != nil || n != 2 {
+		t.Fatalf("import: n=%d err=%v", n, err)
+	}
+
+	c, err := consents.Get(ctx, tenantID, "+15550007777", domain.ChannelSMS)
+	if err != nil {
+		t.Fatalf("get consent: %v", err)
+	}
+	if c.Status != domain.ConsentOptedOut {
+		t.Fatalf("expected opted_out, got %s", c.Status)
+	}
+	wantHistory := []domain.ConsentEvent{
+		{Status: domain.ConsentOptedIn, Source: domain.ConsentSourceAPI, Actor: "agent-1"},
+		{Status: domain.ConsentDeleted, Source: domain.ConsentSourceAPI, Actor: "agent-2"},
+		{Status: domain.ConsentOptedOut, Source: domain.ConsentSourceImport, Actor: "crm-sync"},
+	}
+	if len(c.History) != len(wantHistory) {
+		t.Fatalf("expected %d history events, got %+v", len(wantHistory), c.History)
+	}
+	for i, w := range wantHistory {
+		got := c.History[i]
+		if got.Status != w.Status || got.Source != w.Source || got.Actor != w.Actor {
+			t.Fatalf("history %d: expected %+v, got %+v", i, w, got)
+		}
+	}
+}
+
+func TestSuppressionExpiry(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	dbStore := pg.New(db)
+	tenantID := "t8"
+	phone := "+15550009999"
+	seedTenantOptedIn(t, db, tenantID, phone)
+
+	sups := &service.SuppressionService{Store: dbStore}
+	svc := &service.NotificationService{Store: dbStore, MaxPerDay: 10}
+	now := util.NowUTC()
+
+	if _, err := sups.Put(ctx, tenantID, phone, domain.PutSuppressionRequest{Reason: "hard_bounce", TTLSeconds: 3600}, now); err != nil {
+		t.Fatalf("put suppression: %v", err)
+	}
+	if _, err := sups.Put(ctx, tenantID, "+15550009998", domain.PutSuppressionRequest{}, now); err != nil {
+		t.Fatalf("put permanent suppression: %v", err)
+	}
+	past := now.Add(-time.Minute)
+	for _, req := range []domain.PutSuppressionRequest{{TTLSeconds: -1}, {ExpiresAt: &past}} {
+		if _, err := sups.Put(ctx, tenantID, "+15550009997", req, now); !errors.Is(err, domain.ErrInvalidSuppressionExpiry) {
+			t.Fatalf("expected ErrInvalidSuppressionExpiry for %+v, got %v", req, err)
+		}
+	}
+
+	resp, err := svc.CreateAndEnqueueSMS(ctx, domain.SendSMSRequest{
+		TenantID: tenantID, IdempotencyKey: "s-1", To: phone, TemplateID: "tpl-8",
+	}, "msg-s1", now)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if resp.State != string(domain.StateSuppressed) {
+		t.Fatalf("expected suppressed while suppression is active, got %s", resp.State)
+	}
+
+	// Two hours later the temporary suppression has lapsed.
+	later := now.Add(2 * time.Hour)
+	resp, err = svc.CreateAndEnqueueSMS(ctx, domain.SendSMSRequest{
+		TenantID: tenantID, IdempotencyKey: "s-2", To: phone, TemplateID: "tpl-8",
+	}, "msg-s2", later)
+	if err != nil {
+		t.Fatalf("create after expiry: %v", err)
+	}
+	if resp.State != string(domain.StateQueued) {
+		t.Fatalf("expected queued after expiry, got %s", resp.State)
+	}
+
+	page, err := sups.List(ctx, store.SuppressionFilter{TenantID: tenantID, Now: later})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(page.Suppressions) != 1 || page.Suppressions[0].Reason != domain.DefaultSuppressionReason {
+		t.Fatalf("expected only the permanent suppression, got %+v", page.Suppressions)
+	}
+
+	if err := sups.Delete(ctx, tenantID, "+15550009998"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := sups.Get(ctx, tenantID, "+15550009998"); err != domain.ErrSuppressionNotFound {
+		t.Fatalf("expected not found after delete, got %v", err)
+	}
+}
+
+func TestPhoneFormatsShareSuppression(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	dbStore := pg.New(db)
+	tenantID := "t10"
+	seedTenantOptedIn(t, db, tenantID, "+15550001313")
+
+	sups := &service.SuppressionService{Store: dbStore}
+	svc := &service.NotificationService{Store: dbStore, MaxPerDay: 10}
+	now := util.NowUTC()
+
+	if _, err := sups.Put(ctx, tenantID, "+1 (555) 000-1313", domain.PutSuppressionRequest{}, now); err != nil {
+		t.Fatalf("put suppression: %v", err)
+	}
+
+	// A national-format number resolves to the same E.164 key (default region US).
+	resp, err := svc.CreateAndEnqueueSMS(ctx, domain.SendSMSRequest{
+		TenantID: tenantID, IdempotencyKey: "p-1", To: "555-000-1313", TemplateID: "tpl-10",
+	}, "msg-p1", now)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if resp.State != string(domain.StateSuppressed) {
+		t.Fatalf("expected suppressed, got %s", resp.State)
+	}
+
+	_, err = svc.CreateAndEnqueueSMS(ctx, domain.SendSMSRequest{
+		TenantID: tenantID, IdempotencyKey: "p-2", To: "12", TemplateID: "tpl-10",
+	}, "msg-p2", now)
+	if !errors.Is(err, domain.ErrInvalidPhone) {
+		t.Fatalf("expected invalid phone, got %v", err)
+	}
+
+	var country string
+	if err := db.QueryRow(ctx, `SELECT country FROM messages WHERE id='msg-p1'`).Scan(&country); err != nil {
+		t.Fatalf("read country: %v", err)
+	}
+	if country != "US" {
+		t.Fatalf("expected country US, got %q", country)
+	}
+}
+
+func TestCountryPolicy(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	dbStore := pg.New(db)
+	tenantID := "t11"
+	seedTenantOptedIn(t, db, tenantID, "+15550001414")
+	seedTenantOptedIn(t, db, tenantID, "+15550001415")
+
+	policies := &service.CountryPolicyService{Store: dbStore}
+	req := domain.PutCountryPolicyRequest{
+		AllowedCountries: []string{"us"},
+		BlockedPrefixes:  []string{"+1900"},
+		CountryDailyCaps: map[string]int{"us": 1},
+	}
+	if err := req.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if _, err := policies.Put(ctx, tenantID, req, util.NowUTC()); err != nil {
+		t.Fatalf("put policy: %v", err)
+	}
+
+	svc := &service.NotificationService{Store: dbStore, MaxPerDay: 10}
+	reqs := []domain.SendSMSRequest{
+		{TenantID: tenantID, IdempotencyKey: "c-1", To: "+15550001414", TemplateID: "tpl-11"},
+		{TenantID: tenantID, IdempotencyKey: "c-2", To: "+15550001415", TemplateID: "tpl-11"},
+		{TenantID: tenantID, IdempotencyKey: "c-3", To: "+447700900123", TemplateID: "tpl-11"},
+		{TenantID: tenantID, IdempotencyKey: "c-4", To: "+19005550100", TemplateID: "tpl-11"},
+	}
+	ids := []string{"msg-c1", "msg-c2", "msg-c3", "msg-c4"}
+	if _, err := svc.CreateAndEnqueueSMSBatch(ctx, tenantID, reqs, ids, util.NowUTC()); err != nil {
+		t.Fatalf("batch: %v", err)
+	}
+
+	want := []string{"", domain.ReasonCountryCapExceeded, domain.ReasonCountryBlocked, domain.ReasonPrefixBlocked}
+	for i, id := range ids {
+		var lastError string
+		if err := db.QueryRow(ctx, `SELECT COALESCE(last_error,'') FROM messages WHERE id=$1`, id).Scan(&lastError); err != nil {
+			t.Fatalf("read %s: %v", id, err)
+		}
+		if lastError != want[i] {
+			t.Fatalf("%s: expected reason %q, got %q", id, want[i], lastError)
+		}
+	}
+	assertMessageStateDB(t, db, "msg-c1", string(domain.StateQueued))
+
+	if err := policies.Delete(ctx, tenantID); err != nil {
+		t.Fatalf("delete policy: %v", err)
+	}
+	if _, err := policies.Get(ctx, tenantID); !errors.Is(err, domain.ErrCountryPolicyNotFound) {
+		t.Fatalf("expected not found after delete, got %v", err)
+	}
+}
+
+func TestQuietHoursScheduleAndRelease(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	dbStore := pg.New(db)
+	tenantID := "t12"
+	to := "+15550001616" // 555 maps to many zones, so the window's timezone is used
+	seedTenantOptedIn(t, db, tenantID, to)
+
+	now := time.Date(2024, 7, 3, 3, 0, 0, 0, time.UTC)
+	windows := &service.DeliveryWindowService{Store: dbStore}
+	if _, err := windows.Put(ctx, tenantID, "", domain.PutDeliveryWindowRequest{Start: "09:00", End: "21:00", Timezone: "UTC"}, now); err != nil {
+		t.Fatalf("put window: %v", err)
+	}
+
+	tpls := &service.TemplateService{Store: dbStore}
+	for _, req := range []domain.CreateTemplateRequest{
+		{TemplateID: "promo", Body: "Sale today"},
+		{TemplateID: "otp", Body: "Code {code}", Transactional: true},
+	} {
+		tpl, err := tpls.Create(ctx, tenantID, req, now)
+		if err != nil {
+			t.Fatalf("create %s: %v", req.TemplateID, err)
+		}
+		if _, err := tpls.Publish(ctx, tenantID, tpl.TemplateID, tpl.Version, now); err != nil {
+			t.Fatalf("publish %s: %v", req.TemplateID, err)
+		}
+	}
+
+	svc := &service.NotificationService{Store: dbStore, MaxPerDay: 10, Templates: templates.NewCache(dbStore, time.Minute)}
+	resp, err := svc.CreateAndEnqueueSMS(ctx, domain.SendSMSRequest{
+		TenantID: tenantID, IdempotencyKey: "q-1", To: to, TemplateID: "promo",
+	}, "msg-q1", now)
+	if err != nil {
+		t.Fatalf("create promo: %v", err)
+	}
+	opens := time.Date(2024, 7, 3, 9, 0, 0, 0, time.UTC)
+	if resp.State != string(domain.StateScheduled) || resp.ScheduledAt == nil || !resp.ScheduledAt.Equal(opens) {
+		t.Fatalf("expected scheduled until %s, got %+v", opens, resp)
+	}
+
+	// A TTL that runs out before the window opens is rejected rather than expired unsent.
+	if _, err := svc.CreateAndEnqueueSMS(ctx, domain.SendSMSRequest{
+		TenantID: tenantID, IdempotencyKey: "q-ttl", To: to, TemplateID: "promo", TTLSeconds: 3600,
+	}, "msg-q-ttl", now); !errors.Is(err, domain.ErrExpiresBeforeWindow) {
+		t.Fatalf("expected ErrExpiresBeforeWindow, got %v", err)
+	}
+
+	resp, err = svc.CreateAndEnqueueSMS(ctx, domain.SendSMSRequest{
+		TenantID: tenantID, IdempotencyKey: "q-2", To: to, TemplateID: "otp", Vars: map[string]string{"code": "1234"},
+	}, "msg-q2", now)
+	if err != nil {
+		t.Fatalf("create otp: %v", err)
+	}
+	if resp.State != string(domain.StateQueued) {
+		t.Fatalf("expected transactional send to bypass quiet hours, got %s", resp.State)
+	}
+
+	// The scheduled message's outbox row only becomes due when the window opens.
+	rows, err := dbStore.ClaimOutbox(ctx, 10, now, time.Minute)
+	if err != nil {
+		t.Fatalf("claim outbox: %v", err)
+	}
+	if len(rows) != 1 || rows[0].MessageID != "msg-q2" {
+		t.Fatalf("expected only msg-q2 due now, got %+v", rows)
+	}
+	rows, err = dbStore.ClaimOutbox(ctx, 10, opens, time.Minute)
+	if err != nil {
+		t.Fatalf("claim outbox at open: %v", err)
+	}
+	if len(rows) != 1 || rows[0].MessageID != "msg-q1" {
+		t.Fatalf("expected msg-q1 due at window open, got %+v", rows)
+	}
+	if err := dbStore.MarkOutboxSent(ctx, []int64{rows[0].ID}, opens); err != nil {
+		t.Fatalf("mark sent: %v", err)
+	}
+	assertMessageStateDB(t, db, "msg-q1", string(domain.StateQueued))
+
+	// A worker that finds the window closed again holds the message until the next opening.
+	if ok, err := dbStore.ClaimMessage(ctx, "msg-q1", opens, time.Minute); err != nil || !ok {
+		t.Fatalf("claim: ok=%v err=%v", ok, err)
+	}
+	next := opens.Add(24 * time.Hour)
+	if ok, err := dbStore.DeferMessage(ctx, "msg-q1", sqsqueue.SMSJob{MessageID: "msg-q1", SendAt: &next}, next, opens); err != nil || !ok {
+		t.Fatalf("defer: ok=%v err=%v", ok, err)
+	}
+	assertMessageStateDB(t, db, "msg-q1", string(domain.StateScheduled))
+	rows, err = dbStore.ClaimOutbox(ctx, 10, next.Add(-time.Second), time.Minute)
+	if err != nil {
+		t.Fatalf("claim outbox before reopening: %v", err)
+	}
+	for _, r := range rows {
+		if r.MessageID == "msg-q1" {
+			t.Fatalf("msg-q1 due before the next opening")
+		}
+	}
+	if rows, err = dbStore.ClaimOutbox(ctx, 10, next, time.Minute); err != nil || len(rows) != 1 || rows[0].MessageID != "msg-q1" {
+		t.Fatalf("expected msg-q1 due again at the next opening, got %+v err=%v", rows, err)
+	}
+}
+
+func TestSendAtAndCancel(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	dbStore := pg.New(db)
+	tenantID := "t13"
+	to := "+15550001717"
+	seedTenantOptedIn(t, db, tenantID, to)
+
+	now := time.Date(2024, 7, 3, 12, 0, 0, 0, time.UTC)
+	sendAt := now.Add(2 * time.Hour)
+	svc := &service.NotificationService{Store: dbStore, MaxPerDay: 1}
+
+	if _, err := svc.CreateAndEnqueueSMS(ctx, domain.SendSMSRequest{
+		TenantID: tenantID, IdempotencyKey: "s-far", To: to, TemplateID: "tpl", SendAt: ptrTime(now.Add(31 * 24 * time.Hour)),
+	}, "msg-s0", now); !errors.Is(err, domain.ErrSendAtTooFar) {
+		t.Fatalf("expected ErrSendAtTooFar, got %v", err)
+	}
+
+	resp, err := svc.CreateAndEnqueueSMS(ctx, domain.SendSMSRequest{
+		TenantID: tenantID, IdempotencyKey: "s-1", To: to, TemplateID: "tpl", SendAt: &sendAt,
+	}, "msg-s1", now)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if resp.State != string(domain.StateScheduled) || resp.ScheduledAt == nil || !resp.ScheduledAt.Equal(sendAt) {
+		t.Fatalf("expected scheduled until %s, got %+v", sendAt, resp)
+	}
+
+	resp, err = svc.Cancel(ctx, tenantID, "msg-s1", now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if resp.State != string(domain.StateCanceled) {
+		t.Fatalf("expected canceled, got %+v", resp)
+	}
+	assertMessageStateDB(t, db, "msg-s1", string(domain.StateCanceled))
+	if _, err := svc.Cancel(ctx, tenantID, "msg-s1", now.Add(time.Minute)); !errors.Is(err, domain.ErrNotCancelable) {
+		t.Fatalf("expected ErrNotCancelable on second cancel, got %v", err)
+	}
+	if _, err := svc.Cancel(ctx, "other", "msg-s1", now.Add(time.Minute)); !errors.Is(err, domain.ErrMessageNotFound) {
+		t.Fatalf("expected other tenant to get not found, got %v", err)
+	}
+
+	// The withdrawn outbox row is never published.
+	rows, err := dbStore.ClaimOutbox(ctx, 10, sendAt, time.Minute)
+	if err != nil {
+		t.Fatalf("claim outbox: %v", err)
+	}
+	for _, r := range rows {
+		if r.MessageID == "msg-s1" {
+			t.Fatalf("canceled message was claimed from the outbox")
+		}
+	}
+
+	// Canceling gave the daily cap slot back (MaxPerDay is 1).
+	resp, err = svc.CreateAndEnqueueSMS(ctx, domain.SendSMSRequest{
+		TenantID: tenantID, IdempotencyKey: "s-2", To: to, TemplateID: "tpl",
+	}, "msg-s2", now.Add(2*time.Minute))
+	if err != nil {
+		t.Fatalf("create after cancel: %v", err)
+	}
+	if resp.State != string(domain.StateQueued) {
+		t.Fatalf("expected queued after cap release, got %+v", resp)
+	}
+
+	events, _, err := svc.Timeline(ctx, tenantID, "msg-s1")
+	if err != nil {
+		t.Fatalf("timeline: %v", err)
+	}
+	var types []string
+	for _, ev := range events {
+		types = append(types, ev.Type)
+	}
+	if strings.Join(types, ",") != "accepted,scheduled,canceled" {
+		t.Fatalf("unexpected timeline %v", types)
+	}
+}
+
+func ptrTime(t time.Time) *time.Time { return &t }
+
+func TestTemplateVersionsAndSendValidation(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	dbStore := pg.New(db)
+	tenantID := "t9"
+	phone := "+15550001212"
+	seedTenantOptedIn(t, db, tenantID, phone)
+
+	cache := templates.NewCache(dbStore, time.Minute)
+	tplSvc := &service.TemplateService{Store: dbStore, Invalidate: cache.Invalidate}
+	svc := &service.NotificationService{Store: dbStore, MaxPerDay: 10, Templates: cache}
+	now := util.NowUTC()
+
+	send := func(key string) (domain.CreateResponse, error) {
+		return svc.CreateAndEnqueueSMS(ctx, domain.SendSMSRequest{
+			TenantID: tenantID, IdempotencyKey: key, To: phone, TemplateID: "otp",
+			Vars: map[string]string{"code": "123456"},
+		}, "msg-"+key, now)
+	}
+
+	v1, err := tplSvc.Create(ctx, tenantID, domain.CreateTemplateRequest{TemplateID: "otp", Body: "Code {code}"}, now)
+	if err != nil || v1.Version != 1 || v1.Status != domain.TemplateDraft {
+		t.Fatalf("create v1: %+v err=%v", v1, err)
+	}
+	// A draft is not usable for sends.
+	if _, err := send("tpl-1"); err != domain.ErrUnknownTemplate {
+		t.Fatalf("expected unknown template for draft, got %v", err)
+	}
+
+	if _, err := tplSvc.Publish(ctx, tenantID, "otp", 1, now); err != nil {
+		t.Fatalf("publish v1: %v", err)
+	}
+	if _, err := tplSvc.UpdateDraft(ctx, tenantID, "otp", 1, domain.UpdateTemplateRequest{Body: "changed"}, now); err != domain.ErrTemplateNotDraft {
+		t.Fatalf("expected published version to be immutable, got %v", err)
+	}
+	if resp, err := send("tpl-2"); err != nil || resp.State != string(domain.StateQueued) {
+		t.Fatalf("send with published template: %+v err=%v", resp, err)
+	}
+	// Required variables are enforced at accept time.
+	if _, err := svc.CreateAndEnqueueSMS(ctx, domain.SendSMSRequest{
+		TenantID: tenantID, IdempotencyKey: "tpl-novars", To: phone, TemplateID: "otp",
+	}, "msg-tpl-novars", now); !errors.Is(err, templates.ErrMissingVars) {
+		t.Fatalf("expected missing vars, got %v", err)
+	}
+	if _, err := tplSvc.Create(ctx, tenantID, domain.CreateTemplateRequest{TemplateID: "bad", Body: "Hi {name"}, now); !errors.Is(err, domain.ErrInvalidTemplate) {
+		t.Fatalf("expected invalid template, got %v", err)
+	}
+
+	v2, err := tplSvc.Create(ctx, tenantID, domain.CreateTemplateRequest{TemplateID: "otp", Body: "Your code is {code}"}, now)
+	if err != nil || v2.Version != 2 {
+		t.Fatalf("create v2: %+v err=%v", v2, err)
+	}
+	if _, err := tplSvc.Publish(ctx, tenantID, "otp", 2, now.Add(time.Second)); err != nil {
+		t.Fatalf("publish v2: %v", err)
+	}
+	tpl, ok, err := cache.Resolve(ctx, tenantID, "otp")
+	if err != nil || !ok || tpl.Version != 2 {
+		t.Fatalf("expected v2 to be active, got %+v ok=%v err=%v", tpl, ok, err)
+	}
+
+	// Archiving every published version makes the template unusable again.
+	for _, v := range []int{1, 2} {
+		if err := tplSvc.Delete(ctx, tenantID, "otp", v, now); err != nil {
+			t.Fatalf("delete v%d: %v", v, err)
+		}
+	}
+	if _, err := send("tpl-3"); err != domain.ErrUnknownTemplate {
+		t.Fatalf("expected unknown template after archive, got %v", err)
+	}
+	versions, err := tplSvc.List(ctx, tenantID, "otp")
+	if err != nil || len(versions) != 2 || versions[0].Status != domain.TemplateArchived {
+		t.Fatalf("expected archived history, got %+v err=%v", versions, err)
+	}
+
+	// Concurrent creates each get their own version.
+	const n = 8
+	errs := make(chan error, n)
+	for range n {
+		go func() {
+			_, err := tplSvc.Create(ctx, tenantID, domain.CreateTemplateRequest{TemplateID: "welcome", Body: "Welcome"}, now)
+			errs <- err
+		}()
+	}
+	for range n {
+		if err := <-errs; err != nil {
+			t.Fatalf("concurrent create: %v", err)
+		}
+	}
+	if versions, err := tplSvc.List(ctx, tenantID, "welcome"); err != nil || len(versions) != n || versions[0].Version != n {
+		t.Fatalf("expected versions 1..%d, got %+v err=%v", n, versions, err)
+	}
 }
 
 func TestWorkerQueuedToSubmitted(t *testing.T) {
@@ -228,10 +902,14 @@ func TestWorkerQueuedToSubmitted(t *testing.T) {
 		t.Fatalf("insert message: %v", err)
 	}
 
+	registry, err := providers.NewRegistry(fakeProvider{sid: "SM999"})
+	if err != nil {
+		t.Fatalf("registry: %v", err)
+	}
 	p := &workerproc.Processor{
 		Store:     dbStore,
-		Sender:    fakeTwilioSender{sid: "SM999"},
-		Templates: map[string]string{templateID: "Hi {name}, ref {ref}"},
+		Providers: registry,
+		Templates: templates.Static{templateID: "Hi {name}, ref {ref}"},
 	}
 
 	err = p.Process(ctx, sqsqueue.SMSJob{MessageID: msgID})
@@ -240,14 +918,32 @@ func TestWorkerQueuedToSubmitted(t *testing.T) {
 	}
 
 	assertMessageStateDB(t, db, msgID, "submitted")
+
+	msg, _, err := dbStore.GetMessage(ctx, tenantID, msgID)
+	if err != nil {
+		t.Fatalf("get message: %v", err)
+	}
+	if msg.Encoding != "GSM-7" || msg.Segments != 1 {
+		t.Fatalf("expected GSM-7 single segment, got %s/%d", msg.Encoding, msg.Segments)
+	}
 }
 
-type fakeTwilioSender struct {
+type fakeProvider struct {
 	sid string
 }
 
-func (f fakeTwilioSender) SendSMS(ctx context.Context, req twilio.SendRequest) (twilio.SendResponse, int, []byte, error) {
-	return twilio.SendResponse{Sid: f.sid, Status: "queued"}, 201, []byte(`{"sid":"` + f.sid + `"}`), nil
+func (f fakeProvider) Name() string { return "twilio" }
+
+func (f fakeProvider) Send(ctx context.Context, req providers.SendRequest) (providers.SendResult, error) {
+	return providers.SendResult{ProviderMsgID: f.sid, Status: "queued", HTTPStatus: 201, Raw: []byte(`{"sid":"` + f.sid + `"}`)}, nil
+}
+
+func (f fakeProvider) Classify(err error, res providers.SendResult) providers.ErrorClass {
+	return providers.ErrorPermanent
+}
+
+func (f fakeProvider) ParseStatusCallback(form url.Values) providers.StatusUpdate {
+	return twilio.ParseStatusCallback(form)
 }
 
 func insertTenant(t *testing.T, db *pgxpool.Pool, tenantID string) {
@@ -380,3 +1076,394 @@ func twilioSignature(authToken, fullURL string, form url.Values) string {
 }
 
 // TODO: add worker-level integration test for queued -> submitted using a fake Twilio sender.
+
+func TestExpiredMessageIsNotSent(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	dbStore := pg.New(db)
+	tenantID := "t14"
+	to := "+15550001818"
+	seedTenantOptedIn(t, db, tenantID, to)
+
+	svc := &service.NotificationService{Store: dbStore, MaxPerDay: 10}
+	// Accepted two hours ago with a one minute TTL, as if it sat in a backlog since.
+	accepted := util.NowUTC().Add(-2 * time.Hour)
+	if _, err := svc.CreateAndEnqueueSMS(ctx, domain.SendSMSRequest{
+		TenantID: tenantID, IdempotencyKey: "e-0", To: to, TemplateID: "otp", ExpiresAt: &accepted,
+	}, "msg-e0", accepted); !errors.Is(err, domain.ErrInvalidExpiry) {
+		t.Fatalf("expected ErrInvalidExpiry for an expiry in the past, got %v", err)
+	}
+	resp, err := svc.CreateAndEnqueueSMS(ctx, domain.SendSMSRequest{
+		TenantID: tenantID, IdempotencyKey: "e-1", To: to, TemplateID: "otp", TTLSeconds: 60,
+	}, "msg-e1", accepted)
+	if err != nil || resp.State != string(domain.StateQueued) {
+		t.Fatalf("create: %+v %v", resp, err)
+	}
+
+	registry, err := providers.NewRegistry(fakeProvider{sid: "SM-late"})
+	if err != nil {
+		t.Fatalf("registry: %v", err)
+	}
+	p := &workerproc.Processor{
+		Store:     dbStore,
+		Providers: registry,
+		Templates: templates.Static{"otp": "Your code"},
+	}
+	if err := p.Process(ctx, sqsqueue.SMSJob{MessageID: "msg-e1"}); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	assertMessageStateDB(t, db, "msg-e1", string(domain.StateExpired))
+	// Like a cancel, expiry gives the daily cap slot back.
+	var capCount int
+	if err := db.QueryRow(ctx, `SELECT count FROM send_caps_daily WHERE tenant_id=$1 AND phone=$2`, tenantID, to).Scan(&capCount); err != nil || capCount != 0 {
+		t.Fatalf("expected the cap slot released, got %d err=%v", capCount, err)
+	}
+
+	var attempts int
+	if err := db.QueryRow(ctx, `SELECT count(*) FROM provider_attempts WHERE message_id=$1`, "msg-e1").Scan(&attempts); err != nil {
+		t.Fatalf("count attempts: %v", err)
+	}
+	if attempts != 0 {
+		t.Fatalf("expired message reached the provider %d times", attempts)
+	}
+}
+
+func TestOutboxPublishesHighPriorityFirst(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	dbStore := pg.New(db)
+	tenantID := "t15"
+	to := "+15550001919"
+	seedTenantOptedIn(t, db, tenantID, to)
+
+	now := time.Date(2024, 7, 3, 12, 0, 0, 0, time.UTC)
+	svc := &service.NotificationService{Store: dbStore, MaxPerDay: 10}
+	for i, p := range []string{domain.PriorityLow, "", domain.PriorityHigh} {
+		if _, err := svc.CreateAndEnqueueSMS(ctx, domain.SendSMSRequest{
+			TenantID: tenantID, IdempotencyKey: fmt.Sprintf("p-%d", i), To: to, TemplateID: "tpl", Priority: p,
+		}, fmt.Sprintf("msg-p%d", i), now.Add(time.Duration(i)*time.Second)); err != nil {
+			t.Fatalf("create %d: %v", i, err)
+		}
+	}
+
+	var order []string
+	for range 3 {
+		rows, err := dbStore.ClaimOutbox(ctx, 1, now.Add(time.Minute), time.Hour)
+		if err != nil || len(rows) != 1 {
+			t.Fatalf("claim outbox: %+v %v", rows, err)
+		}
+		order = append(order, rows[0].MessageID)
+	}
+	if strings.Join(order, ",") != "msg-p2,msg-p1,msg-p0" {
+		t.Fatalf("expected high, normal, low publish order, got %v", order)
+	}
+}
+
+func TestSharedRateLimitBuckets(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	dbStore := pg.New(db)
+	tenantID := "t16"
+	insertTenant(t, db, tenantID)
+
+	limits := &service.RateLimitService{Store: dbStore}
+	req := domain.PutTenantRateLimitRequest{PerSecond: 2}
+	if err := req.Validate(); err != nil || req.Burst != 2 {
+		t.Fatalf("validate: %+v %v", req, err)
+	}
+	if _, err := limits.Put(ctx, tenantID, req, time.Now()); err != nil {
+		t.Fatalf("put limit: %v", err)
+	}
+	if _, err := limits.Put(ctx, "missing-tenant", req, time.Now()); !errors.Is(err, domain.ErrTenantNotFound) {
+		t.Fatalf("expected ErrTenantNotFound, got %v", err)
+	}
+
+	// Burst of 2 goes through immediately, the next sends are spaced at 2/s, and a reservation
+	// more than a second out is refused.
+	key := "tenant:" + tenantID
+	var waits []time.Duration
+	for range 4 {
+		d, ok, err := dbStore.ReserveToken(ctx, key, 2, 2, time.Second)
+		if err != nil || !ok {
+			t.Fatalf("reserve: %v %v", ok, err)
+		}
+		waits = append(waits, d)
+	}
+	if waits[0] != 0 || waits[1] != 0 || waits[2] <= 0 || waits[2] > 600*time.Millisecond || waits[3] <= waits[2] {
+		t.Fatalf("unexpected waits %v", waits)
+	}
+	if _, ok, err := dbStore.ReserveToken(ctx, key, 2, 2, time.Second); err != nil || ok {
+		t.Fatalf("expected refusal beyond max wait, got ok=%v err=%v", ok, err)
+	}
+	// A refunded token makes room for the next reservation.
+	if err := dbStore.RefundToken(ctx, key, 2); err != nil {
+		t.Fatalf("refund: %v", err)
+	}
+	if _, ok, err := dbStore.ReserveToken(ctx, key, 2, 2, time.Second); err != nil || !ok {
+		t.Fatalf("expected a reservation after the refund, got ok=%v err=%v", ok, err)
+	}
+
+	if err := limits.Delete(ctx, tenantID); err != nil {
+		t.Fatalf("delete limit: %v", err)
+	}
+	if _, err := limits.Get(ctx, tenantID); !errors.Is(err, domain.ErrRateLimitNotFound) {
+		t.Fatalf("expected not found after delete, got %v", err)
+	}
+}
+
+// busyProvider answers every send with a retryable 503 and a Retry-After.
+type busyProvider struct{ fakeProvider }
+
+func (busyProvider) Send(ctx context.Context, req providers.SendRequest) (providers.SendResult, error) {
+	return providers.SendResult{HTTPStatus: 503, RetryAfter: 30 * time.Second, Raw: []byte(`{"status":503}`)}, errors.New("twilio status 503")
+}
+
+func (busyProvider) Classify(err error, res providers.SendResult) providers.ErrorClass {
+	return providers.ErrorRetryable
+}
+
+func TestRetryableFailureIsDeferredToSQS(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	dbStore := pg.New(db)
+	tenantID := "t17"
+	to := "+15550001919"
+	seedTenantOptedIn(t, db, tenantID, to)
+
+	svc := &service.NotificationService{Store: dbStore, MaxPerDay: 10}
+	if _, err := svc.CreateAndEnqueueSMS(ctx, domain.SendSMSRequest{
+		TenantID: tenantID, IdempotencyKey: "r-1", To: to, TemplateID: "otp",
+	}, "msg-r1", util.NowUTC()); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	registry, err := providers.NewRegistry(busyProvider{})
+	if err != nil {
+		t.Fatalf("registry: %v", err)
+	}
+	p := &workerproc.Processor{
+		Store:     dbStore,
+		Providers: registry,
+		Templates: templates.Static{"otp": "Your code"},
+		Retry: &retry.Policies{
+			Default: retry.Policy{MaxAttempts: 10},
+			Rules:   []retry.Rule{{TemplateID: "otp", Policy: retry.Policy{MaxAttempts: 2}}},
+		},
+	}
+
+	// First receive: handed back to SQS no sooner than the provider's Retry-After.
+	err = p.Process(ctx, sqsqueue.SMSJob{MessageID: "msg-r1", ReceiveCount: 1})
+	var rl *sqsqueue.RetryLater
+	if !errors.As(err, &rl) || rl.Delay < 30*time.Second {
+		t.Fatalf("expected RetryLater of at least 30s, got %v", err)
+	}
+	assertMessageStateDB(t, db, "msg-r1", "queued")
+
+	// Last receive under the otp rule: the message fails for good.
+	if err := p.Process(ctx, sqsqueue.SMSJob{MessageID: "msg-r1", ReceiveCount: 2}); err == nil || errors.As(err, &rl) {
+		t.Fatalf("expected a final error on the last attempt, got %v", err)
+	}
+	assertMessageStateDB(t, db, "msg-r1", "failed")
+	msg, _, err := dbStore.GetMessage(ctx, tenantID, "msg-r1")
+	if err != nil || msg.FailureReason != string(providers.ReasonProviderError) {
+		t.Fatalf("expected failure reason provider_error, got %+v %v", msg, err)
+	}
+
+	var attempts int
+	if err := db.QueryRow(ctx, `SELECT count(*) FROM provider_attempts WHERE message_id=$1`, "msg-r1").Scan(&attempts); err != nil {
+		t.Fatalf("count attempts: %v", err)
+	}
+	if attempts != 2 {
+		t.Fatalf("expected one provider call per receive, got %d", attempts)
+	}
+}
+
+func TestUnreachableReceiptsAutoSuppress(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	dbStore := pg.New(db)
+	tenantID := "t18"
+	to := "+15550001818"
+	seedTenantOptedIn(t, db, tenantID, to)
+
+	authToken := "testtoken"
+	publicURL := "https://example.com/v1/webhooks/twilio/status"
+	s := httpserver.New()
+	webhook := &httpserver.Webhook{
+		Store:           dbStore,
+		VerifySignature: twilio.VerifySignature,
+		ParseStatus:     twilio.ParseStatusCallback,
+		AuthToken:       authToken,
+		PublicURL:       publicURL,
+		AutoSuppress: &autosuppress.Policy{
+			Store: dbStore,
+			Rules: []autosuppress.Rule{{Reasons: []string{"unreachable"}, After: 2, TTLSeconds: 3600}},
+		},
+	}
+	webhook.Register(s.Mux)
+
+	svc := &service.NotificationService{Store: dbStore, MaxPerDay: 10}
+	receipt := func(msgID, sid string) {
+		t.Helper()
+		if _, err := svc.CreateAndEnqueueSMS(ctx, domain.SendSMSRequest{
+			TenantID: tenantID, IdempotencyKey: msgID, To: to, TemplateID: "tpl-18",
+		}, msgID, util.NowUTC()); err != nil {
+			t.Fatalf("create %s: %v", msgID, err)
+		}
+		if err := dbStore.SetProviderDetails(ctx, store.ProviderDetailsUpdate{
+			ID: msgID, Provider: "twilio", ProviderMsgID: sid, State: "submitted", Now: util.NowUTC(),
+		}); err != nil {
+			t.Fatalf("set provider details: %v", err)
+		}
+		form := url.Values{
+			"MessageSid":    []string{sid},
+			"MessageStatus": []string{"undelivered"},
+			"ErrorCode":     []string{"30003"},
+		}
+		req := httptest.NewRequest(http.MethodPost, publicURL, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("X-Twilio-Signature", twilioSignature(authToken, publicURL, form))
+		rr := httptest.NewRecorder()
+		s.Mux.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rr.Code)
+		}
+	}
+
+	receipt("msg-u1", "SMU1")
+	if _, found, err := dbStore.GetSuppression(ctx, tenantID, to); err != nil || found {
+		t.Fatalf("expected no suppression after one failure, found=%v err=%v", found, err)
+	}
+
+	receipt("msg-u2", "SMU2")
+	sp, found, err := dbStore.GetSuppression(ctx, tenantID, to)
+	if err != nil || !found {
+		t.Fatalf("expected a suppression after two failures, found=%v err=%v", found, err)
+	}
+	if sp.Reason != autosuppress.ReasonPrefix+"unreachable" || sp.ExpiresAt == nil {
+		t.Fatalf("expected a temporary auto:unreachable suppression, got %+v", sp)
+	}
+	var streaks int
+	if err := db.QueryRow(ctx, `SELECT count(*) FROM delivery_failure_streaks WHERE tenant_id=$1`, tenantID).Scan(&streaks); err != nil || streaks != 0 {
+		t.Fatalf("expected the streak to end with the suppression, got %d err=%v", streaks, err)
+	}
+}
+
+func TestInboundKeywords(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	dbStore := pg.New(db)
+	from := "+15550002020"
+	seedTenantOptedIn(t, db, "t19", from)
+	seedTenantOptedIn(t, db, "t20", from)
+
+	// t19 messaged the number last, so it answers the keywords.
+	svc := &service.NotificationService{Store: dbStore, MaxPerDay: 10}
+	if _, err := svc.CreateAndEnqueueSMS(ctx, domain.SendSMSRequest{
+		TenantID: "t19", IdempotencyKey: "in-1", To: from, TemplateID: "tpl-19",
+	}, "msg-in1", util.NowUTC()); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := dbStore.SetProviderDetails(ctx, store.ProviderDetailsUpdate{
+		ID: "msg-in1", Provider: "twilio", ProviderMsgID: "SMOUT1", State: "submitted", Now: util.NowUTC(),
+	}); err != nil {
+		t.Fatalf("set provider details: %v", err)
+	}
+	replies := &service.KeywordReplyService{Store: dbStore}
+	if _, err := replies.Put(ctx, "t19", domain.PutKeywordRepliesRequest{Help: "Acme alerts: call 555-0100 for help."}, util.NowUTC()); err != nil {
+		t.Fatalf("put keyword replies: %v", err)
+	}
+
+	authToken := "testtoken"
+	inboundURL := "https://example.com/v1/webhooks/twilio/inbound"
+	s := httpserver.New()
+	webhook := &httpserver.Webhook{
+		Store:           dbStore,
+		VerifySignature: twilio.VerifySignature,
+		ParseStatus:     twilio.ParseStatusCallback,
+		AuthToken:       authToken,
+		Inbound: &service.InboundService{
+			Store:    dbStore,
+			Defaults: domain.KeywordReplies{Help: "Reply STOP to unsubscribe.", Stop: "You are unsubscribed."},
+		},
+		ParseInbound: twilio.ParseInboundMessage,
+		InboundURL:   inboundURL,
+	}
+	webhook.Register(s.Mux)
+
+	send := func(sid, body string) string {
+		t.Helper()
+		form := url.Values{
+			"MessageSid": []string{sid},
+			"From":       []string{from},
+			"To":         []string{"+15550009000"},
+			"Body":       []string{body},
+		}
+		req := httptest.NewRequest(http.MethodPost, inboundURL, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("X-Twilio-Signature", twilioSignature(authToken, inboundURL, form))
+		rr := httptest.NewRecorder()
+		s.Mux.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rr.Code)
+		}
+		return rr.Body.String()
+	}
+	consentStatus := func(tenantID string) string {
+		t.Helper()
+		c, found, err := dbStore.GetConsent(ctx, tenantID, from, "sms")
+		if err != nil || !found {
+			t.Fatalf("get consent: found=%v err=%v", found, err)
+		}
+		return c.Status
+	}
+
+	if body := send("SMIN1", " Stop "); !strings.Contains(body, "<Message>t19: You are unsubscribed.</Message>") {
+		t.Fatalf("expected the default STOP reply under t19's name, got %s", body)
+	}
+	if consentStatus("t19") != "opted_out" || consentStatus("t20") != "opted_out" {
+		t.Fatal("expected STOP to opt the number out of every tenant")
+	}
+
+	// An opt-out made through the API is not undone by START.
+	consents := &service.ConsentService{Store: dbStore}
+	if _, err := consents.Set(ctx, "t20", from, "sms", domain.ConsentOptedOut, domain.ConsentSourceAPI, "support", util.NowUTC()); err != nil {
+		t.Fatalf("set consent: %v", err)
+	}
+	if body := send("SMIN2", "START"); !strings.Contains(body, "<Response/>") {
+		t.Fatalf("expected no START reply, got %s", body)
+	}
+	if consentStatus("t19") != "opted_in" || consentStatus("t20") != "opted_out" {
+		t.Fatal("expected START to reverse only the keyword opt-out")
+	}
+
+	if body := send("SMIN3", "help"); !strings.Contains(body, "Acme alerts: call 555-0100 for help.") {
+		t.Fatalf("expected the tenant's HELP reply, got %s", body)
+	}
+
+	// Redelivery of a stored message is a no-op.
+	send("SMIN3", "help")
+	var n int
+	if err := db.QueryRow(ctx, `SELECT count(*) FROM inbound_messages WHERE from_phone=$1`, from).Scan(&n); err != nil {
+		t.Fatalf("count inbound: %v", err)
+	}
+	if n != 3 {
+		t.Fatalf("expected 3 inbound messages, got %d", n)
+	}
+	events, err := dbStore.ListConsentEvents(ctx, "t19", from, "sms")
+	if err != nil || len(events) != 2 || events[0].Source != domain.ConsentSourceKeyword || events[0].Actor != "twilio:SMIN1" {
+		t.Fatalf("expected keyword consent history, got %+v %v", events, err)
+	}
+}